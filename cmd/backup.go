@@ -3,11 +3,14 @@ package cmd
 import (
 	"log/slog"
 	"os"
+	"slices"
 
-	"github.com/hibare/stashly/internal/config"
 	"github.com/spf13/cobra"
 )
 
+// backupJobs holds the names of the jobs to run; empty runs every job.
+var backupJobs []string
+
 var backupCmd = &cobra.Command{
 	Use:   "backup",
 	Short: "Trigger a backup run immediately",
@@ -15,21 +18,31 @@ var backupCmd = &cobra.Command{
 		ctx := cmd.Context()
 
 		// Load config
-		cfg, err := config.LoadConfig(ctx, cfgFile)
-		if err != nil {
-			slog.ErrorContext(ctx, "Failed to load config", "error", err)
-			os.Exit(1)
+		cfg := loadValidConfig(ctx)
+
+		ran := 0
+		for _, job := range cfg.BackupJobs() {
+			if len(backupJobs) > 0 && !slices.Contains(backupJobs, job.JobName()) {
+				continue
+			}
+			ran++
+
+			slog.InfoContext(ctx, "Starting immediate backup", "job", job.JobName())
+			if bErr := doBackup(ctx, job); bErr != nil {
+				slog.ErrorContext(ctx, "Backup failed", "job", job.JobName(), "error", bErr)
+				continue
+			}
+			slog.InfoContext(ctx, "Backup completed successfully", "job", job.JobName())
 		}
 
-		slog.InfoContext(ctx, "Starting immediate backup")
-		if bErr := doBackup(ctx, cfg); bErr != nil {
-			slog.ErrorContext(ctx, "Backup failed", "error", bErr)
-			return
+		if ran == 0 {
+			slog.ErrorContext(ctx, "No matching backup jobs", "jobs", backupJobs)
+			os.Exit(1)
 		}
-		slog.InfoContext(ctx, "Backup completed successfully")
 	},
 }
 
 func init() {
+	backupCmd.Flags().StringSliceVar(&backupJobs, "job", nil, "run only the named job(s) (default all jobs)")
 	rootCmd.AddCommand(backupCmd)
 }
@@ -3,48 +3,259 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/hibare/stashly/internal/config"
 	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/events"
 	"github.com/hibare/stashly/internal/exec"
+	"github.com/hibare/stashly/internal/heartbeat"
+	"github.com/hibare/stashly/internal/history"
+	"github.com/hibare/stashly/internal/lock"
+	"github.com/hibare/stashly/internal/logging"
+	"github.com/hibare/stashly/internal/metrics"
 	"github.com/hibare/stashly/internal/notifiers"
+	"github.com/hibare/stashly/internal/scheduler"
 	"github.com/hibare/stashly/internal/storage/s3"
+	"github.com/hibare/stashly/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func doBackup(ctx context.Context, cfg *config.Config) error {
+// sendEvent delivers the event to notifiers, even if the run itself has been cancelled.
+func sendEvent(ctx context.Context, notify notifiers.NotifierStoreIface, ev events.Event) {
+	ctx = context.WithoutCancel(ctx)
+	if err := notify.Notify(ctx, ev); err != nil && !errors.Is(err, notifiers.ErrNotifiersDisabled) {
+		slog.ErrorContext(ctx, "Failed to send notification", "event", ev.Type, "error", err)
+	}
+}
+
+// emitFunc sends a backup lifecycle event for the current run.
+type emitFunc func(t events.Type, dump *dumpster.DumpResponse, err error)
+
+// newEmitter returns an emitFunc delivering events for the run to the job's notifiers.
+func newEmitter(ctx context.Context, cfg *config.Config, runID string) emitFunc {
+	notify := notifiers.NewNotifier(cfg)
+	notify.InitStore()
+
+	return func(t events.Type, dump *dumpster.DumpResponse, err error) {
+		ev := events.New(t, runID, dump, err)
+		ev.Job = cfg.Job
+		sendEvent(ctx, notify, ev)
+	}
+}
+
+// notifyBlackout reports a scheduled run skipped or deferred by a blackout window.
+func notifyBlackout(ctx context.Context, cfg *config.Config, window string, until time.Time, deferred bool) {
+	notify := notifiers.NewNotifier(cfg)
+	notify.InitStore()
+
+	t := events.TypeSkipped
+	if deferred {
+		t = events.TypeDeferred
+	}
+	ev := events.New(t, "", nil, nil)
+	ev.Job = cfg.Job
+	ev.Window = window
+	ev.Until = until
+	sendEvent(ctx, notify, ev)
+}
+
+// acquireLock takes the job's distributed lock, if enabled, and returns a function releasing it.
+// The returned context is cancelled with lock.ErrLost if the lock is lost while the backup runs.
+func acquireLock(ctx context.Context, cfg *config.Config) (context.Context, func(), error) {
+	if !cfg.Lock.Enabled {
+		return ctx, func() {}, nil
+	}
+
 	store := s3.NewS3Storage(cfg)
 	if err := store.Init(); err != nil {
+		return nil, nil, err
+	}
+
+	lk, err := lock.NewLocker(store, cfg.JobName(), cfg.Lock.Owner, cfg.Lock.TTL).Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.DebugContext(ctx, "Acquired backup lock", "job", cfg.JobName(), "owner", cfg.Lock.Owner)
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	released := make(chan struct{})
+	go func() {
+		select {
+		case <-lk.Lost():
+			slog.ErrorContext(ctx, "Backup lock lost; cancelling the backup", "job", cfg.JobName())
+			cancel(lock.ErrLost)
+		case <-released:
+		}
+	}()
+
+	return lockCtx, func() {
+		close(released)
+		cancel(nil)
+		if rErr := lk.Release(context.WithoutCancel(ctx)); rErr != nil {
+			slog.WarnContext(ctx, "Failed to release backup lock", "job", cfg.JobName(), "error", rErr)
+		}
+	}, nil
+}
+
+// recordRun adds the run to the job's history, locally and in the bucket if configured.
+func recordRun(ctx context.Context, cfg *config.Config, entry history.Entry) {
+	ctx = context.WithoutCancel(ctx)
+	entry.Job = cfg.JobName()
+	entry.Instance = cfg.App.InstanceID
+	entry.Trigger = string(scheduler.TriggerFrom(ctx))
+	if entry.FinishedAt.IsZero() {
+		entry.FinishedAt = time.Now()
+	}
+
+	if cfg.History.Enabled {
+		if err := history.NewFile(cfg.History.Path).Append(entry); err != nil {
+			slog.WarnContext(ctx, "Failed to record run history", "path", cfg.History.Path, "error", err)
+		}
+	}
+
+	if cfg.History.Upload {
+		store := s3.NewS3Storage(cfg)
+		err := store.Init()
+		if err == nil {
+			err = history.Upload(ctx, store, entry)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to upload run history", "error", err)
+		}
+	}
+}
+
+// runStatus returns the history status of a finished run. Failed runs may still return the partial
+// dump details; a failed purge does not fail the backup.
+func runStatus(ctx context.Context, dumpResp *dumpster.DumpResponse) history.Status {
+	uploaded := dumpResp != nil && dumpResp.Uploaded()
+	switch {
+	case !uploaded && ctx.Err() != nil:
+		return history.StatusCancelled
+	case !uploaded:
+		return history.StatusFailure
+	case dumpResp.Partial():
+		return history.StatusPartial
+	default:
+		return history.StatusSuccess
+	}
+}
+
+func doBackup(ctx context.Context, cfg *config.Config) (err error) {
+	runID := uuid.NewString()
+	startedAt := time.Now()
+	ctx, span := tracing.Start(ctx, "backup",
+		attribute.String("stashly.job", cfg.JobName()),
+		attribute.String("stashly.run_id", runID),
+		attribute.String("stashly.trigger", string(scheduler.TriggerFrom(ctx))))
+	defer func() { tracing.End(span, err) }()
+
+	tail := logging.NewTail(cfg.Heartbeat.TailLines)
+	ctx = logging.WithTail(ctx, tail)
+
+	lockCtx, unlock, err := acquireLock(ctx, cfg)
+	if errors.Is(err, lock.ErrLocked) {
+		slog.InfoContext(ctx, "Backup is running on another instance; skipping", "job", cfg.JobName(), "reason", err)
+		recordRun(ctx, cfg, history.Entry{RunID: runID, Status: history.StatusSkipped, StartedAt: startedAt, Error: err.Error()})
+		return nil
+	}
+	if err != nil {
+		err = fmt.Errorf("error acquiring backup lock: %w", err)
+		newEmitter(ctx, cfg, runID)(events.TypeFailure, nil, err)
+		metrics.FromContext(ctx).ObserveRun(cfg.JobName(), history.StatusFailure, time.Since(startedAt), nil)
+		recordRun(ctx, cfg, history.Entry{RunID: runID, Status: history.StatusFailure, StartedAt: startedAt, Error: err.Error()})
 		return err
 	}
+	defer unlock()
+	ctx = lockCtx
+
+	pinger := heartbeat.NewPinger(cfg.Heartbeat)
+	if hErr := pinger.Start(ctx, runID); hErr != nil {
+		slog.WarnContext(ctx, "Failed to send start heartbeat", "error", hErr)
+	}
+
+	dumpResp, err := runBackup(ctx, cfg, runID)
+
+	entry := history.Entry{RunID: runID, Status: runStatus(ctx, dumpResp), StartedAt: startedAt}
+	if dumpResp != nil {
+		entry.SetDump(dumpResp)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	recordRun(ctx, cfg, entry)
+	span.SetAttributes(attribute.String("stashly.status", string(entry.Status)))
+	metrics.FromContext(ctx).ObserveRun(cfg.JobName(), entry.Status, time.Since(startedAt), dumpResp)
+
+	// Partial backups are reported as failures so monitors alert on missing databases.
+	ctx = context.WithoutCancel(ctx)
+	if err != nil || dumpResp.Partial() {
+		if hErr := pinger.Fail(ctx, runID, tail.String()); hErr != nil {
+			slog.WarnContext(ctx, "Failed to send failure heartbeat", "error", hErr)
+		}
+	} else if hErr := pinger.Success(ctx, runID, tail.String()); hErr != nil {
+		slog.WarnContext(ctx, "Failed to send success heartbeat", "error", hErr)
+	}
+	return err
+}
+
+// lastBackup returns the time of the job's newest backup in storage.
+func lastBackup(ctx context.Context, cfg *config.Config) (time.Time, error) {
+	store := s3.NewS3Storage(cfg)
+	if err := store.Init(); err != nil {
+		return time.Time{}, err
+	}
+	return dumpster.NewDumpster(cfg, store, exec.NewExec()).LastDump(ctx)
+}
+
+func runBackup(ctx context.Context, cfg *config.Config, runID string) (*dumpster.DumpResponse, error) {
+	emit := newEmitter(ctx, cfg, runID)
+
+	slog.InfoContext(ctx, "Starting backup run", "run_id", runID, "job", cfg.JobName())
+	emit(events.TypeStarted, nil, nil)
+
+	store := s3.NewS3Storage(cfg)
+	if err := store.Init(); err != nil {
+		emit(events.TypeFailure, nil, err)
+		return nil, err
+	}
 
 	exec := exec.NewExec()
 	dump := dumpster.NewDumpster(cfg, store, exec)
-	notify := notifiers.NewNotifier(cfg)
-	notify.InitStore()
 
 	// Add new backup
 	dumpResp, err := dump.CreateDump(ctx)
 	if err != nil {
-		if nErr := notify.NotifyBackupFailure(ctx, err); nErr != nil {
-			slog.ErrorContext(ctx, "Failed to send NotifyBackupFailure", "error", nErr)
+		if ctx.Err() != nil {
+			// The cause tells a shutdown apart from a maximum duration or blackout window
+			err = context.Cause(ctx)
+			slog.WarnContext(ctx, "Backup run cancelled", "run_id", runID, "job", cfg.JobName(), "reason", err)
+			emit(events.TypeCancelled, dumpResp, err)
+		} else {
+			emit(events.TypeFailure, dumpResp, err)
 		}
-		return err
+		// The partial results keep the per-database outcome for the history
+		return dumpResp, err
 	}
 
-	databases := dumpResp.ExportedDatabases
-	key := dumpResp.StorageKey
-
-	if nErr := notify.NotifyBackupSuccess(ctx, databases, key); nErr != nil {
-		slog.ErrorContext(ctx, "Failed to send NotifyBackupSuccess", "error", nErr)
+	if dumpResp.Partial() {
+		emit(events.TypePartial, dumpResp, nil)
+	} else {
+		emit(events.TypeSuccess, dumpResp, nil)
 	}
 
 	// Purge old backups
-	if pErr := dump.PurgeDumps(ctx); pErr != nil {
-		if nErr := notify.NotifyBackupDeleteFailure(ctx, pErr); nErr != nil {
-			slog.ErrorContext(ctx, "Failed to send NotifyBackupDeleteFailure", "error", nErr)
-		}
-		return pErr
+	purgeResp, pErr := dump.PurgeDumps(ctx)
+	metrics.FromContext(ctx).ObservePurge(cfg.JobName(), purgeResp)
+	if pErr != nil {
+		emit(events.TypePurgeFailure, dumpResp, pErr)
+		return dumpResp, pErr
 	}
-	return nil
+	emit(events.TypePurgeSuccess, dumpResp, nil)
+	return dumpResp, nil
 }
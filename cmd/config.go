@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Create, inspect and check the configuration",
+}
+
+var (
+	configInitOutput         string
+	configInitForce          bool
+	configInitNonInteractive bool
+	configInitSet            []string
+)
+
+// initPrompts are the settings "config init" asks for, in order. A prompt with onlyIf is only
+// asked if that setting is true.
+var initPrompts = []struct {
+	key, question, onlyIf string
+}{
+	{key: "postgres.host", question: "PostgreSQL host"},
+	{key: "postgres.port", question: "PostgreSQL port"},
+	{key: "postgres.user", question: "PostgreSQL user"},
+	{key: "postgres.password", question: "PostgreSQL password or file://, env://, vault:// reference"},
+	{key: "s3.endpoint", question: "S3 endpoint URL (empty for AWS S3)"},
+	{key: "s3.region", question: "S3 region"},
+	{key: "s3.bucket", question: "S3 bucket"},
+	{key: "s3.prefix", question: "S3 key prefix"},
+	{key: "s3.access-key", question: "S3 access key"},
+	{key: "s3.secret-key", question: "S3 secret key or file://, env://, vault:// reference"},
+	{key: "backup.cron", question: "Backup schedule (cron)"},
+	{key: "backup.timezone", question: "Time zone of the schedule"},
+	{key: "backup.retention-count", question: "Number of backups to keep"},
+	{key: "backup.encrypt", question: "Encrypt backups with GPG (true/false)"},
+	{key: "encryption.gpg.key-server", question: "GPG key server", onlyIf: "backup.encrypt"},
+	{key: "encryption.gpg.key-id", question: "GPG key ID", onlyIf: "backup.encrypt"},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generate a commented config file",
+	Long: `Generate a config file listing every setting with its description and default.
+
+Asks for the essential settings, such as the database and the bucket, unless --non-interactive
+is given. Settings can also be given with --set, e.g. --set s3.bucket=backups, which skips their
+question. The file is written to --output ("-" for stdout) and is not overwritten unless --force
+is given. Settings that still need attention, such as a missing bucket, are listed afterwards.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+
+		settings := map[string]string{}
+		for _, set := range configInitSet {
+			key, value, ok := strings.Cut(set, "=")
+			if !ok {
+				slog.ErrorContext(ctx, "Invalid --set; want key=value", "set", set)
+				os.Exit(1)
+			}
+			settings[key] = value
+		}
+		if _, err := config.NewConfig(settings); err != nil {
+			slog.ErrorContext(ctx, "Invalid --set", "error", err)
+			os.Exit(1)
+		}
+		if !configInitForce && configInitOutput != "-" {
+			if _, err := os.Stat(configInitOutput); err == nil {
+				slog.ErrorContext(ctx, "Config file already exists; use --force to overwrite it", "file", configInitOutput)
+				os.Exit(1)
+			}
+		}
+		if !configInitNonInteractive {
+			if err := promptSettings(cmd.InOrStdin(), cmd.ErrOrStderr(), settings); err != nil {
+				slog.ErrorContext(ctx, "Failed to read answers", "error", err)
+				os.Exit(1)
+			}
+		}
+
+		data, err := config.Sample(settings)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to generate config", "error", err)
+			os.Exit(1)
+		}
+		if err = writeConfigFile(configInitOutput, data, configInitForce); err != nil {
+			slog.ErrorContext(ctx, "Failed to write config", "error", err)
+			os.Exit(1)
+		}
+		if configInitOutput != "-" {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %s\n", configInitOutput)
+		}
+
+		cfg, _ := config.NewConfig(settings)
+		for _, fe := range validationErrors(cfg.Validate()) {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Needs attention: %s\n", fe.Error())
+		}
+	},
+}
+
+// promptSettings asks for the settings in initPrompts that are not set yet. An empty answer keeps
+// the default; an invalid answer is asked again.
+func promptSettings(in io.Reader, out io.Writer, settings map[string]string) error {
+	r := bufio.NewReader(in)
+	for _, p := range initPrompts {
+		if _, ok := settings[p.key]; ok {
+			continue
+		}
+		if p.onlyIf != "" {
+			value, ok := settings[p.onlyIf]
+			if !ok {
+				value = config.Default(p.onlyIf)
+			}
+			if enabled, _ := strconv.ParseBool(value); !enabled {
+				continue
+			}
+		}
+
+		for {
+			if def := config.Default(p.key); def != "" {
+				_, _ = fmt.Fprintf(out, "%s [%s]: ", p.question, def)
+			} else {
+				_, _ = fmt.Fprintf(out, "%s: ", p.question)
+			}
+			answer, err := r.ReadString('\n')
+			answer = strings.TrimSpace(answer)
+			if err != nil && (!errors.Is(err, io.EOF) || answer != "") {
+				return err
+			}
+			if answer == "" {
+				if err != nil {
+					// Keep the defaults of the remaining settings once the input ends
+					return nil
+				}
+				break
+			}
+			settings[p.key] = answer
+			if _, err = config.NewConfig(settings); err == nil {
+				break
+			}
+			delete(settings, p.key)
+			_, _ = fmt.Fprintf(out, "Invalid %s: %v\n", p.key, err)
+		}
+	}
+	return nil
+}
+
+// writeConfigFile writes a generated config file, readable only by its owner as it may contain
+// secrets. An existing file is only replaced if force is set; "-" writes to stdout.
+func writeConfigFile(path string, data []byte, force bool) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o600) //nolint:gosec // the path is given by the operator
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists; use --force to overwrite it", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration with secrets redacted",
+	Long: `Print the configuration as the daemon sees it: the config file merged with environment
+variables and defaults, after resolving secret references. Passwords, tokens and other secrets
+are replaced with ` + config.Redacted + `, and settings overridden by an environment variable are
+marked with the variable's name.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+
+		cfg, err := config.LoadConfig(ctx, cfgFile)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load config", "error", err)
+			os.Exit(1)
+		}
+
+		annotations := map[string]string{}
+		for key, envVar := range config.EnvOverrides() {
+			annotations[key] = "from $" + envVar
+		}
+		data, err := config.Encode(cfg, config.EncodeOptions{Redact: true, Annotations: annotations})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to encode config", "error", err)
+			os.Exit(1)
+		}
+		_, _ = os.Stdout.Write(data)
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a JSON Schema of the config file",
+	Long: `Print a JSON Schema of the config file, generated from the configuration structs, for editors
+and CI to validate config files against. For example, with the YAML language server:
+
+  stashly config schema > stashly.schema.json
+  # and add to the top of config.yaml:
+  # yaml-language-server: $schema=./stashly.schema.json`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.Schema()); err != nil {
+			slog.ErrorContext(cmd.Context(), "Failed to encode schema", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration without connecting to anything",
+	Long: `Load the configuration from the config file and environment and report every invalid setting,
+such as a malformed cron expression, a retention count below 1, a missing bucket or encryption
+enabled without a GPG key. Exits non-zero if the configuration is invalid.
+
+Use "stashly doctor" to also check connectivity and permissions.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+
+		cfg, err := config.LoadConfig(ctx, cfgFile)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load config", "error", err)
+			os.Exit(1)
+		}
+
+		fieldErrs := validationErrors(cfg.Validate())
+		if len(fieldErrs) == 0 {
+			_, _ = fmt.Fprintln(os.Stdout, "Configuration is valid")
+			return
+		}
+		for _, fe := range fieldErrs {
+			_, _ = fmt.Fprintln(os.Stderr, fe.Error())
+		}
+		os.Exit(1)
+	},
+}
+
+// validationErrors returns the invalid settings reported by config.Validate.
+func validationErrors(err error) []config.FieldError {
+	if err == nil {
+		return nil
+	}
+	var vErr *config.ValidationError
+	if errors.As(err, &vErr) {
+		return vErr.Errors
+	}
+	return []config.FieldError{{Field: "config", Message: err.Error()}}
+}
+
+// loadValidConfig loads the configuration and exits if it cannot be loaded or is invalid, so a
+// backup never runs with a feature, such as encryption, silently missing.
+func loadValidConfig(ctx context.Context) *config.Config {
+	cfg, err := config.LoadConfig(ctx, cfgFile)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load config", "error", err)
+		os.Exit(1)
+	}
+	if fieldErrs := validationErrors(cfg.Validate()); len(fieldErrs) > 0 {
+		for _, fe := range fieldErrs {
+			slog.ErrorContext(ctx, "Invalid config", "field", fe.Field, "error", fe.Message)
+		}
+		os.Exit(1)
+	}
+	return cfg
+}
+
+func init() {
+	configInitCmd.Flags().StringVarP(&configInitOutput, "output", "o", "config.yaml", `file to write, or "-" for stdout`)
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "overwrite an existing file")
+	configInitCmd.Flags().BoolVar(&configInitNonInteractive, "non-interactive", false, "do not ask for settings")
+	configInitCmd.Flags().StringArrayVar(&configInitSet, "set", nil, "set a setting, e.g. --set s3.bucket=backups (repeatable)")
+
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
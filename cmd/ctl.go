@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/control"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// ctlSocket overrides the control socket path from the config.
+	ctlSocket string
+
+	// ctlJob is the job to trigger; empty triggers every job.
+	ctlJob string
+
+	// ctlJSON prints the status as JSON.
+	ctlJSON bool
+)
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control a running stashly daemon",
+}
+
+var ctlTriggerCmd = &cobra.Command{
+	Use:   "trigger",
+	Short: "Start an immediate backup in the running daemon",
+	Long: `Start an immediate backup through the running daemon's scheduler. If the job is already
+running, the backup starts as soon as the current run finishes, so only one backup runs at a time.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+		if err := ctlClient(cmd).Trigger(ctx, ctlJob); err != nil {
+			slog.ErrorContext(ctx, "Failed to trigger backup", "error", err)
+			os.Exit(1)
+		}
+		slog.InfoContext(ctx, "Backup triggered", "job", ctlJob)
+	},
+}
+
+var ctlStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the running daemon's jobs",
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+		statuses, err := ctlClient(cmd).Status(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get status", "error", err)
+			os.Exit(1)
+		}
+
+		if ctlJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(statuses)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "JOB\tSTATE\tNEXT RUN\tLAST FINISHED\tLAST ERROR")
+		for _, s := range statuses {
+			state := "idle"
+			switch {
+			case s.Running && s.Queued:
+				state = "running (queued)"
+			case s.Running:
+				state = "running"
+			case s.Deferred:
+				state = "deferred"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Name, state,
+				formatTime(s.NextRun), formatTime(s.LastFinished), s.LastError)
+		}
+		_ = w.Flush()
+	},
+}
+
+// ctlClient returns a client for the control socket given by --socket or the config.
+func ctlClient(cmd *cobra.Command) *control.Client {
+	socket := ctlSocket
+	if socket == "" {
+		cfg, err := config.LoadConfig(cmd.Context(), cfgFile)
+		if err != nil {
+			slog.ErrorContext(cmd.Context(), "Failed to load config", "error", err)
+			os.Exit(1)
+		}
+		socket = cfg.Control.Socket
+	}
+	return control.NewClient(socket)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func init() {
+	ctlCmd.PersistentFlags().StringVar(&ctlSocket, "socket", "", "control socket path (default from config)")
+	ctlTriggerCmd.Flags().StringVar(&ctlJob, "job", "", "trigger only the named job (default all jobs)")
+	ctlStatusCmd.Flags().BoolVar(&ctlJSON, "json", false, "print the status as JSON")
+	ctlCmd.AddCommand(ctlTriggerCmd, ctlStatusCmd)
+	rootCmd.AddCommand(ctlCmd)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/exec"
+	"github.com/hibare/stashly/internal/notifiers"
+	"github.com/hibare/stashly/internal/storage/s3"
+	"github.com/spf13/cobra"
+)
+
+// Statuses printed by the doctor command.
+const (
+	doctorPass = "PASS"
+	doctorFail = "FAIL"
+	doctorSkip = "SKIP"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the whole backup pipeline without backing up",
+	Long: `Check every stage of each backup job without dumping anything:
+
+  - the configuration is valid (see "stashly config validate")
+  - psql and pg_dump are installed and pg_dump is not older than the server
+  - the credentials are valid and have the CONNECT privilege on every selected database
+  - the bucket exists and a probe object can be written, read, listed and deleted
+  - the GPG key can be downloaded, if encryption is enabled
+  - every enabled notifier delivers a test message, sent once rather than per job
+  - the workspace has room for the estimated size of the dumps
+
+Prints a table of the results and exits non-zero if any check fails.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+
+		cfg, err := config.LoadConfig(ctx, cfgFile)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load config", "error", err)
+			os.Exit(1)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "JOB\tCHECK\tSTATUS\tDETAIL")
+		failed := false
+		row := func(job, check string, err error, skipped bool, detail string) {
+			status := doctorPass
+			switch {
+			case skipped:
+				status = doctorSkip
+			case err != nil:
+				status = doctorFail
+				failed = true
+			}
+			if err != nil {
+				if detail != "" {
+					detail += ": "
+				}
+				detail += err.Error()
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", job, check, status, detail)
+		}
+
+		fieldErrs := validationErrors(cfg.Validate())
+		for _, fe := range fieldErrs {
+			row("-", "config", errors.New(fe.Message), false, fe.Field)
+		}
+		if len(fieldErrs) == 0 {
+			row("-", "config", nil, false, "valid")
+		}
+
+		for _, job := range cfg.BackupJobs() {
+			d := dumpster.NewDumpster(job, s3.NewS3Storage(job), exec.NewExec())
+			for _, r := range d.Preflight(ctx) {
+				row(job.JobName(), r.Name, r.Err, r.Skipped(), r.Detail)
+			}
+		}
+
+		// Notifiers are shared by the jobs, so each gets a single test message
+		notify := notifiers.NewNotifier(cfg)
+		notify.InitStore()
+		if !notify.Enabled() {
+			row("-", "notifiers", nil, true, "notifiers disabled")
+		}
+		for _, r := range notify.Test(ctx) {
+			row("-", "notifier "+r.Name, r.Err, false, "test message")
+		}
+		_ = w.Flush()
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
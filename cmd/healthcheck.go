@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/exec"
+	"github.com/hibare/stashly/internal/health"
+	"github.com/hibare/stashly/internal/scheduler"
+	"github.com/hibare/stashly/internal/storage/s3"
+	"github.com/spf13/cobra"
+)
+
+// Probe paths served on the daemon's HTTP listener.
+const (
+	livenessPath  = "/healthz"
+	readinessPath = "/readyz"
+)
+
+var (
+	// healthcheckURL overrides the probe URL derived from the config.
+	healthcheckURL string
+
+	// healthcheckReady probes readiness instead of liveness.
+	healthcheckReady bool
+
+	// healthcheckTimeout bounds the probe request.
+	healthcheckTimeout time.Duration
+)
+
+var healthcheckCmd = &cobra.Command{
+	Use:   "healthcheck",
+	Short: "Probe the running daemon's health endpoint",
+	Long: `Probe the running daemon's /healthz endpoint (or /readyz with --ready) and exit non-zero
+if it is unhealthy. Without --url the address is read from http.listen, without resolving secrets,
+and the check passes if http.enabled is false. The Docker image uses it as its HEALTHCHECK:
+
+  HEALTHCHECK CMD ["/bin/stashly", "healthcheck"]`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx, cancel := context.WithTimeout(cmd.Context(), healthcheckTimeout)
+		defer cancel()
+
+		url := healthcheckURL
+		if url == "" {
+			// Secrets are not needed for the listen address, and an unavailable secret provider
+			// must not mark a healthy daemon unhealthy
+			cfg, err := config.LoadConfigWithoutSecrets(ctx, cfgFile)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to load config", "error", err)
+				os.Exit(1)
+			}
+			// The image's HEALTHCHECK must not fail deployments that keep the listener disabled
+			if !cfg.HTTP.Enabled {
+				slog.InfoContext(ctx, "HTTP listener disabled; nothing to probe")
+				return
+			}
+			path := livenessPath
+			if healthcheckReady {
+				path = readinessPath
+			}
+			if url, err = health.LocalURL(cfg.HTTP.Listen, path); err != nil {
+				slog.ErrorContext(ctx, "Failed to build probe URL", "error", err)
+				os.Exit(1)
+			}
+		}
+
+		resp, err := health.Probe(ctx, url)
+		if err != nil {
+			args := []any{"url", url, "error", err}
+			if resp != nil {
+				args = append(args, "checks", resp.Checks)
+			}
+			slog.ErrorContext(ctx, "Health check failed", args...)
+			os.Exit(1)
+		}
+		slog.DebugContext(ctx, "Health check passed", "url", url, "checks", resp.Checks)
+	},
+}
+
+// livenessChecks report whether the daemon's scheduler is running and starting jobs on time.
+func livenessChecks(sched *scheduler.Scheduler) []health.Check {
+	return []health.Check{{
+		Name:  "scheduler",
+		Check: func(context.Context) error { return sched.Healthy() },
+	}}
+}
+
+// readinessChecks extend the liveness checks with the Postgres connection, storage and, if
+// http.ready-max-age is set, the age of the newest backup of each job. startedAt is when the daemon started.
+func readinessChecks(cfg *config.Config, sched *scheduler.Scheduler, startedAt time.Time) []health.Check {
+	checks := livenessChecks(sched)
+	for _, job := range cfg.BackupJobs() {
+		name := job.JobName()
+		checks = append(checks,
+			health.Check{
+				Name: "postgres/" + name,
+				Check: func(ctx context.Context) error {
+					return dumpster.NewDumpster(job, nil, exec.NewExec()).Ping(ctx)
+				},
+			},
+			health.Check{
+				Name: "storage/" + name,
+				Check: func(ctx context.Context) error {
+					store := s3.NewS3Storage(job)
+					if err := store.Init(); err != nil {
+						return err
+					}
+					return store.Ping(ctx)
+				},
+			},
+		)
+		if cfg.HTTP.ReadyMaxAge > 0 {
+			checks = append(checks, health.Check{
+				Name: "last-backup/" + name,
+				Check: health.MaxAge(cfg.HTTP.ReadyMaxAge, startedAt, func(ctx context.Context) (time.Time, error) {
+					return lastBackup(ctx, job)
+				}),
+			})
+		}
+	}
+	return checks
+}
+
+func init() {
+	healthcheckCmd.Flags().StringVar(&healthcheckURL, "url", "", "probe URL (default from http.listen in the config)")
+	healthcheckCmd.Flags().BoolVar(&healthcheckReady, "ready", false, "probe readiness (/readyz) instead of liveness")
+	healthcheckCmd.Flags().DurationVar(&healthcheckTimeout, "timeout", 30*time.Second, "timeout for the probe")
+	rootCmd.AddCommand(healthcheckCmd)
+}
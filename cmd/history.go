@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/events"
+	"github.com/hibare/stashly/internal/history"
+	"github.com/spf13/cobra"
+)
+
+// Output formats supported by the history command.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var (
+	historyFailed bool
+	historySince  string
+	historyUntil  string
+	historyJob    string
+	historyOutput string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show past backup runs",
+	Long: `Show past backup runs recorded in the local history, oldest first.
+
+--since and --until accept an age (7d, 36h), a date (2024-07-01) or an RFC 3339 timestamp.
+For example, every failed run of the nightly job in Q3:
+
+  stashly history --job nightly --failed --since 2024-07-01 --until 2024-10-01`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+
+		cfg, err := config.LoadConfig(ctx, cfgFile)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load config", "error", err)
+			os.Exit(1)
+		}
+
+		now := time.Now()
+		filter := history.Filter{Job: historyJob, FailedOnly: historyFailed}
+		if filter.Since, err = history.ParseTime(historySince, now); err != nil {
+			slog.ErrorContext(ctx, "Invalid --since", "error", err)
+			os.Exit(1)
+		}
+		if filter.Until, err = history.ParseTime(historyUntil, now); err != nil {
+			slog.ErrorContext(ctx, "Invalid --until", "error", err)
+			os.Exit(1)
+		}
+
+		entries, err := history.NewFile(cfg.History.Path).Entries(filter)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to read history", "error", err)
+			os.Exit(1)
+		}
+
+		switch historyOutput {
+		case outputJSON:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(entries)
+		case outputTable:
+			printHistory(entries)
+		default:
+			slog.ErrorContext(ctx, "Unknown output format", "output", historyOutput)
+			os.Exit(1)
+		}
+	},
+}
+
+func printHistory(entries []history.Entry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STARTED\tJOB\tTRIGGER\tSTATUS\tDURATION\tDATABASES\tSIZE\tKEY / ERROR")
+	for _, e := range entries {
+		databases, size := "-", "-"
+		if len(e.Databases) > 0 {
+			ok := 0
+			for _, db := range e.Databases {
+				if db.Status == history.DatabaseOK {
+					ok++
+				}
+			}
+			databases = strconv.Itoa(ok) + "/" + strconv.Itoa(len(e.Databases))
+		}
+		if e.Bytes > 0 {
+			size = events.FormatBytes(e.Bytes)
+		}
+		detail := e.Key
+		if e.Error != "" {
+			detail = e.Error
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.StartedAt.Local().Format(time.DateTime), e.Job,
+			e.Trigger, e.Status, e.Duration().Round(time.Second), databases, size, detail)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	historyCmd.Flags().BoolVar(&historyFailed, "failed", false, "show only attempted runs that did not fully succeed (partial, failure or cancelled)")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "show runs started at or after this time")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "show runs started before this time")
+	historyCmd.Flags().StringVar(&historyJob, "job", "", "show only runs of the named job")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", outputTable, "output format: table or json")
+	rootCmd.AddCommand(historyCmd)
+}
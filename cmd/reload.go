@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/scheduler"
+)
+
+// reloadDebounce coalesces the several events editors and ConfigMap updates emit for one change.
+const reloadDebounce = 500 * time.Millisecond
+
+// watchConfig reloads the configuration on a reload signal (SIGHUP) and whenever the config file changes.
+func watchConfig(ctx context.Context, current *atomic.Pointer[config.Config], sched *scheduler.Scheduler) {
+	changes := make(chan struct{}, 1)
+	if err := config.Watch(ctx, cfgFile, func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}); err != nil {
+		slog.WarnContext(ctx, "Not watching config file for changes", "error", err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(sigs, reloadSignals...)
+		defer signal.Stop(sigs)
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigs:
+			slog.InfoContext(ctx, "Received reload signal", "signal", sig.String())
+			_ = reloadConfig(ctx, current, sched)
+		case <-changes:
+			debounce = time.After(reloadDebounce)
+		case <-debounce:
+			debounce = nil
+			_ = reloadConfig(ctx, current, sched)
+		}
+	}
+}
+
+// errInvalidConfig is returned by reloadConfig for a configuration that fails validation.
+var errInvalidConfig = errors.New("invalid config")
+
+// reloadConfig loads and validates the configuration and, if it is valid, reschedules the jobs and
+// makes it current. Runs in progress keep the configuration they started with; an invalid
+// configuration is rejected and the daemon keeps running with the current one.
+func reloadConfig(ctx context.Context, current *atomic.Pointer[config.Config], sched *scheduler.Scheduler) error {
+	cfg, err := config.LoadConfig(ctx, cfgFile)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to reload config; keeping current config", "error", err)
+		return err
+	}
+	if fieldErrs := validationErrors(cfg.Validate()); len(fieldErrs) > 0 {
+		for _, fe := range fieldErrs {
+			slog.ErrorContext(ctx, "Invalid config; keeping current config", "field", fe.Field, "error", fe.Message)
+		}
+		return errInvalidConfig
+	}
+
+	jobs := scheduledJobs(cfg)
+	if err = sched.Replace(cfg.Backup.Location(), jobs); err != nil {
+		slog.ErrorContext(ctx, "Failed to reschedule backups; keeping current config", "error", err)
+		return err
+	}
+	previous := current.Swap(cfg)
+
+	for _, setting := range restartRequired(previous, cfg) {
+		slog.WarnContext(ctx, "Config change takes effect after a restart", "setting", setting)
+	}
+	for _, job := range jobs {
+		slog.InfoContext(ctx, "Rescheduled backup", "job", job.Name, "cron", job.Cron, "timezone", cfg.Backup.Location().String())
+	}
+	slog.InfoContext(ctx, "Reloaded config", "jobs", len(jobs))
+	return nil
+}
+
+// restartRequired returns the changed settings that are only read at startup, such as listen addresses.
+func restartRequired(previous, cfg *config.Config) []string {
+	settings := []string{}
+	for name, changed := range map[string]bool{
+		"control": !reflect.DeepEqual(previous.Control, cfg.Control),
+		"http.enabled, http.listen": previous.HTTP.Enabled != cfg.HTTP.Enabled ||
+			previous.HTTP.Listen != cfg.HTTP.Listen,
+		"api":                          !reflect.DeepEqual(previous.API, cfg.API),
+		"backup.shutdown-grace-period": previous.Backup.ShutdownGracePeriod != cfg.Backup.ShutdownGracePeriod,
+	} {
+		if changed {
+			settings = append(settings, name)
+		}
+	}
+	slices.Sort(settings)
+	return settings
+}
@@ -3,15 +3,26 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
-	"github.com/go-co-op/gocron"
 	"github.com/spf13/cobra"
 
 	commonLogger "github.com/hibare/GoCommon/v2/pkg/logger"
 	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/control"
+	"github.com/hibare/stashly/internal/health"
+	"github.com/hibare/stashly/internal/history"
+	"github.com/hibare/stashly/internal/metrics"
+	"github.com/hibare/stashly/internal/scheduler"
+	"github.com/hibare/stashly/internal/server"
+	"github.com/hibare/stashly/internal/tracing"
 )
 
 // cfgFile holds the path to the config file.
@@ -29,47 +40,179 @@ With Stashly, you can:
   - Schedule recurring PostgreSQL backups with flexible cron syntax
   - Automatically upload dumps to cloud storage backends
   - Get notified of backup failures through integrated notifiers
-  - Run in the background as a long-lived process.`,
+  - Run in the background as a long-lived process.
+
+With http.enabled, Prometheus metrics and health probes are served on http.listen. That listener is
+unauthenticated and does not serve the web dashboard, which reads the REST API; run "stashly serve"
+for the API and dashboard.`,
 	Run: func(cmd *cobra.Command, _ []string) {
 		// start cron job that runs Dump according to config.
-		// cron runs in background; block forever.
+		// cron runs in background; block until SIGINT/SIGTERM.
 		ctx := cmd.Context()
 
 		// Load config.
-		cfg, err := config.LoadConfig(ctx, cfgFile)
-		if err != nil {
-			slog.ErrorContext(ctx, "Failed to load config", "error", err)
+		cfg := loadValidConfig(ctx)
+
+		if err := runDaemon(ctx, cfg, false); err != nil {
 			os.Exit(1)
 		}
+	},
+}
 
-		slog.InfoContext(ctx, "Starting scheduled backup", "cron", cfg.Backup.Cron)
-		scheduler := gocron.NewScheduler(time.UTC)
-		_, err = scheduler.Cron(cfg.Backup.Cron).Do(func() {
-			if bErr := doBackup(ctx, cfg); bErr != nil {
-				slog.ErrorContext(ctx, "Scheduled backup failed", "error", bErr)
-			} else {
-				slog.InfoContext(ctx, "Scheduled backup completed successfully")
-			}
-		})
+// runDaemon schedules the configured jobs and blocks until ctx is cancelled. With serveAPI, the
+// REST API is served as well and failing to start it is fatal.
+func runDaemon(ctx context.Context, cfg *config.Config, serveAPI bool) error {
+	var m *metrics.Metrics
+	if cfg.HTTP.Enabled {
+		m = metrics.New()
+		ctx = metrics.WithMetrics(ctx, m)
+	}
+
+	// Reloads swap the configuration atomically; each run uses the configuration it was scheduled with
+	current := &atomic.Pointer[config.Config]{}
+	current.Store(cfg)
+
+	sched := scheduler.New(ctx, cfg.Backup.Location(), cfg.Backup.ShutdownGracePeriod)
+	for _, sj := range scheduledJobs(cfg) {
+		slog.InfoContext(ctx, "Starting scheduled backup",
+			"job", sj.Name, "cron", sj.Cron, "timezone", cfg.Backup.Location().String())
+		if err := sched.Add(sj); err != nil {
+			slog.ErrorContext(ctx, "Failed to schedule backup", "job", sj.Name, "error", err)
+		}
+	}
+	if cfg.Control.Enabled {
+		srv := control.NewServer(cfg.Control.Socket, sched)
+		if sErr := srv.Start(ctx); sErr != nil {
+			slog.WarnContext(ctx, "Control socket unavailable; on-demand triggers disabled", "error", sErr)
+		} else {
+			defer func() {
+				if cErr := srv.Close(context.WithoutCancel(ctx)); cErr != nil {
+					slog.WarnContext(ctx, "Failed to close control socket", "error", cErr)
+				}
+			}()
+		}
+	}
+	if cfg.HTTP.Enabled {
+		m.WatchScheduler(sched)
+		srv := server.New(cfg.HTTP.Listen)
+		srv.Handle("GET /metrics", m.Handler())
+		srv.Handle("GET /healthz", health.Handler(livenessChecks(sched)...))
+		startedAt := time.Now()
+		srv.Handle("GET /readyz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			health.Handler(readinessChecks(current.Load(), sched, startedAt)...).ServeHTTP(w, r)
+		}))
+		if sErr := srv.Start(ctx); sErr != nil {
+			slog.WarnContext(ctx, "HTTP listener unavailable; metrics and health probes disabled", "error", sErr)
+		} else {
+			defer func() {
+				if cErr := srv.Close(context.WithoutCancel(ctx)); cErr != nil {
+					slog.WarnContext(ctx, "Failed to close HTTP listener", "error", cErr)
+				}
+			}()
+		}
+	}
+	if serveAPI {
+		srv, err := startAPI(ctx, current, sched)
 		if err != nil {
-			slog.ErrorContext(ctx, "Failed to schedule backup", "error", err)
+			slog.ErrorContext(ctx, "Failed to start REST API", "error", err)
+			return err
 		}
-		scheduler.StartBlocking()
-	},
+		defer func() {
+			if cErr := srv.Close(context.WithoutCancel(ctx)); cErr != nil {
+				slog.WarnContext(ctx, "Failed to close REST API listener", "error", cErr)
+			}
+		}()
+	}
+	go triggerOnSignal(ctx, sched)
+	go watchConfig(ctx, current, sched)
+
+	sched.Run(ctx)
+	slog.InfoContext(ctx, "Scheduler stopped")
+	return nil
+}
+
+// scheduledJobs returns the scheduler jobs for the configured backup jobs. Each run builds its
+// storage and notifiers from the job's configuration, so a reload takes effect from the next run.
+func scheduledJobs(cfg *config.Config) []scheduler.Job {
+	jobs := []scheduler.Job{}
+	for _, job := range cfg.BackupJobs() {
+		sj := scheduler.Job{
+			Name:   job.JobName(),
+			Cron:   job.Backup.Cron,
+			Jitter: job.Backup.Jitter,
+			Queue:  job.Backup.Overlap == config.OverlapQueue,
+			Run: func(runCtx context.Context) error {
+				return doBackup(runCtx, job)
+			},
+			Blackouts:   cfg.Maintenance.Windows(job.JobName()),
+			MaxDuration: cfg.Maintenance.MaxDuration,
+			OnBlackout: func(runCtx context.Context, window string, until time.Time, deferred bool) {
+				notifyBlackout(runCtx, job, window, until, deferred)
+				if !deferred {
+					recordRun(runCtx, job, history.Entry{
+						Status:    history.StatusSkipped,
+						StartedAt: time.Now(),
+						Error:     fmt.Sprintf("blackout window %q active until %s", window, until.Format(time.RFC3339)),
+					})
+				}
+			},
+		}
+		if job.Backup.CatchUp {
+			sj.LastRun = func(runCtx context.Context) (time.Time, error) {
+				return lastBackup(runCtx, job)
+			}
+		}
+		jobs = append(jobs, sj)
+	}
+	return jobs
+}
+
+// triggerOnSignal starts an immediate run of every job whenever a trigger signal (SIGUSR1) is received.
+func triggerOnSignal(ctx context.Context, sched *scheduler.Scheduler) {
+	if len(triggerSignals) == 0 {
+		return
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, triggerSignals...)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigs:
+			slog.InfoContext(ctx, "Received trigger signal", "signal", sig.String())
+			if err := sched.Trigger(""); err != nil {
+				slog.WarnContext(ctx, "Failed to trigger backup", "error", err)
+			}
+		}
+	}
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// SIGINT and SIGTERM cancel the command context so running backups can shut down cleanly.
+// Traces are exported if configured through the OTEL_* environment variables.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	shutdownTracing, tErr := tracing.Setup(ctx)
+	if tErr != nil {
+		slog.WarnContext(ctx, "Failed to set up tracing; spans will not be exported", "error", tErr)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+
+	if tErr = shutdownTracing(context.Background()); tErr != nil {
+		slog.Warn("Failed to flush traces", "error", tErr)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
 func init() {
-	ctx := context.Background()
-	rootCmd.SetContext(ctx)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is /etc/stashly/config.yaml)")
 	cobra.OnInitialize(commonLogger.InitDefaultLogger)
 }
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+
+	"github.com/hibare/stashly/internal/api"
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/dashboard"
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/exec"
+	"github.com/hibare/stashly/internal/history"
+	"github.com/hibare/stashly/internal/scheduler"
+	"github.com/hibare/stashly/internal/server"
+	"github.com/hibare/stashly/internal/storage/s3"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run scheduled backups and serve the REST API",
+	Long: `Run the daemon like the root command and additionally serve a JSON REST API on api.listen for
+listing, downloading, pinning and deleting backups, triggering runs and querying their status.
+Unless api.dashboard is false, a web dashboard is served at the root of the same listener. It is
+not served on http.listen, as it needs the token-protected API on the same origin.
+
+Every request must carry "Authorization: Bearer <api.token>". Set api.tls-cert and api.tls-key to
+serve the API over HTTPS.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+
+		cfg := loadValidConfig(ctx)
+		if err := validateAPIConfig(cfg.API); err != nil {
+			slog.ErrorContext(ctx, "Invalid REST API config", "error", err)
+			os.Exit(1)
+		}
+
+		if err := runDaemon(ctx, cfg, true); err != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+// validateAPIConfig refuses to serve the API without authentication or with half a TLS key pair.
+func validateAPIConfig(cfg config.APIConfig) error {
+	switch {
+	case cfg.Listen == "":
+		return errors.New("api.listen is not set")
+	case cfg.Token == "":
+		return errors.New("api.token is not set")
+	case (cfg.TLSCert == "") != (cfg.TLSKey == ""):
+		return errors.New("api.tls-cert and api.tls-key must be set together")
+	default:
+		return nil
+	}
+}
+
+// startAPI serves the REST API for the daemon's jobs. Jobs are looked up in the current configuration,
+// so reloads apply; the listener, token and TLS settings apply after a restart.
+func startAPI(ctx context.Context, current *atomic.Pointer[config.Config], sched *scheduler.Scheduler) (*server.Server, error) {
+	cfg := current.Load()
+	opts := api.Options{
+		Token:     cfg.API.Token,
+		Scheduler: sched,
+		Backups: func(name string) (api.Backups, error) {
+			for _, job := range current.Load().BackupJobs() {
+				if job.JobName() != name {
+					continue
+				}
+				store := s3.NewS3Storage(job)
+				if err := store.Init(); err != nil {
+					return nil, err
+				}
+				return dumpster.NewDumpster(job, store, exec.NewExec()), nil
+			}
+			return nil, fmt.Errorf("%w: %s", scheduler.ErrUnknownJob, name)
+		},
+	}
+	if cfg.History.Enabled {
+		opts.History = history.NewFile(cfg.History.Path)
+	}
+
+	srv := server.New(cfg.API.Listen)
+	srv.Handle(api.Prefix+"/", api.NewHandler(opts))
+	if cfg.API.Dashboard {
+		srv.Handle("/", dashboard.Handler())
+	}
+	if cfg.API.TLSCert != "" {
+		srv.UseTLS(cfg.API.TLSCert, cfg.API.TLSKey)
+	}
+	if err := srv.Start(ctx); err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+}
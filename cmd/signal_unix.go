@@ -0,0 +1,14 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// triggerSignals start an immediate backup in the running daemon.
+var triggerSignals = []os.Signal{syscall.SIGUSR1}
+
+// reloadSignals reload the configuration of the running daemon.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,9 @@
+package cmd
+
+import "os"
+
+// triggerSignals is empty on Windows, which has no SIGUSR1; use the control socket instead.
+var triggerSignals []os.Signal
+
+// reloadSignals is empty on Windows, which has no SIGHUP; config file changes are still picked up.
+var reloadSignals []os.Signal
@@ -0,0 +1,385 @@
+// Package api serves the JSON REST API of "stashly serve" for listing, triggering, downloading,
+// pinning and deleting backups.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/history"
+	"github.com/hibare/stashly/internal/scheduler"
+	"github.com/hibare/stashly/internal/storage"
+)
+
+// Prefix is the path under which the API is served.
+const Prefix = "/api/v1"
+
+const (
+	// maxBodySize bounds the size of request bodies.
+	maxBodySize = 64 << 10
+
+	// sessionCookie holds the session ID of a signed-in dashboard.
+	sessionCookie = "stashly_session"
+)
+
+// Scheduler is the part of the scheduler exposed by the API.
+type Scheduler interface {
+	Trigger(job string) error
+	Status() []scheduler.JobStatus
+}
+
+// Backups are the backup operations of a single job.
+type Backups interface {
+	ListBackups(ctx context.Context) ([]dumpster.Backup, error)
+	GetBackup(ctx context.Context, id string) (*dumpster.Backup, error)
+	OpenBackup(ctx context.Context, id string) (io.ReadCloser, storage.ObjectInfo, error)
+	DeleteBackup(ctx context.Context, id string) error
+	PinBackup(ctx context.Context, id, label string) error
+	UnpinBackup(ctx context.Context, id string) error
+}
+
+// History returns past runs.
+type History interface {
+	Entries(filter history.Filter) ([]history.Entry, error)
+}
+
+// Options configures the API handler.
+type Options struct {
+	// Token is the bearer token every request must present.
+	Token string
+
+	Scheduler Scheduler
+
+	// Backups returns the backup operations of the named job, or an error wrapping
+	// scheduler.ErrUnknownJob if there is no such job.
+	Backups func(job string) (Backups, error)
+
+	// History is nil if the run history is disabled.
+	History History
+}
+
+// Manifest describes a backup: its objects, pin and, if recorded, the run that created it.
+type Manifest struct {
+	dumpster.Backup
+
+	Run *history.Entry `json:"run,omitempty"`
+}
+
+// sessionRequest is the body of a sign-in request.
+type sessionRequest struct {
+	Token string `json:"token"`
+}
+
+// pinRequest is the optional body of a pin request.
+type pinRequest struct {
+	Label string `json:"label"`
+}
+
+// errorResponse is the body of failed requests.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type handler struct {
+	opts     Options
+	sessions *sessions
+}
+
+// NewHandler returns the API handler. Every request must carry "Authorization: Bearer <token>" or the
+// session cookie set by signing in at POST /api/v1/session.
+func NewHandler(opts Options) http.Handler {
+	h := &handler{opts: opts, sessions: newSessions(sessionTTL)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+Prefix+"/jobs", h.listJobs)
+	mux.HandleFunc("POST "+Prefix+"/jobs/{job}/trigger", h.trigger)
+	mux.HandleFunc("GET "+Prefix+"/jobs/{job}/backups", h.listBackups)
+	mux.HandleFunc("GET "+Prefix+"/jobs/{job}/backups/{id}", h.getBackup)
+	mux.HandleFunc("DELETE "+Prefix+"/jobs/{job}/backups/{id}", h.deleteBackup)
+	mux.HandleFunc("GET "+Prefix+"/jobs/{job}/backups/{id}/download", h.download)
+	mux.HandleFunc("PUT "+Prefix+"/jobs/{job}/backups/{id}/pin", h.pin)
+	mux.HandleFunc("DELETE "+Prefix+"/jobs/{job}/backups/{id}/pin", h.unpin)
+	mux.HandleFunc("GET "+Prefix+"/runs", h.listRuns)
+
+	root := http.NewServeMux()
+	root.HandleFunc("POST "+Prefix+"/session", h.login)
+	root.HandleFunc("DELETE "+Prefix+"/session", h.logout)
+	root.Handle("/", h.authenticate(mux))
+	return root
+}
+
+// validToken reports whether token is the configured token.
+func (h *handler) validToken(token string) bool {
+	return h.opts.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.Token)) == 1
+}
+
+// authenticate rejects requests without the configured token as a bearer token or the
+// session cookie of a signed-in dashboard.
+func (h *handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ok bool
+		if token, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); bearer {
+			ok = h.validToken(token)
+		} else if cookie, err := r.Cookie(sessionCookie); err == nil {
+			ok = h.sessions.valid(cookie.Value)
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="stashly"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// login checks the token in the body and starts a session, whose ID is stored in the session
+// cookie so browsers can follow download links. The cookie is never sent by other sites.
+func (h *handler) login(w http.ResponseWriter, r *http.Request) {
+	var req sessionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if !h.validToken(req.Token) {
+		writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+		return
+	}
+	http.SetCookie(w, h.cookie(r, h.sessions.create(), int(sessionTTL.Seconds())))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logout ends the session and clears the session cookie.
+func (h *handler) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		h.sessions.end(cookie.Value)
+	}
+	http.SetCookie(w, h.cookie(r, "", -1))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) cookie(r *http.Request, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+func (h *handler) listJobs(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.opts.Scheduler.Status())
+}
+
+func (h *handler) trigger(w http.ResponseWriter, r *http.Request) {
+	job := r.PathValue("job")
+	if err := h.opts.Scheduler.Trigger(job); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Backup triggered through the API", "job", job)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *handler) listBackups(w http.ResponseWriter, r *http.Request) {
+	backups, ok := h.backups(w, r)
+	if !ok {
+		return
+	}
+	list, err := backups.ListBackups(r.Context())
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *handler) getBackup(w http.ResponseWriter, r *http.Request) {
+	backups, ok := h.backups(w, r)
+	if !ok {
+		return
+	}
+	b, err := backups.GetBackup(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Manifest{Backup: *b, Run: h.findRun(r.PathValue("job"), b.ID)})
+}
+
+// findRun returns the run that uploaded the backup, if it is in the history.
+func (h *handler) findRun(job, id string) *history.Entry {
+	if h.opts.History == nil {
+		return nil
+	}
+	entries, err := h.opts.History.Entries(history.Filter{Job: job})
+	if err != nil {
+		slog.Warn("Failed to read run history", "error", err)
+		return nil
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if strings.Contains("/"+entries[i].Key, "/"+id+"/") {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+func (h *handler) download(w http.ResponseWriter, r *http.Request) {
+	backups, ok := h.backups(w, r)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	body, obj, err := backups.OpenBackup(r.Context(), id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	defer func() { _ = body.Close() }()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+"-"+path.Base(obj.Key)))
+	if obj.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	if _, err = io.Copy(w, body); err != nil {
+		slog.WarnContext(r.Context(), "Backup download interrupted", "job", r.PathValue("job"), "id", id, "error", err)
+	}
+}
+
+func (h *handler) deleteBackup(w http.ResponseWriter, r *http.Request) {
+	backups, ok := h.backups(w, r)
+	if !ok {
+		return
+	}
+	if err := backups.DeleteBackup(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Backup deleted through the API", "job", r.PathValue("job"), "id", r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) pin(w http.ResponseWriter, r *http.Request) {
+	backups, ok := h.backups(w, r)
+	if !ok {
+		return
+	}
+	var req pinRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if err := backups.PinBackup(r.Context(), r.PathValue("id"), req.Label); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) unpin(w http.ResponseWriter, r *http.Request) {
+	backups, ok := h.backups(w, r)
+	if !ok {
+		return
+	}
+	if err := backups.UnpinBackup(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listRuns returns past runs, newest first. It accepts the filters of the history command:
+// job, failed, since, until, plus limit.
+func (h *handler) listRuns(w http.ResponseWriter, r *http.Request) {
+	if h.opts.History == nil {
+		writeJSON(w, http.StatusOK, []history.Entry{})
+		return
+	}
+
+	q := r.URL.Query()
+	filter := history.Filter{Job: q.Get("job"), FailedOnly: q.Get("failed") == "true"}
+	now := time.Now()
+	var err error
+	if v := q.Get("since"); v != "" {
+		if filter.Since, err = history.ParseTime(v, now); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since: %w", err))
+			return
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if filter.Until, err = history.ParseTime(v, now); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid until: %w", err))
+			return
+		}
+	}
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %q", v))
+			return
+		}
+	}
+
+	entries, err := h.opts.History.Entries(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	slices.Reverse(entries)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// backups returns the backup operations of the request's job, writing an error response if there are none.
+func (h *handler) backups(w http.ResponseWriter, r *http.Request) (Backups, bool) {
+	backups, err := h.opts.Backups(r.PathValue("job"))
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return nil, false
+	}
+	return backups, true
+}
+
+// statusOf maps an error to the HTTP status reported to the client.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob), errors.Is(err, dumpster.ErrBackupNotFound),
+		errors.Is(err, storage.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, dumpster.ErrBackupPinned), errors.Is(err, storage.ErrPreconditionFailed):
+		return http.StatusConflict
+	case errors.Is(err, scheduler.ErrStopping):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write API response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/history"
+	"github.com/hibare/stashly/internal/scheduler"
+	"github.com/hibare/stashly/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "s3cret"
+
+type fakeScheduler struct {
+	triggered []string
+}
+
+func (s *fakeScheduler) Trigger(job string) error {
+	if job != "nightly" {
+		return fmt.Errorf("%w: %s", scheduler.ErrUnknownJob, job)
+	}
+	s.triggered = append(s.triggered, job)
+	return nil
+}
+
+func (s *fakeScheduler) Status() []scheduler.JobStatus {
+	return []scheduler.JobStatus{{Name: "nightly", Cron: "0 0 * * *"}}
+}
+
+type fakeBackups struct {
+	backups map[string]*dumpster.Backup
+	deleted []string
+}
+
+func (b *fakeBackups) ListBackups(context.Context) ([]dumpster.Backup, error) {
+	return []dumpster.Backup{*b.backups["20240102000000"], *b.backups["20240101000000"]}, nil
+}
+
+func (b *fakeBackups) GetBackup(_ context.Context, id string) (*dumpster.Backup, error) {
+	backup, ok := b.backups[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", dumpster.ErrBackupNotFound, id)
+	}
+	return backup, nil
+}
+
+func (b *fakeBackups) OpenBackup(ctx context.Context, id string) (io.ReadCloser, storage.ObjectInfo, error) {
+	backup, err := b.GetBackup(ctx, id)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+	return io.NopCloser(strings.NewReader("archive")), backup.Objects[0], nil
+}
+
+func (b *fakeBackups) DeleteBackup(ctx context.Context, id string) error {
+	backup, err := b.GetBackup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if backup.Pinned() {
+		return dumpster.ErrBackupPinned
+	}
+	b.deleted = append(b.deleted, id)
+	return nil
+}
+
+func (b *fakeBackups) PinBackup(ctx context.Context, id, label string) error {
+	backup, err := b.GetBackup(ctx, id)
+	if err != nil {
+		return err
+	}
+	backup.Pin = &dumpster.Pin{Label: label}
+	return nil
+}
+
+func (b *fakeBackups) UnpinBackup(_ context.Context, id string) error {
+	if backup, ok := b.backups[id]; ok {
+		backup.Pin = nil
+	}
+	return nil
+}
+
+type fakeHistory []history.Entry
+
+func (h fakeHistory) Entries(filter history.Filter) ([]history.Entry, error) {
+	entries := []history.Entry{}
+	for _, e := range h {
+		if filter.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *fakeScheduler, *fakeBackups) {
+	t.Helper()
+	sched := &fakeScheduler{}
+	backups := &fakeBackups{backups: map[string]*dumpster.Backup{
+		"20240101000000": {ID: "20240101000000", Size: 7, Pin: &dumpster.Pin{Label: "keep"},
+			Objects: []storage.ObjectInfo{{Key: "20240101000000/export.zip", Size: 7}}},
+		"20240102000000": {ID: "20240102000000", Size: 7,
+			Objects: []storage.ObjectInfo{{Key: "20240102000000/export.zip.gpg", Size: 7}}},
+	}}
+	runs := fakeHistory{
+		{RunID: "r1", Job: "nightly", Status: history.StatusSuccess, StartedAt: time.Now().Add(-48 * time.Hour),
+			Key: "backups/host/20240101000000/export.zip"},
+		{RunID: "r2", Job: "nightly", Status: history.StatusFailure, StartedAt: time.Now().Add(-24 * time.Hour)},
+		{RunID: "r3", Job: "nightly", Status: history.StatusSuccess, StartedAt: time.Now().Add(-time.Hour),
+			Key: "backups/host/20240102000000/export.zip.gpg"},
+	}
+
+	srv := httptest.NewServer(NewHandler(Options{
+		Token:     testToken,
+		Scheduler: sched,
+		Backups: func(job string) (Backups, error) {
+			if job != "nightly" {
+				return nil, fmt.Errorf("%w: %s", scheduler.ErrUnknownJob, job)
+			}
+			return backups, nil
+		},
+		History: runs,
+	}))
+	t.Cleanup(srv.Close)
+	return srv, sched, backups
+}
+
+func do(t *testing.T, srv *httptest.Server, method, path, body string) (*http.Response, string) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), method, srv.URL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, resp.Body.Close())
+	require.NoError(t, err)
+	return resp, string(data)
+}
+
+func TestHandler_Authentication(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	for _, header := range []string{"", "Bearer wrong", "Basic " + testToken} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+Prefix+"/jobs", nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, header)
+		assert.Equal(t, `Bearer realm="stashly"`, resp.Header.Get("WWW-Authenticate"))
+	}
+
+	resp, body := do(t, srv, http.MethodGet, Prefix+"/jobs", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `[{"name":"nightly","cron":"0 0 * * *","running":false,"queued":false,"deferred":false}]`, body)
+}
+
+func TestHandler_Trigger(t *testing.T) {
+	srv, sched, _ := newTestServer(t)
+
+	resp, _ := do(t, srv, http.MethodPost, Prefix+"/jobs/nightly/trigger", "")
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, []string{"nightly"}, sched.triggered)
+
+	resp, body := do(t, srv, http.MethodPost, Prefix+"/jobs/weekly/trigger", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.JSONEq(t, `{"error":"unknown job: weekly"}`, body)
+}
+
+func TestHandler_Backups(t *testing.T) {
+	srv, _, backups := newTestServer(t)
+
+	resp, body := do(t, srv, http.MethodGet, Prefix+"/jobs/nightly/backups", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var list []dumpster.Backup
+	require.NoError(t, json.Unmarshal([]byte(body), &list))
+	require.Len(t, list, 2)
+	assert.Equal(t, "20240102000000", list[0].ID)
+
+	resp, body = do(t, srv, http.MethodGet, Prefix+"/jobs/nightly/backups/20240102000000", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal([]byte(body), &manifest))
+	assert.Equal(t, "20240102000000/export.zip.gpg", manifest.Objects[0].Key)
+	require.NotNil(t, manifest.Run)
+	assert.Equal(t, "r3", manifest.Run.RunID)
+
+	resp, _ = do(t, srv, http.MethodGet, Prefix+"/jobs/nightly/backups/20240109000000", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = do(t, srv, http.MethodGet, Prefix+"/jobs/weekly/backups", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Pinned backups cannot be deleted until unpinned
+	resp, _ = do(t, srv, http.MethodDelete, Prefix+"/jobs/nightly/backups/20240101000000", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp, _ = do(t, srv, http.MethodDelete, Prefix+"/jobs/nightly/backups/20240101000000/pin", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = do(t, srv, http.MethodDelete, Prefix+"/jobs/nightly/backups/20240101000000", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, []string{"20240101000000"}, backups.deleted)
+
+	resp, _ = do(t, srv, http.MethodPut, Prefix+"/jobs/nightly/backups/20240102000000/pin", `{"label":"pre-upgrade"}`)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "pre-upgrade", backups.backups["20240102000000"].Pin.Label)
+	resp, _ = do(t, srv, http.MethodPut, Prefix+"/jobs/nightly/backups/20240101000000/pin", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = do(t, srv, http.MethodPut, Prefix+"/jobs/nightly/backups/20240101000000/pin", "{")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestHandler_Download(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, body := do(t, srv, http.MethodGet, Prefix+"/jobs/nightly/backups/20240102000000/download", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "archive", body)
+	assert.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="20240102000000-export.zip.gpg"`, resp.Header.Get("Content-Disposition"))
+	assert.Equal(t, "7", resp.Header.Get("Content-Length"))
+}
+
+func TestHandler_Runs(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, body := do(t, srv, http.MethodGet, Prefix+"/runs?limit=2", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var entries []history.Entry
+	require.NoError(t, json.Unmarshal([]byte(body), &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "r3", entries[0].RunID, "newest first")
+	assert.Equal(t, "r2", entries[1].RunID)
+
+	_, body = do(t, srv, http.MethodGet, Prefix+"/runs?failed=true&since=36h", "")
+	require.NoError(t, json.Unmarshal([]byte(body), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "r2", entries[0].RunID)
+
+	resp, _ = do(t, srv, http.MethodGet, Prefix+"/runs?limit=-1", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = do(t, srv, http.MethodGet, Prefix+"/runs?since=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestHandler_Session(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	post := func(body string) *http.Response {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL+Prefix+"/session", strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post(`{"token":"wrong"}`).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, post(`{`).StatusCode)
+
+	resp := post(`{"token":"` + testToken + `"}`)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	cookies := resp.Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	assert.Equal(t, int(sessionTTL.Seconds()), cookies[0].MaxAge)
+	// The cookie holds an opaque session ID, not the API token
+	assert.NotEmpty(t, cookies[0].Value)
+	assert.NotContains(t, cookies[0].Value, testToken)
+
+	// The cookie authenticates requests without a bearer token, such as download links
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		srv.URL+Prefix+"/jobs/nightly/backups/20240102000000/download", nil)
+	require.NoError(t, err)
+	req.AddCookie(cookies[0])
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The API token is not accepted as a session cookie
+	req, err = http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+Prefix+"/jobs", nil)
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: testToken})
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Signing out ends the session
+	req, err = http.NewRequestWithContext(context.Background(), http.MethodDelete, srv.URL+Prefix+"/session", nil)
+	require.NoError(t, err)
+	req.AddCookie(cookies[0])
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Len(t, resp.Cookies(), 1)
+	assert.Empty(t, resp.Cookies()[0].Value)
+
+	req, err = http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+Prefix+"/jobs", nil)
+	require.NoError(t, err)
+	req.AddCookie(cookies[0])
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// sessionTTL is how long a dashboard stays signed in.
+const sessionTTL = 12 * time.Hour
+
+// sessions tracks the signed-in dashboards by opaque session ID, so the API token itself is never
+// stored in a cookie. Sessions are kept in memory and end when the daemon restarts.
+type sessions struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func newSessions(ttl time.Duration) *sessions {
+	return &sessions{ttl: ttl, now: time.Now, expires: map[string]time.Time{}}
+}
+
+// create starts a session and returns its ID. Expired sessions are removed.
+func (s *sessions) create() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, expires := range s.expires {
+		if !now.Before(expires) {
+			delete(s.expires, id)
+		}
+	}
+	id := rand.Text()
+	s.expires[id] = now.Add(s.ttl)
+	return id
+}
+
+// valid reports whether id is a session that has not expired.
+func (s *sessions) valid(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.expires[id]
+	return ok && s.now().Before(expires)
+}
+
+// end removes the session, if any.
+func (s *sessions) end(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expires, id)
+}
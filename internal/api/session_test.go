@@ -0,0 +1,30 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	s := newSessions(time.Hour)
+	s.now = func() time.Time { return now }
+
+	first := s.create()
+	second := s.create()
+	assert.NotEqual(t, first, second)
+	assert.True(t, s.valid(first))
+	assert.False(t, s.valid(""))
+	assert.False(t, s.valid("unknown"))
+
+	s.end(second)
+	assert.False(t, s.valid(second))
+
+	// Sessions expire after the TTL and are removed when the next one starts
+	now = now.Add(time.Hour)
+	assert.False(t, s.valid(first))
+	s.create()
+	assert.Len(t, s.expires, 1)
+}
@@ -0,0 +1,9 @@
+// Package assets embeds application assets such as the logo.
+package assets
+
+import _ "embed"
+
+// Favicon is the Stashly logo as a PNG image.
+//
+//go:embed favicon.png
+var Favicon []byte
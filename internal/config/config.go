@@ -4,12 +4,16 @@ package config
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
 	commonLogger "github.com/hibare/GoCommon/v2/pkg/logger"
 	commonUtils "github.com/hibare/GoCommon/v2/pkg/utils"
 	"github.com/hibare/stashly/internal/constants"
+	"github.com/hibare/stashly/internal/logging"
+	"github.com/hibare/stashly/internal/retry"
 	"github.com/spf13/viper"
 )
 
@@ -35,15 +39,15 @@ type PostgresConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
 	User     string `mapstructure:"user"`
-	Password string `mapstructure:"password"`
+	Password string `mapstructure:"password" secret:"true"`
 }
 
 // S3Config holds S3 storage configuration.
 type S3Config struct {
 	Endpoint  string `mapstructure:"endpoint"`
 	Region    string `mapstructure:"region"`
-	AccessKey string `mapstructure:"access-key"`
-	SecretKey string `mapstructure:"secret-key"`
+	AccessKey string `mapstructure:"access-key" secret:"true"`
+	SecretKey string `mapstructure:"secret-key" secret:"true"`
 	Bucket    string `mapstructure:"bucket"`
 	Prefix    string `mapstructure:"prefix"`
 }
@@ -54,6 +58,84 @@ type BackupConfig struct {
 	DateTimeLayout string `mapstructure:"date-time-layout"`
 	Cron           string `mapstructure:"cron"`
 	Encrypt        bool   `mapstructure:"encrypt"`
+
+	// Databases filters the databases to dump.
+	Databases DatabaseFilterConfig `mapstructure:"databases"`
+
+	// Timezone is the IANA time zone cron expressions are evaluated in.
+	Timezone string `mapstructure:"timezone"`
+
+	// Jitter delays each scheduled run by a random duration up to this value.
+	Jitter time.Duration `mapstructure:"jitter"`
+
+	// CatchUp runs a backup at startup if a scheduled run was missed since the last backup.
+	CatchUp bool `mapstructure:"catch-up"`
+
+	// Retry configures automatic retries of failed runs.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Overlap decides what happens when a scheduled run is due while the previous run of the
+	// same job is still in progress: OverlapSkip or OverlapQueue.
+	Overlap string `mapstructure:"overlap"`
+
+	// ShutdownGracePeriod is how long a running backup may continue after a shutdown signal
+	// before it is cancelled. Zero cancels immediately.
+	ShutdownGracePeriod time.Duration `mapstructure:"shutdown-grace-period"`
+
+	// Timeout cancels a backup run, including its retries, that takes longer; zero means no limit.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// DatabaseTimeout cancels a single pg_dump invocation that takes longer; zero means no limit.
+	DatabaseTimeout time.Duration `mapstructure:"database-timeout"`
+
+	// LockWaitTimeout is passed to pg_dump as --lock-wait-timeout, so it fails instead of waiting
+	// indefinitely for a table lock; zero means pg_dump's default of waiting forever.
+	LockWaitTimeout time.Duration `mapstructure:"lock-wait-timeout"`
+
+	// WorkspaceDir is the parent directory of the per-run workspaces; empty uses the system temp directory.
+	WorkspaceDir string `mapstructure:"workspace-dir"`
+
+	// KeepLocal keeps the run workspace with the dumps and archive instead of removing it after the run.
+	KeepLocal bool `mapstructure:"keep-local"`
+
+	// SpaceCheck fails a run up front if the workspace lacks free space for the estimated database size.
+	SpaceCheck bool `mapstructure:"space-check"`
+}
+
+// RetryConfig holds configuration for retrying failed backup runs with exponential backoff.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts, including the first; 1 disables retries.
+	MaxAttempts  int           `mapstructure:"max-attempts"`
+	InitialDelay time.Duration `mapstructure:"initial-delay"`
+	Multiplier   float64       `mapstructure:"multiplier"`
+
+	// PerStage retries individual stages instead of the whole run: only failed databases are
+	// dumped again and failed uploads are retried without dumping again.
+	PerStage bool `mapstructure:"per-stage"`
+}
+
+// Policy returns the retry policy described by the configuration.
+func (r *RetryConfig) Policy() retry.Policy {
+	return retry.Policy{
+		MaxAttempts:  r.MaxAttempts,
+		InitialDelay: r.InitialDelay,
+		Multiplier:   r.Multiplier,
+	}
+}
+
+// Overlap policies supported by BackupConfig.Overlap.
+const (
+	OverlapSkip  = "skip"
+	OverlapQueue = "queue"
+)
+
+// Location returns the time zone cron expressions are evaluated in, defaulting to UTC.
+func (b *BackupConfig) Location() *time.Location {
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 // GPGConfig holds GPG encryption configuration.
@@ -70,29 +152,351 @@ type Encryption struct {
 // DiscordNotifierConfig holds configuration for the Discord notifier.
 type DiscordNotifierConfig struct {
 	Enabled bool   `mapstructure:"enabled"`
-	Webhook string `mapstructure:"webhook"`
+	Webhook string `mapstructure:"webhook" secret:"true"`
+}
+
+// NotifierPriorityConfig maps backup events to notifier-specific priorities.
+type NotifierPriorityConfig struct {
+	Success       int `mapstructure:"success"`
+	Failure       int `mapstructure:"failure"`
+	DeleteFailure int `mapstructure:"delete-failure"`
+}
+
+// NtfyNotifierConfig holds configuration for the ntfy notifier.
+type NtfyNotifierConfig struct {
+	Enabled  bool                   `mapstructure:"enabled"`
+	URL      string                 `mapstructure:"url"`
+	Token    string                 `mapstructure:"token" secret:"true"`
+	Tags     []string               `mapstructure:"tags"`
+	Priority NotifierPriorityConfig `mapstructure:"priority"`
+}
+
+// GotifyNotifierConfig holds configuration for the Gotify notifier.
+type GotifyNotifierConfig struct {
+	Enabled  bool                   `mapstructure:"enabled"`
+	URL      string                 `mapstructure:"url"`
+	Token    string                 `mapstructure:"token" secret:"true"`
+	Priority NotifierPriorityConfig `mapstructure:"priority"`
+}
+
+// TeamsNotifierConfig holds configuration for the Microsoft Teams notifier.
+type TeamsNotifierConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Webhook string `mapstructure:"webhook" secret:"true"`
+}
+
+// NotifierInstanceConfig holds configuration for a single named notifier instance.
+// Only the settings block matching Type is used.
+type NotifierInstanceConfig struct {
+	Name    string                `mapstructure:"name"`
+	Type    string                `mapstructure:"type"`
+	Events  []string              `mapstructure:"events"`
+	Discord DiscordNotifierConfig `mapstructure:"discord"`
+	Ntfy    NtfyNotifierConfig    `mapstructure:"ntfy"`
+	Gotify  GotifyNotifierConfig  `mapstructure:"gotify"`
+	Teams   TeamsNotifierConfig   `mapstructure:"teams"`
+}
+
+// Notifier types supported by NotifierInstanceConfig.Type.
+const (
+	NotifierTypeDiscord = "discord"
+	NotifierTypeNtfy    = "ntfy"
+	NotifierTypeGotify  = "gotify"
+	NotifierTypeTeams   = "teams"
+)
+
+// validate reports why the instance cannot be used, or returns nil.
+func (n *NotifierInstanceConfig) validate() error {
+	switch n.Type {
+	case NotifierTypeDiscord:
+		if n.Discord.Webhook == "" {
+			return errors.New("missing discord webhook")
+		}
+	case NotifierTypeNtfy:
+		if n.Ntfy.URL == "" {
+			return errors.New("missing ntfy topic url")
+		}
+	case NotifierTypeGotify:
+		if n.Gotify.URL == "" || n.Gotify.Token == "" {
+			return errors.New("missing gotify url/token")
+		}
+	case NotifierTypeTeams:
+		if n.Teams.Webhook == "" {
+			return errors.New("missing teams webhook")
+		}
+	default:
+		return fmt.Errorf("unknown notifier type %q", n.Type)
+	}
+	return nil
+}
+
+// applyDefaults fills in settings that viper cannot default inside list entries.
+func (n *NotifierInstanceConfig) applyDefaults(index int) {
+	if n.Name == "" {
+		n.Name = fmt.Sprintf("%s-%d", n.Type, index)
+	}
+	setPriorityDefaults(&n.Ntfy.Priority, NotifierPriorityConfig{
+		Success:       constants.DefaultNtfyPrioritySuccess,
+		Failure:       constants.DefaultNtfyPriorityFailure,
+		DeleteFailure: constants.DefaultNtfyPriorityDeleteFailure,
+	})
+	setPriorityDefaults(&n.Gotify.Priority, NotifierPriorityConfig{
+		Success:       constants.DefaultGotifyPrioritySuccess,
+		Failure:       constants.DefaultGotifyPriorityFailure,
+		DeleteFailure: constants.DefaultGotifyPriorityDeleteFailure,
+	})
+}
+
+func setPriorityDefaults(p *NotifierPriorityConfig, defaults NotifierPriorityConfig) {
+	if p.Success == 0 {
+		p.Success = defaults.Success
+	}
+	if p.Failure == 0 {
+		p.Failure = defaults.Failure
+	}
+	if p.DeleteFailure == 0 {
+		p.DeleteFailure = defaults.DeleteFailure
+	}
 }
 
 // NotifiersConfig holds configuration for all notifiers.
+// The per-type blocks (discord, ntfy, ...) configure a single unnamed notifier of that type
+// and are kept for backward compatibility; Instances allows any number of named notifiers.
 type NotifiersConfig struct {
-	Enabled bool                  `mapstructure:"enabled"`
-	Discord DiscordNotifierConfig `mapstructure:"discord"`
+	Enabled   bool                     `mapstructure:"enabled"`
+	Discord   DiscordNotifierConfig    `mapstructure:"discord"`
+	Ntfy      NtfyNotifierConfig       `mapstructure:"ntfy"`
+	Gotify    GotifyNotifierConfig     `mapstructure:"gotify"`
+	Teams     TeamsNotifierConfig      `mapstructure:"teams"`
+	Instances []NotifierInstanceConfig `mapstructure:"instances"`
+}
+
+// All returns every configured notifier instance, including the legacy per-type blocks.
+// Listed instances are always enabled.
+func (n *NotifiersConfig) All() []NotifierInstanceConfig {
+	all := []NotifierInstanceConfig{}
+	if n.Discord.Enabled {
+		all = append(all, NotifierInstanceConfig{Name: NotifierTypeDiscord, Type: NotifierTypeDiscord, Discord: n.Discord})
+	}
+	if n.Ntfy.Enabled {
+		all = append(all, NotifierInstanceConfig{Name: NotifierTypeNtfy, Type: NotifierTypeNtfy, Ntfy: n.Ntfy})
+	}
+	if n.Gotify.Enabled {
+		all = append(all, NotifierInstanceConfig{Name: NotifierTypeGotify, Type: NotifierTypeGotify, Gotify: n.Gotify})
+	}
+	if n.Teams.Enabled {
+		all = append(all, NotifierInstanceConfig{Name: NotifierTypeTeams, Type: NotifierTypeTeams, Teams: n.Teams})
+	}
+
+	for _, instance := range n.Instances {
+		instance.Discord.Enabled = instance.Type == NotifierTypeDiscord
+		instance.Ntfy.Enabled = instance.Type == NotifierTypeNtfy
+		instance.Gotify.Enabled = instance.Type == NotifierTypeGotify
+		instance.Teams.Enabled = instance.Type == NotifierTypeTeams
+		all = append(all, instance)
+	}
+	return all
+}
+
+// HeartbeatConfig holds configuration for dead-man's-switch heartbeat pings.
+type HeartbeatConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Provider  string        `mapstructure:"provider"`
+	URL       string        `mapstructure:"url" secret:"true"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+	TailLines int           `mapstructure:"tail-lines"`
+}
+
+// LockConfig holds configuration for the distributed backup lock stored in the storage backend.
+type LockConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	TTL     time.Duration `mapstructure:"ttl"`
+
+	// Owner identifies this instance in the lock; defaults to the instance ID.
+	Owner string `mapstructure:"owner"`
+}
+
+// ControlConfig holds configuration for the daemon's local control socket.
+type ControlConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Socket  string `mapstructure:"socket"`
+}
+
+// HTTPConfig holds configuration for the daemon's optional HTTP listener serving /metrics and health probes.
+type HTTPConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Listen  string `mapstructure:"listen"`
+
+	// ReadyMaxAge fails /readyz once a job's newest backup is older than this; 0 disables the check.
+	ReadyMaxAge time.Duration `mapstructure:"ready-max-age"`
+}
+
+// APIConfig holds configuration for the REST API served by "stashly serve".
+type APIConfig struct {
+	Listen string `mapstructure:"listen"`
+
+	// Token is the bearer token clients must present; serve refuses to start without one.
+	Token string `mapstructure:"token" secret:"true"`
+
+	// TLSCert and TLSKey are PEM files; when set, the API is served over HTTPS.
+	TLSCert string `mapstructure:"tls-cert"`
+	TLSKey  string `mapstructure:"tls-key"`
+
+	// Dashboard serves the web dashboard at the root of the API listener.
+	Dashboard bool `mapstructure:"dashboard"`
+}
+
+// HistoryConfig holds configuration for the persistent history of backup runs.
+type HistoryConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"`
+
+	// Upload also stores each entry as an object under <s3.prefix>/.stashly/history/.
+	Upload bool `mapstructure:"upload"`
 }
 
 // Config is the main configuration struct that holds all configuration sections.
 type Config struct {
-	App        AppConfig       `mapstructure:"app"`
-	Postgres   PostgresConfig  `mapstructure:"postgres"`
-	S3         S3Config        `mapstructure:"s3"`
-	Backup     BackupConfig    `mapstructure:"backup"`
-	Encryption Encryption      `mapstructure:"encryption"`
-	Notifiers  NotifiersConfig `mapstructure:"notifiers"`
-	Logger     LoggerConfig    `mapstructure:"logger"`
+	App         AppConfig         `mapstructure:"app"`
+	Postgres    PostgresConfig    `mapstructure:"postgres"`
+	S3          S3Config          `mapstructure:"s3"`
+	Backup      BackupConfig      `mapstructure:"backup"`
+	Encryption  Encryption        `mapstructure:"encryption"`
+	Notifiers   NotifiersConfig   `mapstructure:"notifiers"`
+	Heartbeat   HeartbeatConfig   `mapstructure:"heartbeat"`
+	Lock        LockConfig        `mapstructure:"lock"`
+	Maintenance MaintenanceConfig `mapstructure:"maintenance"`
+	Control     ControlConfig     `mapstructure:"control"`
+	History     HistoryConfig     `mapstructure:"history"`
+	HTTP        HTTPConfig        `mapstructure:"http"`
+	API         APIConfig         `mapstructure:"api"`
+	Secrets     SecretsConfig     `mapstructure:"secrets"`
+	Logger      LoggerConfig      `mapstructure:"logger"`
+	Jobs        []JobConfig       `mapstructure:"jobs"`
+
+	// Job is the name of the backup job this configuration was resolved for (see ForJob).
+	// It is empty for the implicit job formed by the top-level settings.
+	Job string `mapstructure:"-"`
 }
 
-// LoadConfig loads config from viper.
-func LoadConfig(ctx context.Context, configPath string) (*Config, error) {
-	var cfg *Config
+// envBindings maps the configuration keys to the environment variables that set them.
+var envBindings = map[string]string{
+	"postgres.host":                "STASHLY_POSTGRES_HOST",
+	"postgres.port":                "STASHLY_POSTGRES_PORT",
+	"postgres.user":                "STASHLY_POSTGRES_USER",
+	"postgres.password":            "STASHLY_POSTGRES_PASSWORD",
+	"s3.endpoint":                  "STASHLY_S3_ENDPOINT",
+	"s3.region":                    "STASHLY_S3_REGION",
+	"s3.access-key":                "STASHLY_S3_ACCESS_KEY",
+	"s3.secret-key":                "STASHLY_S3_SECRET_KEY",
+	"s3.bucket":                    "STASHLY_S3_BUCKET",
+	"s3.prefix":                    "STASHLY_S3_PREFIX",
+	"backup.retention-count":       "STASHLY_BACKUP_RETENTION_COUNT",
+	"backup.date-time-layout":      "STASHLY_BACKUP_DATE_TIME_LAYOUT",
+	"backup.cron":                  "STASHLY_BACKUP_CRON",
+	"backup.encrypt":               "STASHLY_BACKUP_ENCRYPT",
+	"backup.shutdown-grace-period": "STASHLY_BACKUP_SHUTDOWN_GRACE_PERIOD",
+	"backup.timezone":              "STASHLY_BACKUP_TIMEZONE",
+	"backup.jitter":                "STASHLY_BACKUP_JITTER",
+	"backup.catch-up":              "STASHLY_BACKUP_CATCH_UP",
+	"backup.overlap":               "STASHLY_BACKUP_OVERLAP",
+	"backup.retry.max-attempts":    "STASHLY_BACKUP_RETRY_MAX_ATTEMPTS",
+	"backup.retry.initial-delay":   "STASHLY_BACKUP_RETRY_INITIAL_DELAY",
+	"backup.retry.multiplier":      "STASHLY_BACKUP_RETRY_MULTIPLIER",
+	"backup.retry.per-stage":       "STASHLY_BACKUP_RETRY_PER_STAGE",
+	"backup.databases.include":     "STASHLY_BACKUP_DATABASES_INCLUDE",
+	"backup.databases.exclude":     "STASHLY_BACKUP_DATABASES_EXCLUDE",
+	"backup.timeout":               "STASHLY_BACKUP_TIMEOUT",
+	"backup.database-timeout":      "STASHLY_BACKUP_DATABASE_TIMEOUT",
+	"backup.lock-wait-timeout":     "STASHLY_BACKUP_LOCK_WAIT_TIMEOUT",
+	"backup.workspace-dir":         "STASHLY_BACKUP_WORKSPACE_DIR",
+	"backup.keep-local":            "STASHLY_BACKUP_KEEP_LOCAL",
+	"backup.space-check":           "STASHLY_BACKUP_SPACE_CHECK",
+	"encryption.gpg.key-server":    "STASHLY_ENCRYPTION_GPG_KEY_SERVER",
+	"encryption.gpg.key-id":        "STASHLY_ENCRYPTION_GPG_KEY_ID",
+	"notifiers.enabled":            "STASHLY_NOTIFIERS_ENABLED",
+	"notifiers.discord.enabled":    "STASHLY_NOTIFIERS_DISCORD_ENABLED",
+	"notifiers.discord.webhook":    "STASHLY_NOTIFIERS_DISCORD_WEBHOOK",
+	"notifiers.ntfy.enabled":       "STASHLY_NOTIFIERS_NTFY_ENABLED",
+	"notifiers.ntfy.url":           "STASHLY_NOTIFIERS_NTFY_URL",
+	"notifiers.ntfy.token":         "STASHLY_NOTIFIERS_NTFY_TOKEN",
+	"notifiers.ntfy.tags":          "STASHLY_NOTIFIERS_NTFY_TAGS",
+	"notifiers.gotify.enabled":     "STASHLY_NOTIFIERS_GOTIFY_ENABLED",
+	"notifiers.gotify.url":         "STASHLY_NOTIFIERS_GOTIFY_URL",
+	"notifiers.gotify.token":       "STASHLY_NOTIFIERS_GOTIFY_TOKEN",
+	"notifiers.teams.enabled":      "STASHLY_NOTIFIERS_TEAMS_ENABLED",
+	"notifiers.teams.webhook":      "STASHLY_NOTIFIERS_TEAMS_WEBHOOK",
+	"heartbeat.enabled":            "STASHLY_HEARTBEAT_ENABLED",
+	"heartbeat.provider":           "STASHLY_HEARTBEAT_PROVIDER",
+	"heartbeat.url":                "STASHLY_HEARTBEAT_URL",
+	"heartbeat.timeout":            "STASHLY_HEARTBEAT_TIMEOUT",
+	"heartbeat.tail-lines":         "STASHLY_HEARTBEAT_TAIL_LINES",
+	"lock.enabled":                 "STASHLY_LOCK_ENABLED",
+	"lock.ttl":                     "STASHLY_LOCK_TTL",
+	"lock.owner":                   "STASHLY_LOCK_OWNER",
+	"maintenance.max-duration":     "STASHLY_MAINTENANCE_MAX_DURATION",
+	"control.enabled":              "STASHLY_CONTROL_ENABLED",
+	"control.socket":               "STASHLY_CONTROL_SOCKET",
+	"history.enabled":              "STASHLY_HISTORY_ENABLED",
+	"history.path":                 "STASHLY_HISTORY_PATH",
+	"history.upload":               "STASHLY_HISTORY_UPLOAD",
+	"http.enabled":                 "STASHLY_HTTP_ENABLED",
+	"http.listen":                  "STASHLY_HTTP_LISTEN",
+	"http.ready-max-age":           "STASHLY_HTTP_READY_MAX_AGE",
+	"api.listen":                   "STASHLY_API_LISTEN",
+	"api.token":                    "STASHLY_API_TOKEN",
+	"api.tls-cert":                 "STASHLY_API_TLS_CERT",
+	"api.tls-key":                  "STASHLY_API_TLS_KEY",
+	"api.dashboard":                "STASHLY_API_DASHBOARD",
+	"secrets.vault.address":        "STASHLY_SECRETS_VAULT_ADDRESS",
+	"secrets.vault.token":          "STASHLY_SECRETS_VAULT_TOKEN",
+	"secrets.vault.namespace":      "STASHLY_SECRETS_VAULT_NAMESPACE",
+	"secrets.vault.kv-version":     "STASHLY_SECRETS_VAULT_KV_VERSION",
+	"secrets.vault.timeout":        "STASHLY_SECRETS_VAULT_TIMEOUT",
+	"logger.level":                 "STASHLY_LOGGER_LEVEL",
+	"logger.mode":                  "STASHLY_LOGGER_MODE",
+	"app.instance-id":              "STASHLY_APP_INSTANCE_ID",
+}
+
+// setDefaults sets the defaults of the settings. The instance ID defaults to the hostname, which is
+// set by LoadConfig so the defaults do not depend on the machine.
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("postgres.host", constants.DefaultPostgresHost)
+	v.SetDefault("postgres.port", constants.DefaultPostgresPort)
+	v.SetDefault("backup.retention-count", constants.DefaultRetentionCount)
+	v.SetDefault("backup.date-time-layout", constants.DefaultDateTimeLayout)
+	v.SetDefault("backup.cron", constants.DefaultCron)
+	v.SetDefault("backup.timezone", constants.DefaultTimezone)
+	v.SetDefault("backup.overlap", OverlapSkip)
+	v.SetDefault("backup.space-check", true)
+	v.SetDefault("backup.retry.max-attempts", constants.DefaultRetryMaxAttempts)
+	v.SetDefault("backup.retry.initial-delay", constants.DefaultRetryInitialDelay)
+	v.SetDefault("backup.retry.multiplier", constants.DefaultRetryMultiplier)
+	v.SetDefault("notifiers.ntfy.priority.success", constants.DefaultNtfyPrioritySuccess)
+	v.SetDefault("notifiers.ntfy.priority.failure", constants.DefaultNtfyPriorityFailure)
+	v.SetDefault("notifiers.ntfy.priority.delete-failure", constants.DefaultNtfyPriorityDeleteFailure)
+	v.SetDefault("notifiers.gotify.priority.success", constants.DefaultGotifyPrioritySuccess)
+	v.SetDefault("notifiers.gotify.priority.failure", constants.DefaultGotifyPriorityFailure)
+	v.SetDefault("notifiers.gotify.priority.delete-failure", constants.DefaultGotifyPriorityDeleteFailure)
+	v.SetDefault("heartbeat.provider", constants.DefaultHeartbeatProvider)
+	v.SetDefault("heartbeat.timeout", constants.DefaultHeartbeatTimeout)
+	v.SetDefault("heartbeat.tail-lines", constants.DefaultHeartbeatTailLines)
+	v.SetDefault("lock.ttl", constants.DefaultLockTTL)
+	v.SetDefault("control.enabled", true)
+	v.SetDefault("control.socket", constants.DefaultControlSocket)
+	v.SetDefault("history.enabled", true)
+	v.SetDefault("history.path", constants.DefaultHistoryPath)
+	v.SetDefault("http.listen", constants.DefaultHTTPListen)
+	v.SetDefault("api.listen", constants.DefaultAPIListen)
+	v.SetDefault("api.dashboard", true)
+	v.SetDefault("secrets.vault.kv-version", 2)
+	v.SetDefault("logger.level", commonLogger.DefaultLoggerLevel)
+	v.SetDefault("logger.mode", commonLogger.DefaultLoggerMode)
+}
+
+// newViper returns a viper instance reading the config file at configPath, or searching the
+// working directory and the default path for it if configPath is empty.
+func newViper(configPath string) *viper.Viper {
 	v := viper.New()
 	v.SetConfigName(configFileName)
 	v.SetConfigType(configFileType)
@@ -104,6 +508,25 @@ func LoadConfig(ctx context.Context, configPath string) (*Config, error) {
 		v.AddConfigPath(".")
 		v.AddConfigPath(configFileDefaultPath)
 	}
+	return v
+}
+
+// LoadConfig loads config from viper.
+func LoadConfig(ctx context.Context, configPath string) (*Config, error) {
+	return loadConfig(ctx, configPath, true)
+}
+
+// LoadConfigWithoutSecrets loads config like LoadConfig but leaves secret settings as configured:
+// _FILE variables are not read and file://, env:// and vault:// references are not resolved. It
+// suits commands that only need other settings and must not fail when a secret provider does,
+// such as healthcheck.
+func LoadConfigWithoutSecrets(ctx context.Context, configPath string) (*Config, error) {
+	return loadConfig(ctx, configPath, false)
+}
+
+func loadConfig(ctx context.Context, configPath string, withSecrets bool) (*Config, error) {
+	var cfg *Config
+	v := newViper(configPath)
 
 	// Environment variable binding (STASHLY_POSTGRES_HOST, etc.)
 	v.SetEnvPrefix("STASHLY")
@@ -111,31 +534,6 @@ func LoadConfig(ctx context.Context, configPath string) (*Config, error) {
 	v.AutomaticEnv()
 
 	// Bind all configuration fields to environment variables
-	envBindings := map[string]string{
-		"postgres.host":             "STASHLY_POSTGRES_HOST",
-		"postgres.port":             "STASHLY_POSTGRES_PORT",
-		"postgres.user":             "STASHLY_POSTGRES_USER",
-		"postgres.password":         "STASHLY_POSTGRES_PASSWORD",
-		"s3.endpoint":               "STASHLY_S3_ENDPOINT",
-		"s3.region":                 "STASHLY_S3_REGION",
-		"s3.access-key":             "STASHLY_S3_ACCESS_KEY",
-		"s3.secret-key":             "STASHLY_S3_SECRET_KEY",
-		"s3.bucket":                 "STASHLY_S3_BUCKET",
-		"s3.prefix":                 "STASHLY_S3_PREFIX",
-		"backup.retention-count":    "STASHLY_BACKUP_RETENTION_COUNT",
-		"backup.date-time-layout":   "STASHLY_BACKUP_DATE_TIME_LAYOUT",
-		"backup.cron":               "STASHLY_BACKUP_CRON",
-		"backup.encrypt":            "STASHLY_BACKUP_ENCRYPT",
-		"encryption.gpg.key-server": "STASHLY_ENCRYPTION_GPG_KEY_SERVER",
-		"encryption.gpg.key-id":     "STASHLY_ENCRYPTION_GPG_KEY_ID",
-		"notifiers.enabled":         "STASHLY_NOTIFIERS_ENABLED",
-		"notifiers.discord.enabled": "STASHLY_NOTIFIERS_DISCORD_ENABLED",
-		"notifiers.discord.webhook": "STASHLY_NOTIFIERS_DISCORD_WEBHOOK",
-		"logger.level":              "STASHLY_LOGGER_LEVEL",
-		"logger.mode":               "STASHLY_LOGGER_MODE",
-		"app.instance-id":           "STASHLY_APP_INSTANCE_ID",
-	}
-
 	for configKey, envVar := range envBindings {
 		if err := v.BindEnv(configKey, envVar); err != nil {
 			slog.WarnContext(ctx, "Failed to bind environment variable",
@@ -145,6 +543,13 @@ func LoadConfig(ctx context.Context, configPath string) (*Config, error) {
 		}
 	}
 
+	// Secrets mounted as files (STASHLY_POSTGRES_PASSWORD_FILE, etc.)
+	if withSecrets {
+		if err := applySecretFiles(v, envBindings); err != nil {
+			return nil, err
+		}
+	}
+
 	// Try read config
 	if err := v.ReadInConfig(); err != nil {
 		var notFoundErr viper.ConfigFileNotFoundError
@@ -158,14 +563,7 @@ func LoadConfig(ctx context.Context, configPath string) (*Config, error) {
 	}
 
 	// Add defaults
-	v.SetDefault("postgres.host", constants.DefaultPostgresHost)
-	v.SetDefault("postgres.port", constants.DefaultPostgresPort)
-	v.SetDefault("postgres.port", "5432")
-	v.SetDefault("backup.retention-count", constants.DefaultRetentionCount)
-	v.SetDefault("backup.date-time-layout", constants.DefaultDateTimeLayout)
-	v.SetDefault("backup.cron", constants.DefaultCron)
-	v.SetDefault("logger.level", commonLogger.DefaultLoggerLevel)
-	v.SetDefault("logger.mode", commonLogger.DefaultLoggerMode)
+	setDefaults(v)
 	v.SetDefault("app.instance-id", commonUtils.GetHostname())
 
 	// Unmarshal into Current
@@ -175,20 +573,40 @@ func LoadConfig(ctx context.Context, configPath string) (*Config, error) {
 
 	// Initialize logger
 	commonLogger.InitLogger(&cfg.Logger.Level, &cfg.Logger.Mode)
+	logging.Install()
+
+	// Resolve file://, env:// and vault:// references in secret settings
+	if withSecrets {
+		if err := resolveSecrets(ctx, cfg); err != nil {
+			return nil, fmt.Errorf("error resolving secrets: %w", err)
+		}
+	}
+
+	// Invalid settings, such as an unknown time zone or a negative timeout, are left as configured
+	// for Validate to report; LoadConfig only fills in settings derived from others.
+	if cfg.Lock.Owner == "" {
+		cfg.Lock.Owner = cfg.App.InstanceID
+	}
+
+	for i := range cfg.Notifiers.Instances {
+		cfg.Notifiers.Instances[i].applyDefaults(i)
+	}
 
-	// Encryption sanity check
-	if cfg.Backup.Encrypt {
-		if cfg.Encryption.GPG.KeyServer == "" || cfg.Encryption.GPG.KeyID == "" {
-			slog.WarnContext(ctx, "GPG encryption enabled but key-server/key-id not set; disabling encryption")
-			cfg.Backup.Encrypt = false
+	seenJobs := map[string]bool{}
+	for i := range cfg.Jobs {
+		if cfg.Jobs[i].Name == "" {
+			cfg.Jobs[i].Name = fmt.Sprintf("job-%d", i)
 		}
+		seenJobs[cfg.Jobs[i].Name] = true
 	}
 
-	// Notifiers sanity check
-	if cfg.Notifiers.Discord.Enabled {
-		if cfg.Notifiers.Discord.Webhook == "" {
-			slog.WarnContext(ctx, "Discord notifier enabled but missing webhook; disabling notifier")
-			cfg.Notifiers.Discord.Enabled = false
+	for i := range cfg.Maintenance.Blackouts {
+		blackout := &cfg.Maintenance.Blackouts[i]
+		blackout.applyDefaults(i)
+		for _, job := range blackout.Jobs {
+			if job != DefaultJobName && !seenJobs[job] {
+				slog.WarnContext(ctx, "Blackout window references unknown backup job", "name", blackout.Name, "job", job)
+			}
 		}
 	}
 
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -101,8 +102,131 @@ func TestLoadConfig_EncryptSanityCheck(t *testing.T) {
 	cfg, err := LoadConfig(ctx, configFile)
 	require.NoError(t, err)
 
-	// Should have been reset to false
-	assert.False(t, cfg.Backup.Encrypt)
+	// Encryption stays requested and fails validation instead of being disabled
+	assert.True(t, cfg.Backup.Encrypt)
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+	assert.Contains(t, vErr.Errors, FieldError{Field: "encryption.gpg.key-id", Message: "is required when backup.encrypt is enabled"})
+}
+
+func TestLoadConfig_Timezone(t *testing.T) {
+	t.Setenv("STASHLY_BACKUP_TIMEZONE", "Mars/Olympus_Mons")
+
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, "")
+	require.NoError(t, err)
+	// An unknown time zone is kept for Validate to report instead of silently using UTC
+	assert.Equal(t, "Mars/Olympus_Mons", cfg.Backup.Timezone)
+
+	t.Setenv("STASHLY_BACKUP_TIMEZONE", "Asia/Tokyo")
+	cfg, err = LoadConfig(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", cfg.Backup.Location().String())
+}
+
+func TestLoadConfig_Workspace(t *testing.T) {
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Backup.WorkspaceDir)
+	assert.False(t, cfg.Backup.KeepLocal)
+	assert.True(t, cfg.Backup.SpaceCheck)
+
+	t.Setenv("STASHLY_BACKUP_WORKSPACE_DIR", "/var/lib/stashly")
+	t.Setenv("STASHLY_BACKUP_KEEP_LOCAL", "true")
+	t.Setenv("STASHLY_BACKUP_SPACE_CHECK", "false")
+	cfg, err = LoadConfig(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/stashly", cfg.Backup.WorkspaceDir)
+	assert.True(t, cfg.Backup.KeepLocal)
+	assert.False(t, cfg.Backup.SpaceCheck)
+}
+
+func TestLoadConfig_Timeouts(t *testing.T) {
+	t.Setenv("STASHLY_BACKUP_TIMEOUT", "2h")
+	t.Setenv("STASHLY_BACKUP_DATABASE_TIMEOUT", "30m")
+	t.Setenv("STASHLY_BACKUP_LOCK_WAIT_TIMEOUT", "-1s")
+
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, cfg.Backup.Timeout)
+	assert.Equal(t, 30*time.Minute, cfg.Backup.DatabaseTimeout)
+	assert.Equal(t, -time.Second, cfg.Backup.LockWaitTimeout, "left for Validate to report")
+	require.ErrorContains(t, cfg.Validate(), "backup.lock-wait-timeout: must not be negative, got -1s")
+}
+
+func TestLoadConfig_HTTP(t *testing.T) {
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, "")
+	require.NoError(t, err)
+	assert.False(t, cfg.HTTP.Enabled)
+	assert.Equal(t, ":9090", cfg.HTTP.Listen)
+
+	t.Setenv("STASHLY_HTTP_ENABLED", "true")
+	t.Setenv("STASHLY_HTTP_LISTEN", "127.0.0.1:9100")
+	t.Setenv("STASHLY_HTTP_READY_MAX_AGE", "26h")
+	cfg, err = LoadConfig(ctx, "")
+	require.NoError(t, err)
+	assert.True(t, cfg.HTTP.Enabled)
+	assert.Equal(t, "127.0.0.1:9100", cfg.HTTP.Listen)
+	assert.Equal(t, 26*time.Hour, cfg.HTTP.ReadyMaxAge)
+}
+
+func TestLoadConfig_API(t *testing.T) {
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, APIConfig{Listen: ":8080", Dashboard: true}, cfg.API)
+
+	t.Setenv("STASHLY_API_LISTEN", ":8443")
+	t.Setenv("STASHLY_API_TOKEN", "s3cret")
+	t.Setenv("STASHLY_API_TLS_CERT", "/etc/stashly/tls.crt")
+	t.Setenv("STASHLY_API_TLS_KEY", "/etc/stashly/tls.key")
+	t.Setenv("STASHLY_API_DASHBOARD", "false")
+	cfg, err = LoadConfig(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, APIConfig{
+		Listen:  ":8443",
+		Token:   "s3cret",
+		TLSCert: "/etc/stashly/tls.crt",
+		TLSKey:  "/etc/stashly/tls.key",
+	}, cfg.API)
+}
+
+func TestLoadConfig_HTTPInvalid(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("http:\n  enabled: true\n  listen: \"\"\n  ready-max-age: -1h\n"), 0o600))
+
+	cfg, err := LoadConfig(t.Context(), configFile)
+	require.NoError(t, err)
+	assert.True(t, cfg.HTTP.Enabled, "listener without an address is left for Validate to report")
+	assert.Equal(t, -time.Hour, cfg.HTTP.ReadyMaxAge)
+
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+	assert.Contains(t, vErr.Errors, FieldError{Field: "http.listen", Message: "is required when http is enabled"})
+	assert.Contains(t, vErr.Errors, FieldError{Field: "http.ready-max-age", Message: "must not be negative, got -1h0m0s"})
+}
+
+func TestLoadConfig_OverlapAndLockInvalid(t *testing.T) {
+	t.Setenv("STASHLY_APP_INSTANCE_ID", "replica-a")
+	t.Setenv("STASHLY_BACKUP_OVERLAP", "parallel")
+	t.Setenv("STASHLY_LOCK_ENABLED", "true")
+	t.Setenv("STASHLY_LOCK_TTL", "-1s")
+
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "parallel", cfg.Backup.Overlap, "left for Validate to report")
+	assert.True(t, cfg.Lock.Enabled)
+	assert.Equal(t, "replica-a", cfg.Lock.Owner)
+	assert.Equal(t, -time.Second, cfg.Lock.TTL)
+
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+	assert.Contains(t, vErr.Errors, FieldError{Field: "backup.overlap", Message: `must be skip or queue, got "parallel"`})
+	assert.Contains(t, vErr.Errors, FieldError{Field: "lock.ttl", Message: "must be positive when lock is enabled, got -1s"})
 }
 
 func TestLoadConfig_DiscordSanityCheck(t *testing.T) {
@@ -112,6 +236,7 @@ func TestLoadConfig_DiscordSanityCheck(t *testing.T) {
 	// Discord enabled but no webhook
 	content := map[string]interface{}{
 		"notifiers": map[string]interface{}{
+			"enabled": true,
 			"discord": map[string]interface{}{
 				"enabled": true,
 			},
@@ -128,8 +253,9 @@ func TestLoadConfig_DiscordSanityCheck(t *testing.T) {
 	cfg, err := LoadConfig(ctx, configFile)
 	require.NoError(t, err)
 
-	// Should have been reset to false
-	assert.False(t, cfg.Notifiers.Discord.Enabled)
+	// The notifier stays enabled and fails validation instead of being disabled
+	assert.True(t, cfg.Notifiers.Discord.Enabled)
+	require.ErrorContains(t, cfg.Validate(), "notifiers.discord.webhook: is required when the notifier is enabled")
 }
 
 func TestLoadConfig_InvalidFile(t *testing.T) {
@@ -466,3 +592,117 @@ func TestLoadConfig_EnvironmentVariablePriority(t *testing.T) {
 	assert.Equal(t, "5434", cfg.Postgres.Port)
 	assert.Equal(t, 15, cfg.Backup.RetentionCount)
 }
+
+func TestLoadConfig_PushNotifiers(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := map[string]interface{}{
+		"notifiers": map[string]interface{}{
+			"enabled": true,
+			"ntfy": map[string]interface{}{
+				"enabled": true,
+				"url":     "https://ntfy.sh/backups",
+				"token":   "tk_abc",
+				"tags":    []string{"postgres", "prod"},
+				"priority": map[string]int{
+					"success": 2,
+				},
+			},
+			"gotify": map[string]interface{}{
+				"enabled": true,
+				"url":     "https://gotify.example.com",
+			},
+		},
+	}
+
+	//nolint:gosec // Safe in tests - using t.TempDir()
+	f, err := os.Create(configFile)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	err = yaml.NewEncoder(f).Encode(content)
+	require.NoError(t, err)
+
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, configFile)
+	require.NoError(t, err)
+
+	assert.True(t, cfg.Notifiers.Ntfy.Enabled)
+	assert.Equal(t, "https://ntfy.sh/backups", cfg.Notifiers.Ntfy.URL)
+	assert.Equal(t, []string{"postgres", "prod"}, cfg.Notifiers.Ntfy.Tags)
+	assert.Equal(t, 2, cfg.Notifiers.Ntfy.Priority.Success)
+	assert.Equal(t, 5, cfg.Notifiers.Ntfy.Priority.Failure)
+	assert.Equal(t, 4, cfg.Notifiers.Ntfy.Priority.DeleteFailure)
+
+	// Gotify requires an app token
+	assert.Equal(t, 8, cfg.Notifiers.Gotify.Priority.Failure)
+	require.ErrorContains(t, cfg.Validate(), "notifiers.gotify.token: is required when the notifier is enabled")
+}
+
+func TestLoadConfig_NotifierInstances(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := map[string]interface{}{
+		"notifiers": map[string]interface{}{
+			"enabled": true,
+			"instances": []map[string]interface{}{
+				{
+					"name":   "oncall",
+					"type":   "discord",
+					"events": []string{"failure", "partial"},
+					"discord": map[string]string{
+						"webhook": "https://discord.com/api/webhooks/oncall",
+					},
+				},
+				{
+					"type": "ntfy",
+					"ntfy": map[string]string{
+						"url": "https://ntfy.sh/backups",
+					},
+				},
+				{
+					"name": "broken",
+					"type": "teams",
+				},
+				{
+					"name": "oncall",
+					"type": "ntfy",
+					"ntfy": map[string]string{
+						"url": "https://ntfy.sh/duplicate",
+					},
+				},
+			},
+		},
+	}
+
+	//nolint:gosec // Safe in tests - using t.TempDir()
+	f, err := os.Create(configFile)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	err = yaml.NewEncoder(f).Encode(content)
+	require.NoError(t, err)
+
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, configFile)
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Notifiers.Instances, 4)
+	assert.Equal(t, "oncall", cfg.Notifiers.Instances[0].Name)
+	assert.Equal(t, []string{"failure", "partial"}, cfg.Notifiers.Instances[0].Events)
+	assert.Equal(t, "ntfy-1", cfg.Notifiers.Instances[1].Name)
+	assert.Equal(t, 5, cfg.Notifiers.Instances[1].Ntfy.Priority.Failure)
+
+	all := cfg.Notifiers.All()
+	require.Len(t, all, 4)
+	assert.True(t, all[0].Discord.Enabled)
+	assert.True(t, all[1].Ntfy.Enabled)
+
+	// Invalid and duplicate instances fail validation
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+	assert.Contains(t, vErr.Errors, FieldError{Field: "notifiers.instances[2]", Message: "broken: missing teams webhook"})
+	assert.Contains(t, vErr.Errors, FieldError{Field: "notifiers.instances[3].name", Message: `duplicate notifier name "oncall"`})
+}
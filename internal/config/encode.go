@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces the values of secret settings in redacted output.
+const Redacted = "REDACTED"
+
+// sampleHeader starts the config files generated by Sample.
+const sampleHeader = `# Stashly configuration, generated by "stashly config init".
+#
+# Every setting can also be set by an environment variable named after its key,
+# e.g. STASHLY_S3_BUCKET for s3.bucket, and secrets from a file with the _FILE suffix.
+# Check the file with "stashly config validate"; "stashly config schema" prints a
+# JSON Schema for editors.
+
+`
+
+// EncodeOptions controls how Encode renders a configuration.
+type EncodeOptions struct {
+	// Comments adds the description of each setting above it.
+	Comments bool
+
+	// Redact replaces the values of secret settings with Redacted.
+	Redact bool
+
+	// Annotations are added as line comments to the settings with the same key, e.g. "s3.bucket".
+	Annotations map[string]string
+}
+
+// Encode renders the configuration as YAML, in the order of the Config struct.
+func Encode(cfg *Config, opts EncodeOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(encodeValue(reflect.ValueOf(cfg).Elem(), "", opts)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeValue returns the YAML node of a setting. The key is empty inside list entries.
+func encodeValue(v reflect.Value, key string, opts EncodeOptions) *yaml.Node {
+	switch {
+	case v.Type() == durationType:
+		return scalarNode(time.Duration(v.Int()).String())
+	case v.Kind() == reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		if v.Len() == 0 || v.Type().Elem().Kind() != reflect.Struct {
+			node.Style = yaml.FlowStyle
+		}
+		for i := range v.Len() {
+			node.Content = append(node.Content, encodeValue(v.Index(i), "", opts))
+		}
+		return node
+	case v.Kind() == reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, f := range settingFields(v.Type()) {
+			name := f.Tag.Get("mapstructure")
+			childKey := ""
+			if key != "" || v.Type() == reflect.TypeFor[Config]() {
+				childKey = strings.TrimPrefix(key+"."+name, ".")
+			}
+
+			keyNode := scalarNode(name)
+			if opts.Comments {
+				keyNode.HeadComment = "# " + describe(v.Type(), f)
+				if v.Type() == reflect.TypeFor[Config]() && len(node.Content) > 0 {
+					// Separate the top-level sections
+					keyNode.HeadComment = "\n" + keyNode.HeadComment
+				}
+			}
+			fv := v.FieldByIndex(f.Index)
+			var valueNode *yaml.Node
+			if opts.Redact && isSecret(f) && fv.String() != "" {
+				valueNode = scalarNode(Redacted)
+			} else {
+				valueNode = encodeValue(fv, childKey, opts)
+			}
+			if annotation, ok := opts.Annotations[childKey]; ok && childKey != "" {
+				valueNode.LineComment = annotation
+			}
+			node.Content = append(node.Content, keyNode, valueNode)
+		}
+		return node
+	default:
+		return scalarNode(v.Interface())
+	}
+}
+
+func scalarNode(value any) *yaml.Node {
+	node := &yaml.Node{}
+	_ = node.Encode(value)
+	return node
+}
+
+// EnvOverrides returns the environment variables that are set and override the config file,
+// keyed by the setting they set, e.g. "s3.bucket": "STASHLY_S3_BUCKET".
+func EnvOverrides() map[string]string {
+	overrides := map[string]string{}
+	for key, envVar := range envBindings {
+		switch {
+		case isEnvSet(envVar):
+			overrides[key] = envVar
+		case isEnvSet(envVar + fileEnvSuffix):
+			overrides[key] = envVar + fileEnvSuffix
+		}
+	}
+	return overrides
+}
+
+func isEnvSet(name string) bool {
+	_, ok := os.LookupEnv(name)
+	return ok
+}
+
+// NewConfig returns the default configuration with the given settings, keyed by config key such
+// as "s3.bucket". Values are parsed like environment variables, e.g. "10m" or "app_*,billing".
+// Settings in lists, such as jobs, cannot be set.
+func NewConfig(settings map[string]string) (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+	known := settingKeys(reflect.TypeFor[Config](), "")
+	for key, value := range settings {
+		if !known[key] {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+		v.Set(key, value)
+	}
+
+	var cfg *Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// settingKeys returns the keys of the settings that are not sections or in lists.
+func settingKeys(t reflect.Type, prefix string) map[string]bool {
+	keys := map[string]bool{}
+	for _, f := range settingFields(t) {
+		key := strings.TrimPrefix(prefix+"."+f.Tag.Get("mapstructure"), ".")
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			for k := range settingKeys(f.Type, key) {
+				keys[k] = true
+			}
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct:
+		default:
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// Sample returns a commented config file with every setting, set to its default or the given
+// settings (see NewConfig).
+func Sample(settings map[string]string) ([]byte, error) {
+	cfg, err := NewConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+	data, err := Encode(cfg, EncodeOptions{Comments: true})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(sampleHeader), data...), nil
+}
+
+// Default returns the default value of a setting in the form NewConfig accepts, e.g. "30s" or
+// "false", or "" if there is no such setting.
+func Default(key string) string {
+	cfg, err := NewConfig(nil)
+	if err != nil {
+		return ""
+	}
+	v := reflect.ValueOf(cfg).Elem()
+	for _, name := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return ""
+		}
+		i := slices.IndexFunc(settingFields(v.Type()), func(f reflect.StructField) bool {
+			return f.Tag.Get("mapstructure") == name
+		})
+		if i < 0 {
+			return ""
+		}
+		v = v.FieldByIndex(settingFields(v.Type())[i].Index)
+	}
+
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		return strings.Join(v.Interface().([]string), ",")
+	case v.Kind() == reflect.Struct || v.Kind() == reflect.Slice:
+		return ""
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
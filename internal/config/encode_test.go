@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEncode_Redact(t *testing.T) {
+	cfg := validConfig()
+	cfg.Postgres.Password = "pg-secret"
+	cfg.S3.SecretKey = "s3-secret"
+	cfg.Notifiers.Instances = []NotifierInstanceConfig{
+		{Name: "ops", Type: NotifierTypeGotify, Gotify: GotifyNotifierConfig{URL: "https://gotify.example.com", Token: "gotify-secret"}},
+	}
+	cfg.Jobs = []JobConfig{{Name: "crm", Postgres: PostgresConfig{User: "crm", Password: "job-secret"}}}
+
+	data, err := Encode(cfg, EncodeOptions{Redact: true})
+	require.NoError(t, err)
+	for _, secret := range []string{"pg-secret", "s3-secret", "gotify-secret", "job-secret"} {
+		assert.NotContains(t, string(data), secret)
+	}
+
+	var out struct {
+		Postgres PostgresConfig `yaml:"postgres"`
+		S3       struct {
+			AccessKey string `yaml:"access-key"`
+			Bucket    string `yaml:"bucket"`
+		} `yaml:"s3"`
+		Jobs []struct {
+			Postgres struct {
+				User     string `yaml:"user"`
+				Password string `yaml:"password"`
+			} `yaml:"postgres"`
+		} `yaml:"jobs"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &out))
+	assert.Equal(t, Redacted, out.Postgres.Password)
+	assert.Empty(t, out.S3.AccessKey, "unset secrets stay empty")
+	assert.Equal(t, "backups", out.S3.Bucket)
+	require.Len(t, out.Jobs, 1)
+	assert.Equal(t, "crm", out.Jobs[0].Postgres.User)
+	assert.Equal(t, Redacted, out.Jobs[0].Postgres.Password)
+
+	data, err = Encode(cfg, EncodeOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "pg-secret")
+}
+
+func TestEncode_Annotations(t *testing.T) {
+	data, err := Encode(validConfig(), EncodeOptions{Annotations: map[string]string{"s3.bucket": "from $STASHLY_S3_BUCKET"}})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "bucket: backups # from $STASHLY_S3_BUCKET\n")
+}
+
+func TestEnvOverrides(t *testing.T) {
+	t.Setenv("STASHLY_S3_BUCKET", "from-env")
+	t.Setenv("STASHLY_POSTGRES_PASSWORD_FILE", "/run/secrets/pg")
+
+	overrides := EnvOverrides()
+	assert.Equal(t, "STASHLY_S3_BUCKET", overrides["s3.bucket"])
+	assert.Equal(t, "STASHLY_POSTGRES_PASSWORD_FILE", overrides["postgres.password"])
+	assert.NotContains(t, overrides, "s3.region")
+}
+
+func TestNewConfig(t *testing.T) {
+	cfg, err := NewConfig(map[string]string{
+		"s3.bucket":                "backups",
+		"backup.retention-count":   "7",
+		"backup.jitter":            "5m",
+		"backup.encrypt":           "true",
+		"backup.databases.exclude": "template*,scratch",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "backups", cfg.S3.Bucket)
+	assert.Equal(t, 7, cfg.Backup.RetentionCount)
+	assert.Equal(t, 5*time.Minute, cfg.Backup.Jitter)
+	assert.True(t, cfg.Backup.Encrypt)
+	assert.Equal(t, []string{"template*", "scratch"}, cfg.Backup.Databases.Exclude)
+	assert.Equal(t, "127.0.0.1", cfg.Postgres.Host, "unset settings keep their defaults")
+
+	_, err = NewConfig(map[string]string{"s3.bukket": "backups"})
+	assert.ErrorContains(t, err, `unknown setting "s3.bukket"`)
+
+	_, err = NewConfig(map[string]string{"jobs": "crm"})
+	assert.Error(t, err, "lists of sections cannot be set")
+
+	_, err = NewConfig(map[string]string{"backup.retention-count": "many"})
+	assert.Error(t, err)
+}
+
+func TestDefault(t *testing.T) {
+	assert.Equal(t, "0 0 * * *", Default("backup.cron"))
+	assert.Equal(t, "30s", Default("backup.retry.initial-delay"))
+	assert.Equal(t, "false", Default("backup.encrypt"))
+	assert.Equal(t, "2", Default("secrets.vault.kv-version"))
+	assert.Empty(t, Default("s3.bucket"))
+	assert.Empty(t, Default("backup"))
+	assert.Empty(t, Default("backup.nope"))
+}
+
+func TestSample_Loads(t *testing.T) {
+	data, err := Sample(map[string]string{"s3.bucket": "backups", "backup.cron": "0 3 * * *", "postgres.port": "6432"})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# Bucket the backups are stored in\n  bucket: backups\n")
+
+	cfg, err := LoadConfig(t.Context(), writeFile(t, "config.yaml", string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, "backups", cfg.S3.Bucket)
+	assert.Equal(t, "0 3 * * *", cfg.Backup.Cron)
+	assert.Equal(t, "6432", cfg.Postgres.Port)
+	assert.Equal(t, 30*time.Second, cfg.Backup.Retry.InitialDelay)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestSample_ConfigFileUpToDate(t *testing.T) {
+	want, err := Sample(nil)
+	require.NoError(t, err)
+	got, err := os.ReadFile("../../sample.config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got),
+		"sample.config.yaml is out of date; regenerate it with: stashly config init --non-interactive --force -o sample.config.yaml")
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"path"
+	"regexp"
+	"slices"
+)
+
+// DefaultJobName is the name of the implicit job formed by the top-level settings
+// when no jobs are configured.
+const DefaultJobName = "default"
+
+var jobNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// DatabaseFilterConfig selects which databases are dumped. Patterns use path.Match syntax,
+// e.g. "app_*".
+type DatabaseFilterConfig struct {
+	Include []string `mapstructure:"include"`
+	Exclude []string `mapstructure:"exclude"`
+}
+
+// IsZero reports whether no filters are set.
+func (f DatabaseFilterConfig) IsZero() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Match reports whether a database should be dumped: it must match an include pattern,
+// if any are set, and must not match any exclude pattern.
+func (f DatabaseFilterConfig) Match(name string) bool {
+	matchAny := func(patterns []string) bool {
+		return slices.ContainsFunc(patterns, func(p string) bool {
+			ok, err := path.Match(p, name)
+			return err == nil && ok
+		})
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include) {
+		return false
+	}
+	return !matchAny(f.Exclude)
+}
+
+// JobConfig holds configuration for a named backup job. Unset fields inherit the
+// top-level settings.
+type JobConfig struct {
+	Name           string               `mapstructure:"name"`
+	Cron           string               `mapstructure:"cron"`
+	RetentionCount int                  `mapstructure:"retention-count"`
+	Postgres       PostgresConfig       `mapstructure:"postgres"`
+	Databases      DatabaseFilterConfig `mapstructure:"databases"`
+	S3             S3Config             `mapstructure:"s3"`
+
+	// Notifiers lists the notifier instance names used by this job. Empty uses all notifiers.
+	Notifiers []string `mapstructure:"notifiers"`
+
+	// HeartbeatURL is the job's own heartbeat check, so that one job's pings cannot hide
+	// another job's missed runs. It is required when heartbeat is enabled with several jobs.
+	HeartbeatURL string `mapstructure:"heartbeat-url" secret:"true"`
+}
+
+// JobName returns the name of the job this configuration was resolved for.
+func (c *Config) JobName() string {
+	if c.Job == "" {
+		return DefaultJobName
+	}
+	return c.Job
+}
+
+// BackupJobs returns the resolved configuration of every backup job.
+// Without a jobs list the top-level settings form a single unnamed job.
+func (c *Config) BackupJobs() []*Config {
+	if len(c.Jobs) == 0 {
+		job := *c
+		return []*Config{&job}
+	}
+
+	jobs := make([]*Config, 0, len(c.Jobs))
+	for _, job := range c.Jobs {
+		jobs = append(jobs, c.ForJob(job))
+	}
+	return jobs
+}
+
+// ForJob returns a copy of the configuration with the job's settings applied.
+// The job's S3 prefix defaults to the top-level prefix followed by the job name.
+func (c *Config) ForJob(job JobConfig) *Config {
+	cfg := *c
+	cfg.Job = job.Name
+
+	cfg.Backup.Cron = firstNonEmpty(job.Cron, c.Backup.Cron)
+	if job.RetentionCount > 0 {
+		cfg.Backup.RetentionCount = job.RetentionCount
+	}
+	if !job.Databases.IsZero() {
+		cfg.Backup.Databases = job.Databases
+	}
+
+	cfg.Postgres = PostgresConfig{
+		Host:     firstNonEmpty(job.Postgres.Host, c.Postgres.Host),
+		Port:     firstNonEmpty(job.Postgres.Port, c.Postgres.Port),
+		User:     firstNonEmpty(job.Postgres.User, c.Postgres.User),
+		Password: firstNonEmpty(job.Postgres.Password, c.Postgres.Password),
+	}
+
+	cfg.S3 = S3Config{
+		Endpoint:  firstNonEmpty(job.S3.Endpoint, c.S3.Endpoint),
+		Region:    firstNonEmpty(job.S3.Region, c.S3.Region),
+		AccessKey: firstNonEmpty(job.S3.AccessKey, c.S3.AccessKey),
+		SecretKey: firstNonEmpty(job.S3.SecretKey, c.S3.SecretKey),
+		Bucket:    firstNonEmpty(job.S3.Bucket, c.S3.Bucket),
+		Prefix:    firstNonEmpty(job.S3.Prefix, path.Join(c.S3.Prefix, job.Name)),
+	}
+
+	cfg.Notifiers = c.Notifiers.Select(job.Notifiers)
+	cfg.Heartbeat.URL = firstNonEmpty(job.HeartbeatURL, c.Heartbeat.URL)
+	return &cfg
+}
+
+// Select returns a copy of the notifiers configuration limited to the named notifiers.
+// The legacy per-type blocks are named after their type. An empty list selects all notifiers.
+func (n *NotifiersConfig) Select(names []string) NotifiersConfig {
+	selected := *n
+	if len(names) == 0 {
+		return selected
+	}
+
+	selected.Discord.Enabled = n.Discord.Enabled && slices.Contains(names, NotifierTypeDiscord)
+	selected.Ntfy.Enabled = n.Ntfy.Enabled && slices.Contains(names, NotifierTypeNtfy)
+	selected.Gotify.Enabled = n.Gotify.Enabled && slices.Contains(names, NotifierTypeGotify)
+	selected.Teams.Enabled = n.Teams.Enabled && slices.Contains(names, NotifierTypeTeams)
+
+	instances := []NotifierInstanceConfig{}
+	for _, instance := range n.Instances {
+		if slices.Contains(names, instance.Name) {
+			instances = append(instances, instance)
+		}
+	}
+	selected.Instances = instances
+	return selected
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDatabaseFilterConfig_Match(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter DatabaseFilterConfig
+		db     string
+		want   bool
+	}{
+		{name: "no filters", filter: DatabaseFilterConfig{}, db: "app", want: true},
+		{name: "included", filter: DatabaseFilterConfig{Include: []string{"app_*"}}, db: "app_prod", want: true},
+		{name: "not included", filter: DatabaseFilterConfig{Include: []string{"app_*"}}, db: "crm", want: false},
+		{name: "excluded", filter: DatabaseFilterConfig{Exclude: []string{"*_test"}}, db: "app_test", want: false},
+		{
+			name:   "exclude wins",
+			filter: DatabaseFilterConfig{Include: []string{"app_*"}, Exclude: []string{"app_tmp"}},
+			db:     "app_tmp",
+			want:   false,
+		},
+		{name: "bad pattern", filter: DatabaseFilterConfig{Include: []string{"["}}, db: "app", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(tt.db))
+		})
+	}
+}
+
+func TestConfig_BackupJobs_Default(t *testing.T) {
+	cfg := &Config{
+		Backup: BackupConfig{Cron: "0 0 * * *"},
+		S3:     S3Config{Prefix: "backups"},
+	}
+
+	jobs := cfg.BackupJobs()
+	require.Len(t, jobs, 1)
+	assert.Empty(t, jobs[0].Job)
+	assert.Equal(t, DefaultJobName, jobs[0].JobName())
+	assert.Equal(t, "0 0 * * *", jobs[0].Backup.Cron)
+	assert.Equal(t, "backups", jobs[0].S3.Prefix)
+}
+
+func TestConfig_ForJob(t *testing.T) {
+	cfg := &Config{
+		Postgres: PostgresConfig{Host: "db", Port: "5432", User: "postgres", Password: "secret"},
+		S3:       S3Config{Endpoint: "https://s3", Bucket: "bucket", Prefix: "backups"},
+		Backup:   BackupConfig{Cron: "0 0 * * *", RetentionCount: 30},
+		Notifiers: NotifiersConfig{
+			Enabled: true,
+			Discord: DiscordNotifierConfig{Enabled: true, Webhook: "https://discord"},
+			Instances: []NotifierInstanceConfig{
+				{Name: "oncall", Type: NotifierTypeNtfy},
+				{Name: "team", Type: NotifierTypeTeams},
+			},
+		},
+	}
+
+	job := cfg.ForJob(JobConfig{
+		Name:           "orders",
+		Cron:           "0 3 * * *",
+		RetentionCount: 7,
+		Postgres:       PostgresConfig{Host: "orders-db", Password: "orders"},
+		Databases:      DatabaseFilterConfig{Include: []string{"orders*"}},
+		Notifiers:      []string{"oncall"},
+	})
+
+	assert.Equal(t, "orders", job.Job)
+	assert.Equal(t, "0 3 * * *", job.Backup.Cron)
+	assert.Equal(t, 7, job.Backup.RetentionCount)
+	assert.Equal(t, PostgresConfig{Host: "orders-db", Port: "5432", User: "postgres", Password: "orders"}, job.Postgres)
+	assert.Equal(t, []string{"orders*"}, job.Backup.Databases.Include)
+	assert.Equal(t, "bucket", job.S3.Bucket)
+	assert.Equal(t, "backups/orders", job.S3.Prefix)
+	assert.False(t, job.Notifiers.Discord.Enabled)
+	require.Len(t, job.Notifiers.Instances, 1)
+	assert.Equal(t, "oncall", job.Notifiers.Instances[0].Name)
+
+	// The top-level configuration is left untouched
+	assert.Equal(t, "db", cfg.Postgres.Host)
+	assert.True(t, cfg.Notifiers.Discord.Enabled)
+	assert.Len(t, cfg.Notifiers.Instances, 2)
+
+	inherited := cfg.ForJob(JobConfig{Name: "crm", S3: S3Config{Prefix: "crm-backups"}})
+	assert.Equal(t, "0 0 * * *", inherited.Backup.Cron)
+	assert.Equal(t, 30, inherited.Backup.RetentionCount)
+	assert.Equal(t, "crm-backups", inherited.S3.Prefix)
+	assert.True(t, inherited.Notifiers.Discord.Enabled)
+	assert.Len(t, inherited.Notifiers.Instances, 2)
+}
+
+func TestLoadConfig_Jobs(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := map[string]interface{}{
+		"backup": map[string]interface{}{
+			"cron": "0 1 * * *",
+		},
+		"jobs": []map[string]interface{}{
+			{
+				"name": "orders",
+				"cron": "*/30 * * * *",
+				"postgres": map[string]string{
+					"host": "orders-db",
+				},
+				"databases": map[string]interface{}{
+					"exclude": []string{"*_test"},
+				},
+			},
+			{
+				"postgres": map[string]string{
+					"host": "crm-db",
+				},
+			},
+			{
+				"name": "orders",
+			},
+			{
+				"name": "bad/name",
+			},
+		},
+	}
+
+	//nolint:gosec // Safe in tests - using t.TempDir()
+	f, err := os.Create(configFile)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	err = yaml.NewEncoder(f).Encode(content)
+	require.NoError(t, err)
+
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, configFile)
+	require.NoError(t, err)
+
+	// Invalid and duplicate jobs are kept for Validate to report
+	require.Len(t, cfg.Jobs, 4)
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+	assert.Contains(t, vErr.Errors, FieldError{Field: "jobs[2].name", Message: `duplicate job name "orders"`})
+	assert.Contains(t, vErr.Errors, FieldError{Field: "jobs[3].name", Message: `invalid job name "bad/name": use letters, digits, - and _`})
+
+	jobs := cfg.BackupJobs()
+	assert.Equal(t, "orders", jobs[0].JobName())
+	assert.Equal(t, "*/30 * * * *", jobs[0].Backup.Cron)
+	assert.Equal(t, "orders-db", jobs[0].Postgres.Host)
+	assert.Equal(t, []string{"*_test"}, jobs[0].Backup.Databases.Exclude)
+	assert.Equal(t, "job-1", jobs[1].JobName())
+	assert.Equal(t, "0 1 * * *", jobs[1].Backup.Cron)
+	assert.Equal(t, "crm-db", jobs[1].Postgres.Host)
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hibare/stashly/internal/scheduler"
+)
+
+// Blackout actions supported by BlackoutConfig.Action.
+const (
+	BlackoutSkip  = "skip"
+	BlackoutDefer = "defer"
+)
+
+// MaintenanceConfig restricts when scheduled backups may run.
+type MaintenanceConfig struct {
+	// MaxDuration cancels a scheduled backup that runs longer; zero means no limit.
+	MaxDuration time.Duration `mapstructure:"max-duration"`
+
+	// Blackouts are windows during which scheduled backups do not run.
+	Blackouts []BlackoutConfig `mapstructure:"blackouts"`
+}
+
+// BlackoutConfig describes a blackout window, either recurring (Cron and Duration) or a single
+// fixed period (From and To, RFC 3339 timestamps).
+type BlackoutConfig struct {
+	Name     string        `mapstructure:"name"`
+	Cron     string        `mapstructure:"cron"`
+	Duration time.Duration `mapstructure:"duration"`
+	From     string        `mapstructure:"from"`
+	To       string        `mapstructure:"to"`
+
+	// Action is BlackoutSkip (default) or BlackoutDefer, which runs the backup once the window ends.
+	Action string `mapstructure:"action"`
+
+	// Jobs limits the window to the named backup jobs; empty applies it to all jobs.
+	Jobs []string `mapstructure:"jobs"`
+}
+
+func (b *BlackoutConfig) applyDefaults(index int) {
+	if b.Name == "" {
+		b.Name = fmt.Sprintf("blackout-%d", index)
+	}
+	if b.Action == "" {
+		b.Action = BlackoutSkip
+	}
+}
+
+// Window returns the scheduler window described by the configuration.
+// Recurring windows are evaluated in the backup timezone.
+func (b *BlackoutConfig) Window() (scheduler.Window, error) {
+	if b.Action != BlackoutSkip && b.Action != BlackoutDefer {
+		return scheduler.Window{}, fmt.Errorf("invalid action %q", b.Action)
+	}
+	deferRuns := b.Action == BlackoutDefer
+
+	switch {
+	case b.Cron != "" && (b.From != "" || b.To != ""):
+		return scheduler.Window{}, errors.New("set either cron and duration or from and to")
+	case b.Cron != "":
+		return scheduler.NewRecurringWindow(b.Name, b.Cron, b.Duration, deferRuns)
+	case b.From != "" && b.To != "":
+		from, err := time.Parse(time.RFC3339, b.From)
+		if err != nil {
+			return scheduler.Window{}, fmt.Errorf("invalid from: %w", err)
+		}
+		to, err := time.Parse(time.RFC3339, b.To)
+		if err != nil {
+			return scheduler.Window{}, fmt.Errorf("invalid to: %w", err)
+		}
+		return scheduler.NewFixedWindow(b.Name, from, to, deferRuns)
+	default:
+		return scheduler.Window{}, errors.New("cron and duration or from and to are required")
+	}
+}
+
+// Applies reports whether the window applies to the named backup job.
+func (b *BlackoutConfig) Applies(job string) bool {
+	return len(b.Jobs) == 0 || slices.Contains(b.Jobs, job)
+}
+
+// Windows returns the blackout windows applying to the named backup job.
+// Invalid windows, which Validate rejects, are left out.
+func (m *MaintenanceConfig) Windows(job string) []scheduler.Window {
+	windows := []scheduler.Window{}
+	for _, b := range m.Blackouts {
+		if !b.Applies(job) {
+			continue
+		}
+		if w, err := b.Window(); err == nil {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBlackoutConfig_Window(t *testing.T) {
+	tests := []struct {
+		name     string
+		blackout BlackoutConfig
+		wantErr  bool
+	}{
+		{name: "recurring", blackout: BlackoutConfig{Cron: "0 18 28-31 * *", Duration: 6 * time.Hour, Action: BlackoutSkip}},
+		{
+			name:     "fixed",
+			blackout: BlackoutConfig{From: "2024-12-20T00:00:00Z", To: "2025-01-02T00:00:00+01:00", Action: BlackoutDefer},
+		},
+		{name: "missing duration", blackout: BlackoutConfig{Cron: "0 18 * * *", Action: BlackoutSkip}, wantErr: true},
+		{name: "missing to", blackout: BlackoutConfig{From: "2024-12-20T00:00:00Z", Action: BlackoutSkip}, wantErr: true},
+		{name: "bad timestamp", blackout: BlackoutConfig{From: "2024-12-20", To: "2025-01-02", Action: BlackoutSkip}, wantErr: true},
+		{
+			name:     "both kinds",
+			blackout: BlackoutConfig{Cron: "0 18 * * *", Duration: time.Hour, From: "2024-12-20T00:00:00Z", Action: BlackoutSkip},
+			wantErr:  true,
+		},
+		{name: "bad action", blackout: BlackoutConfig{Cron: "0 18 * * *", Duration: time.Hour, Action: "pause"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := tt.blackout.Window()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.blackout.Action == BlackoutDefer, w.Defer)
+		})
+	}
+}
+
+func TestMaintenanceConfig_Windows(t *testing.T) {
+	m := MaintenanceConfig{Blackouts: []BlackoutConfig{
+		{Name: "all", Cron: "0 18 * * *", Duration: time.Hour, Action: BlackoutSkip},
+		{Name: "finance-close", Cron: "0 18 28-31 * *", Duration: 6 * time.Hour, Action: BlackoutSkip, Jobs: []string{"finance"}},
+	}}
+
+	names := func(job string) []string {
+		out := []string{}
+		for _, w := range m.Windows(job) {
+			out = append(out, w.Name)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"all", "finance-close"}, names("finance"))
+	assert.Equal(t, []string{"all"}, names("orders"))
+}
+
+func TestLoadConfig_Maintenance(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := map[string]interface{}{
+		"maintenance": map[string]interface{}{
+			"max-duration": "2h",
+			"blackouts": []map[string]interface{}{
+				{
+					"name":     "finance-close",
+					"cron":     "0 18 28-31 * *",
+					"duration": "6h",
+					"jobs":     []string{"finance"},
+				},
+				{
+					"from":   "2024-12-20T00:00:00Z",
+					"to":     "2025-01-02T00:00:00Z",
+					"action": "defer",
+				},
+				{
+					"name": "broken",
+					"cron": "0 18 * * *",
+				},
+			},
+		},
+	}
+
+	//nolint:gosec // Safe in tests - using t.TempDir()
+	f, err := os.Create(configFile)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	err = yaml.NewEncoder(f).Encode(content)
+	require.NoError(t, err)
+
+	ctx := t.Context()
+	cfg, err := LoadConfig(ctx, configFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2*time.Hour, cfg.Maintenance.MaxDuration)
+
+	// Defaults are applied; invalid windows are kept for Validate to report
+	require.Len(t, cfg.Maintenance.Blackouts, 3)
+	assert.Equal(t, "finance-close", cfg.Maintenance.Blackouts[0].Name)
+	assert.Equal(t, BlackoutSkip, cfg.Maintenance.Blackouts[0].Action)
+	assert.Equal(t, "blackout-1", cfg.Maintenance.Blackouts[1].Name)
+	assert.Equal(t, BlackoutDefer, cfg.Maintenance.Blackouts[1].Action)
+
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+	assert.Contains(t, vErr.Errors, FieldError{
+		Field:   "maintenance.blackouts[2]",
+		Message: "broken: duration must be positive",
+	})
+}
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// schemaDraft is the JSON Schema dialect of the schema returned by Schema.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches the durations accepted by time.ParseDuration, e.g. "90s" or "1h30m".
+const durationPattern = `^-?(0|([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+)$`
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// descriptions documents every setting, keyed by the struct type declaring it and its config key.
+// They are used for the JSON Schema and the comments of the generated config file; a test keeps
+// them in sync with the structs.
+var descriptions = map[reflect.Type]map[string]string{
+	reflect.TypeFor[Config](): {
+		"app":         "Application settings",
+		"postgres":    "PostgreSQL server to back up",
+		"s3":          "S3 or S3-compatible storage the backups are uploaded to",
+		"backup":      "Backup schedule, retention and run settings",
+		"encryption":  "Encryption of the backup archives (see backup.encrypt)",
+		"notifiers":   "Notifications about backup runs",
+		"heartbeat":   "Dead-man's-switch heartbeat pings",
+		"lock":        "Distributed lock for HA deployments sharing a bucket",
+		"maintenance": "Maintenance windows for scheduled backups",
+		"control":     `Local control socket used by "stashly ctl"`,
+		"history":     `Run history queried by "stashly history"`,
+		"http":        "HTTP listener serving Prometheus metrics and health probes",
+		"api":         `REST API served by "stashly serve"`,
+		"secrets":     "Providers for file://, env:// and vault:// references in secret settings",
+		"logger":      "Logging",
+		"jobs":        "Named backup jobs; unset job settings inherit the top-level settings",
+	},
+	reflect.TypeFor[AppConfig](): {
+		"instance-id": "Identifies this instance in events, metrics and locks; defaults to the hostname",
+	},
+	reflect.TypeFor[PostgresConfig](): {
+		"host":     "Server host name or address",
+		"port":     "Server port",
+		"user":     "User the databases are dumped as",
+		"password": "Password of the user",
+	},
+	reflect.TypeFor[S3Config](): {
+		"endpoint":   "Endpoint URL of an S3-compatible service; empty for AWS S3",
+		"region":     "Region of the bucket",
+		"access-key": "Access key ID",
+		"secret-key": "Secret access key",
+		"bucket":     "Bucket the backups are stored in",
+		"prefix":     "Key prefix of the backups in the bucket",
+	},
+	reflect.TypeFor[BackupConfig](): {
+		"retention-count":       "Number of backups to retain",
+		"date-time-layout":      "Not used; backup names always use the layout 20060102150405, the only accepted value",
+		"cron":                  "Cron schedule of the backups, e.g. 0 0 * * * for daily at midnight",
+		"encrypt":               "Encrypt the backup archives with the GPG key (see encryption.gpg)",
+		"databases":             "Databases to dump; all databases by default",
+		"timezone":              "IANA time zone the cron schedule is evaluated in",
+		"jitter":                "Random delay of up to this duration before each scheduled run",
+		"catch-up":              "Run at startup if a scheduled backup was missed since the last backup",
+		"retry":                 "Automatic retries of failed runs",
+		"overlap":               "What happens to a run that is due while the previous run is still in progress",
+		"shutdown-grace-period": "Time a running backup may finish after a shutdown signal; 0 cancels immediately",
+		"timeout":               "Fail a run, including its retries, that takes longer; 0 means no limit",
+		"database-timeout":      "Kill a single pg_dump that takes longer; 0 means no limit",
+		"lock-wait-timeout":     "pg_dump --lock-wait-timeout: fail instead of waiting for table locks; 0 waits forever",
+		"workspace-dir":         "Parent directory of the per-run workspaces; empty uses the system temp directory",
+		"keep-local":            "Keep the local dumps and archive after the run instead of removing them",
+		"space-check":           "Fail early if the workspace lacks free space for the estimated database size",
+	},
+	reflect.TypeFor[DatabaseFilterConfig](): {
+		"include": "Glob patterns of the databases to dump, e.g. app_*; empty includes all databases",
+		"exclude": "Glob patterns of the databases not to dump",
+	},
+	reflect.TypeFor[RetryConfig](): {
+		"max-attempts":  "Total number of attempts per run, including the first; 1 disables retries",
+		"initial-delay": "Wait before the first retry",
+		"multiplier":    "Factor the delay grows by after each retry",
+		"per-stage":     "Retry failed databases and uploads instead of the whole run",
+	},
+	reflect.TypeFor[Encryption](): {
+		"gpg": "GPG public key the archives are encrypted with",
+	},
+	reflect.TypeFor[GPGConfig](): {
+		"key-server": "Key server the public key is downloaded from, e.g. keyserver.ubuntu.com",
+		"key-id":     "ID or fingerprint of the public key",
+	},
+	reflect.TypeFor[NotifiersConfig](): {
+		"enabled":   "Send notifications",
+		"discord":   "Discord notifier",
+		"ntfy":      "ntfy notifier",
+		"gotify":    "Gotify notifier",
+		"teams":     "Microsoft Teams notifier",
+		"instances": "Any number of named notifiers, in addition to the per-type blocks",
+	},
+	reflect.TypeFor[NotifierInstanceConfig](): {
+		"name":    "Name of the notifier, referenced by jobs[].notifiers; defaults to <type>-<index>",
+		"type":    "Type of the notifier; only the settings block of this type is used",
+		"events":  "Event types sent to the notifier; empty sends the default events",
+		"discord": "Settings of a discord notifier",
+		"ntfy":    "Settings of an ntfy notifier",
+		"gotify":  "Settings of a gotify notifier",
+		"teams":   "Settings of a teams notifier",
+	},
+	reflect.TypeFor[DiscordNotifierConfig](): {
+		"enabled": "Send notifications to Discord",
+		"webhook": "Discord webhook URL",
+	},
+	reflect.TypeFor[NtfyNotifierConfig](): {
+		"enabled":  "Send notifications to ntfy",
+		"url":      "Topic URL, e.g. https://ntfy.sh/my-backups",
+		"token":    "Access token; empty for public topics",
+		"tags":     "Tags added to the messages",
+		"priority": "Message priority per event, from 1 (min) to 5 (urgent)",
+	},
+	reflect.TypeFor[GotifyNotifierConfig](): {
+		"enabled":  "Send notifications to Gotify",
+		"url":      "Gotify server URL",
+		"token":    "Application token",
+		"priority": "Message priority per event, from 0 to 10",
+	},
+	reflect.TypeFor[NotifierPriorityConfig](): {
+		"success":        "Priority of successful backups",
+		"failure":        "Priority of failed backups",
+		"delete-failure": "Priority of failures to delete old backups",
+	},
+	reflect.TypeFor[TeamsNotifierConfig](): {
+		"enabled": "Send notifications to Microsoft Teams",
+		"webhook": "Teams workflow webhook URL",
+	},
+	reflect.TypeFor[HeartbeatConfig](): {
+		"enabled":    "Ping a monitoring service when runs start, succeed and fail",
+		"provider":   "Monitoring service the URL belongs to",
+		"url":        "Ping URL of the check",
+		"timeout":    "Timeout of each ping",
+		"tail-lines": "Run log lines sent with success and failure pings",
+	},
+	reflect.TypeFor[LockConfig](): {
+		"enabled": "Hold a lock in the bucket while a backup runs, so only one instance backs up",
+		"ttl":     "Time after which a lock that is no longer refreshed expires, e.g. after a crash",
+		"owner":   "Identifies this instance in the lock; defaults to app.instance-id",
+	},
+	reflect.TypeFor[MaintenanceConfig](): {
+		"max-duration": "Cancel a scheduled backup that runs longer; 0 means no limit",
+		"blackouts":    "Windows during which scheduled backups are skipped or deferred",
+	},
+	reflect.TypeFor[BlackoutConfig](): {
+		"name":     "Name of the window; defaults to blackout-<index>",
+		"cron":     "Cron schedule of the start of a recurring window",
+		"duration": "Length of a recurring window",
+		"from":     "Start of a one-off window (RFC 3339)",
+		"to":       "End of a one-off window (RFC 3339)",
+		"action":   "Skip runs due in the window, or defer them until it ends",
+		"jobs":     "Backup jobs the window applies to; empty applies it to all jobs",
+	},
+	reflect.TypeFor[ControlConfig](): {
+		"enabled": "Listen on the control socket",
+		"socket":  "Path of the control socket",
+	},
+	reflect.TypeFor[HistoryConfig](): {
+		"enabled": "Record every run",
+		"path":    "Path of the local history file",
+		"upload":  "Also store each entry in the bucket under <s3.prefix>/.stashly/history/",
+	},
+	reflect.TypeFor[HTTPConfig](): {
+		"enabled":       "Serve /metrics, /healthz and /readyz",
+		"listen":        "Listen address",
+		"ready-max-age": "Fail /readyz once a job's newest backup is older than this, e.g. 26h; 0 disables the check",
+	},
+	reflect.TypeFor[APIConfig](): {
+		"listen":    "Listen address",
+		"token":     "Bearer token clients must present; serve refuses to start without one",
+		"tls-cert":  "PEM certificate file; serves HTTPS together with tls-key",
+		"tls-key":   "PEM private key file",
+		"dashboard": "Serve the web dashboard at the root of the API listener",
+	},
+	reflect.TypeFor[SecretsConfig](): {
+		"vault": "HashiCorp Vault KV provider for vault://<mount>/<path>#<key> references",
+	},
+	reflect.TypeFor[VaultConfig](): {
+		"address":    "Vault server URL; enables the provider",
+		"token":      "Vault token",
+		"namespace":  "Vault Enterprise namespace; empty for the root namespace",
+		"kv-version": "Version of the KV secrets engine",
+		"timeout":    "Timeout of each request to Vault; 0 uses 10s",
+	},
+	reflect.TypeFor[LoggerConfig](): {
+		"level": "Log level: debug, info, warn or error",
+		"mode":  "Log format: pretty or json",
+	},
+	reflect.TypeFor[JobConfig](): {
+		"name":            "Name of the job; letters, digits, - and _",
+		"cron":            "Cron schedule of the job",
+		"retention-count": "Number of backups of the job to retain",
+		"postgres":        "PostgreSQL server of the job",
+		"databases":       "Databases the job dumps",
+		"s3":              "Storage of the job; the prefix defaults to <s3.prefix>/<name>",
+		"notifiers":       "Names of the notifiers used by the job; empty uses all notifiers",
+		"heartbeat-url":   "Heartbeat URL of the job; required when heartbeat is enabled with several jobs",
+	},
+}
+
+// enums lists the allowed values of settings that accept a fixed set, keyed like descriptions.
+var enums = map[reflect.Type]map[string][]any{
+	reflect.TypeFor[BackupConfig](): {
+		"overlap": {OverlapSkip, OverlapQueue},
+	},
+	reflect.TypeFor[NotifierInstanceConfig](): {
+		"type": {NotifierTypeDiscord, NotifierTypeNtfy, NotifierTypeGotify, NotifierTypeTeams},
+	},
+	reflect.TypeFor[HeartbeatConfig](): {
+		"provider": {"healthchecks", "uptime-kuma", "cronitor"},
+	},
+	reflect.TypeFor[BlackoutConfig](): {
+		"action": {BlackoutSkip, BlackoutDefer},
+	},
+	reflect.TypeFor[VaultConfig](): {
+		"kv-version": {1, 2},
+	},
+}
+
+// settingFields returns the fields of a configuration struct that are settings.
+func settingFields(t reflect.Type) []reflect.StructField {
+	fields := []reflect.StructField{}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.IsExported() && f.Tag.Get("mapstructure") != "-" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// isSecret reports whether the field holds a secret such as a password or token.
+func isSecret(f reflect.StructField) bool {
+	return f.Tag.Get("secret") == "true"
+}
+
+// describe returns the description of a setting.
+func describe(parent reflect.Type, f reflect.StructField) string {
+	desc := descriptions[parent][f.Tag.Get("mapstructure")]
+	switch {
+	case isSecret(f) && parent == reflect.TypeFor[VaultConfig]():
+		desc += "; may be a file:// or env:// reference"
+	case isSecret(f):
+		desc += "; may be a file://, env:// or vault:// reference"
+	}
+	return desc
+}
+
+// Schema returns a JSON Schema of the config file, generated from the Config struct, for editors
+// and CI to validate config files against.
+func Schema() map[string]any {
+	defaults := viper.New()
+	setDefaults(defaults)
+
+	schema := typeSchema(reflect.TypeFor[Config](), "", defaults)
+	schema["$schema"] = schemaDraft
+	schema["title"] = "Stashly configuration"
+	return schema
+}
+
+// typeSchema returns the schema of a setting of type t. Defaults are looked up by key, which is
+// empty inside list entries.
+func typeSchema(t reflect.Type, key string, defaults *viper.Viper) map[string]any {
+	schema := map[string]any{}
+	switch {
+	case t == durationType:
+		schema["type"] = "string"
+		schema["pattern"] = durationPattern
+	case t.Kind() == reflect.String:
+		schema["type"] = "string"
+	case t.Kind() == reflect.Bool:
+		schema["type"] = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		schema["type"] = "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema["type"] = "number"
+	case t.Kind() == reflect.Slice:
+		schema["type"] = "array"
+		schema["items"] = typeSchema(t.Elem(), "", defaults)
+	case t.Kind() == reflect.Struct:
+		properties := map[string]any{}
+		for _, f := range settingFields(t) {
+			name := f.Tag.Get("mapstructure")
+			childKey := ""
+			if key != "" || t == reflect.TypeFor[Config]() {
+				childKey = strings.TrimPrefix(key+"."+name, ".")
+			}
+			property := typeSchema(f.Type, childKey, defaults)
+			if desc := describe(t, f); desc != "" {
+				property["description"] = desc
+			}
+			if values, ok := enums[t][name]; ok {
+				property["enum"] = values
+			}
+			if isSecret(f) {
+				property["writeOnly"] = true
+			}
+			properties[name] = property
+		}
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["additionalProperties"] = false
+	default:
+	}
+
+	if key != "" && t.Kind() != reflect.Struct && defaults.IsSet(key) {
+		value := defaults.Get(key)
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		schema["default"] = value
+	}
+	return schema
+}
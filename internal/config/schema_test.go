@@ -0,0 +1,112 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// settingTypes returns the configuration struct types reachable from t.
+func settingTypes(t reflect.Type, types map[reflect.Type]bool) {
+	types[t] = true
+	for _, f := range settingFields(t) {
+		ft := f.Type
+		if ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft != durationType && !types[ft] {
+			settingTypes(ft, types)
+		}
+	}
+}
+
+func TestDescriptions_MatchStructs(t *testing.T) {
+	types := map[reflect.Type]bool{}
+	settingTypes(reflect.TypeFor[Config](), types)
+
+	for typ := range types {
+		fields := map[string]bool{}
+		for _, f := range settingFields(typ) {
+			name := f.Tag.Get("mapstructure")
+			fields[name] = true
+			assert.NotEmpty(t, descriptions[typ][name], "%s.%s has no description", typ.Name(), name)
+		}
+		for name := range descriptions[typ] {
+			assert.True(t, fields[name], "description of unknown setting %s.%s", typ.Name(), name)
+		}
+		for name := range enums[typ] {
+			assert.True(t, fields[name], "enum of unknown setting %s.%s", typ.Name(), name)
+		}
+	}
+	for typ := range descriptions {
+		assert.True(t, types[typ], "descriptions of unused type %s", typ.Name())
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	_, err := json.Marshal(schema)
+	require.NoError(t, err)
+
+	property := func(path ...string) map[string]any {
+		t.Helper()
+		s := schema
+		for _, name := range path {
+			if name == "[]" {
+				s = s["items"].(map[string]any)
+				continue
+			}
+			properties, ok := s["properties"].(map[string]any)
+			require.True(t, ok, "no properties at %s", name)
+			s, ok = properties[name].(map[string]any)
+			require.True(t, ok, "no property %s", name)
+		}
+		return s
+	}
+
+	assert.Equal(t, schemaDraft, schema["$schema"])
+	assert.Equal(t, false, schema["additionalProperties"])
+
+	password := property("postgres", "password")
+	assert.Equal(t, "string", password["type"])
+	assert.Equal(t, true, password["writeOnly"])
+	assert.Contains(t, password["description"], "vault://")
+
+	assert.Equal(t, "127.0.0.1", property("postgres", "host")["default"])
+	assert.Equal(t, "integer", property("backup", "retention-count")["type"])
+	assert.Equal(t, "boolean", property("backup", "encrypt")["type"])
+	assert.Equal(t, "number", property("backup", "retry", "multiplier")["type"])
+	assert.Equal(t, []any{OverlapSkip, OverlapQueue}, property("backup", "overlap")["enum"])
+
+	initialDelay := property("backup", "retry", "initial-delay")
+	assert.Equal(t, "string", initialDelay["type"])
+	assert.Equal(t, "30s", initialDelay["default"])
+	assert.Equal(t, durationPattern, initialDelay["pattern"])
+
+	include := property("backup", "databases", "include")
+	assert.Equal(t, "array", include["type"])
+	assert.Equal(t, "string", include["items"].(map[string]any)["type"])
+
+	jobs := property("jobs")
+	assert.Equal(t, "array", jobs["type"])
+	assert.Equal(t, true, property("jobs", "[]", "s3", "secret-key")["writeOnly"])
+	assert.NotContains(t, property("jobs", "[]", "postgres", "host"), "default", "list entries inherit instead of defaulting")
+	assert.Equal(t, false, property("jobs", "[]")["additionalProperties"])
+
+	assert.NotContains(t, property("app", "instance-id"), "default", "the hostname default is machine-specific")
+	assert.NotContains(t, property("secrets", "vault", "token")["description"], "vault://")
+}
+
+func TestDurationPattern(t *testing.T) {
+	re := regexp.MustCompile(durationPattern)
+	for _, d := range []string{"0", "0s", "30s", "1h30m", "1.5h", "250ms", "-5m"} {
+		assert.True(t, re.MatchString(d), d)
+	}
+	for _, d := range []string{"", "30", "1d", "soon"} {
+		assert.False(t, re.MatchString(d), d)
+	}
+}
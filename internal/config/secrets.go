@@ -0,0 +1,129 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibare/stashly/internal/secrets"
+	"github.com/spf13/viper"
+)
+
+// fileEnvSuffix marks an environment variable holding the path of a file with the setting's value,
+// following the Docker and Kubernetes secrets convention, e.g. STASHLY_POSTGRES_PASSWORD_FILE.
+const fileEnvSuffix = "_FILE"
+
+// SecretsConfig configures the external providers secret settings can reference.
+type SecretsConfig struct {
+	Vault VaultConfig `mapstructure:"vault"`
+}
+
+// VaultConfig configures the HashiCorp Vault KV provider for vault:// references.
+// The provider is enabled when Address is set.
+type VaultConfig struct {
+	Address string `mapstructure:"address"`
+
+	// Token may itself be a file:// or env:// reference, e.g. to a Vault agent sink.
+	Token     string        `mapstructure:"token" secret:"true"`
+	Namespace string        `mapstructure:"namespace"`
+	KVVersion int           `mapstructure:"kv-version"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+// applySecretFiles sets each setting whose environment variable has a _FILE variant to the content
+// of that file. Setting both variables is an error, as it is unclear which one is meant.
+func applySecretFiles(v *viper.Viper, envBindings map[string]string) error {
+	for configKey, envVar := range envBindings {
+		path, ok := os.LookupEnv(envVar + fileEnvSuffix)
+		if !ok {
+			continue
+		}
+		if _, set := os.LookupEnv(envVar); set {
+			return fmt.Errorf("both %s and %s%s are set", envVar, envVar, fileEnvSuffix)
+		}
+		value, err := secrets.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s%s: %w", envVar, fileEnvSuffix, err)
+		}
+		v.Set(configKey, value)
+	}
+	return nil
+}
+
+// resolveSecrets replaces file://, env:// and vault:// references in secret settings with the
+// values they point to. The Vault settings are resolved first, so the Vault token can be read from a file.
+func resolveSecrets(ctx context.Context, cfg *Config) error {
+	resolver := secrets.NewResolver(secrets.File{}, secrets.Env{})
+
+	vault := &cfg.Secrets.Vault
+	var err error
+	if vault.Token, err = resolver.Resolve(ctx, vault.Token); err != nil {
+		return fmt.Errorf("secrets.vault.token: %w", err)
+	}
+	if vault.Address != "" {
+		resolver.Register(secrets.NewVault(secrets.VaultOptions{
+			Address:   vault.Address,
+			Token:     vault.Token,
+			Namespace: vault.Namespace,
+			KVVersion: vault.KVVersion,
+			Timeout:   vault.Timeout,
+		}))
+	}
+
+	return walkSecrets(reflect.ValueOf(cfg).Elem(), "", func(field string, value *string) error {
+		resolved, rErr := resolver.Resolve(ctx, *value)
+		if errors.Is(rErr, secrets.ErrUnknownScheme) && vault.Address == "" && strings.HasPrefix(*value, "vault://") {
+			return fmt.Errorf("%s: %w: vault:// references require secrets.vault.address", field, rErr)
+		}
+		if rErr != nil {
+			return fmt.Errorf("%s: %w", field, rErr)
+		}
+		*value = resolved
+		return nil
+	})
+}
+
+// walkSecrets calls fn for every string field tagged secret:"true", named by its config key,
+// e.g. "jobs[0].postgres.password".
+func walkSecrets(v reflect.Value, prefix string, fn func(field string, value *string) error) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name := f.Tag.Get("mapstructure")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			fv := v.Field(i)
+			if f.Tag.Get("secret") == "true" && fv.Kind() == reflect.String {
+				if fv.String() == "" {
+					continue
+				}
+				if err := fn(name, fv.Addr().Interface().(*string)); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := walkSecrets(fv, name, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			if err := walkSecrets(v.Index(i), prefix+"["+strconv.Itoa(i)+"]", fn); err != nil {
+				return err
+			}
+		}
+	default:
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hibare/stashly/internal/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadConfig_SecretFileEnv(t *testing.T) {
+	t.Setenv("STASHLY_POSTGRES_PASSWORD_FILE", writeFile(t, "pg-password", "from-file\n"))
+	t.Setenv("STASHLY_S3_SECRET_KEY_FILE", writeFile(t, "s3-secret", "s3-from-file"))
+
+	cfg, err := LoadConfig(t.Context(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", cfg.Postgres.Password)
+	assert.Equal(t, "s3-from-file", cfg.S3.SecretKey)
+}
+
+func TestLoadConfig_SecretFileEnvConflict(t *testing.T) {
+	t.Setenv("STASHLY_POSTGRES_PASSWORD", "plain")
+	t.Setenv("STASHLY_POSTGRES_PASSWORD_FILE", writeFile(t, "pg-password", "from-file"))
+
+	_, err := LoadConfig(t.Context(), "")
+	require.EqualError(t, err, "both STASHLY_POSTGRES_PASSWORD and STASHLY_POSTGRES_PASSWORD_FILE are set")
+}
+
+func TestLoadConfig_SecretReferences(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" || r.URL.Path != "/v1/secret/data/stashly/discord" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"webhook":"https://discord.com/api/webhooks/1"}}}`))
+	}))
+	defer vault.Close()
+
+	t.Setenv("ORDERS_DB_PASSWORD", "orders-pass")
+	configFile := writeFile(t, "config.yaml", `
+postgres:
+  password: "file://`+writeFile(t, "pg-password", "pg-pass\n")+`"
+s3:
+  access-key: plain-access-key
+secrets:
+  vault:
+    address: "`+vault.URL+`"
+    token: "file://`+writeFile(t, "vault-token", "vault-token\n")+`"
+notifiers:
+  discord:
+    enabled: true
+    webhook: "vault://secret/stashly/discord#webhook"
+  teams:
+    webhook: "https://example.com/env://not-a-reference"
+jobs:
+  - name: orders
+    postgres:
+      password: "env://ORDERS_DB_PASSWORD"
+`)
+
+	cfg, err := LoadConfig(t.Context(), configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "pg-pass", cfg.Postgres.Password)
+	assert.Equal(t, "plain-access-key", cfg.S3.AccessKey)
+	assert.Equal(t, "vault-token", cfg.Secrets.Vault.Token)
+	assert.Equal(t, "https://discord.com/api/webhooks/1", cfg.Notifiers.Discord.Webhook)
+	assert.Equal(t, "https://example.com/env://not-a-reference", cfg.Notifiers.Teams.Webhook)
+	assert.Equal(t, "orders-pass", cfg.Jobs[0].Postgres.Password)
+}
+
+func TestLoadConfig_SecretReferenceError(t *testing.T) {
+	configFile := writeFile(t, "config.yaml", `
+jobs:
+  - name: orders
+    postgres:
+      password: "env://STASHLY_TEST_UNSET_PASSWORD"
+`)
+
+	_, err := LoadConfig(t.Context(), configFile)
+	require.EqualError(t, err,
+		"error resolving secrets: jobs[0].postgres.password: env secret: environment variable STASHLY_TEST_UNSET_PASSWORD is not set")
+}
+
+func TestLoadConfig_SecretReferenceUnknownScheme(t *testing.T) {
+	for value, want := range map[string]string{
+		"vualt://secret/stashly#password": `error resolving secrets: postgres.password: unknown secret provider "vualt"`,
+		"vault://secret/stashly#password": `error resolving secrets: postgres.password: unknown secret provider "vault": ` +
+			"vault:// references require secrets.vault.address",
+	} {
+		configFile := writeFile(t, "config.yaml", "postgres:\n  password: \""+value+"\"\n")
+
+		_, err := LoadConfig(t.Context(), configFile)
+		require.ErrorIs(t, err, secrets.ErrUnknownScheme, value)
+		require.EqualError(t, err, want)
+	}
+}
+
+func TestLoadConfigWithoutSecrets(t *testing.T) {
+	t.Setenv("STASHLY_S3_SECRET_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	configFile := writeFile(t, "config.yaml", `
+http:
+  listen: ":9090"
+postgres:
+  password: "vault://secret/stashly#password"
+secrets:
+  vault:
+    address: "http://127.0.0.1:1"
+`)
+
+	// Neither the missing secret file nor the unreachable Vault server is touched
+	cfg, err := LoadConfigWithoutSecrets(t.Context(), configFile)
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.HTTP.Listen)
+	assert.Equal(t, "vault://secret/stashly#password", cfg.Postgres.Password)
+
+	_, err = LoadConfig(t.Context(), configFile)
+	require.Error(t, err)
+}
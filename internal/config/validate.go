@@ -0,0 +1,254 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hibare/stashly/internal/constants"
+	"github.com/robfig/cron/v3"
+)
+
+// FieldError describes an invalid configuration setting.
+type FieldError struct {
+	// Field is the setting's key in the config file, e.g. "backup.cron" or "jobs.orders.s3.bucket".
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every invalid setting of a configuration.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Error())
+	}
+	return fmt.Sprintf("invalid configuration (%d errors): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	fe := FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+	// Settings inherited by several jobs are reported once
+	for _, e := range v.errs {
+		if e == fe {
+			return
+		}
+	}
+	v.errs = append(v.errs, fe)
+}
+
+// checkURL reports a value that is not an absolute http(s) URL.
+func (v *validator) checkURL(field, value string) {
+	u, err := url.Parse(value)
+	switch {
+	case err != nil:
+		v.add(field, "malformed URL: %v", err)
+	case u.Scheme != "http" && u.Scheme != "https":
+		v.add(field, "URL %q must start with http:// or https://", value)
+	case u.Host == "":
+		v.add(field, "URL %q has no host", value)
+	}
+}
+
+// Validate checks the configuration before a backup is attempted and returns a *ValidationError
+// listing every invalid setting, or nil. Unlike the sanity checks of LoadConfig, which fall back
+// to defaults, a requested feature that cannot be honoured, such as encryption without a GPG key,
+// is an error.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if len(c.Jobs) == 0 {
+		c.validateJob(v, nil)
+	}
+	seenJobs := map[string]bool{}
+	for i := range c.Jobs {
+		name := c.Jobs[i].Name
+		switch {
+		case !jobNameRe.MatchString(name):
+			v.add(fmt.Sprintf("jobs[%d].name", i), "invalid job name %q: use letters, digits, - and _", name)
+		case seenJobs[name]:
+			v.add(fmt.Sprintf("jobs[%d].name", i), "duplicate job name %q", name)
+		}
+		seenJobs[name] = true
+		c.validateJob(v, &c.Jobs[i])
+	}
+
+	if _, err := time.LoadLocation(c.Backup.Timezone); err != nil {
+		v.add("backup.timezone", "unknown time zone %q", c.Backup.Timezone)
+	}
+	if c.Backup.Overlap != OverlapSkip && c.Backup.Overlap != OverlapQueue {
+		v.add("backup.overlap", "must be %s or %s, got %q", OverlapSkip, OverlapQueue, c.Backup.Overlap)
+	}
+	// Backup names always use the default layout, so any other layout would be silently ignored
+	if layout := c.Backup.DateTimeLayout; layout != "" && layout != constants.DefaultDateTimeLayout {
+		v.add("backup.date-time-layout", "is not used and must be unset or %q, got %q",
+			constants.DefaultDateTimeLayout, layout)
+	}
+
+	if c.Backup.Retry.MaxAttempts < 1 {
+		v.add("backup.retry.max-attempts", "must be at least 1, got %d", c.Backup.Retry.MaxAttempts)
+	}
+	if c.Backup.Retry.Multiplier < 1 {
+		v.add("backup.retry.multiplier", "must be at least 1, got %g", c.Backup.Retry.Multiplier)
+	}
+	for _, timeout := range []struct {
+		field string
+		value time.Duration
+	}{
+		{"backup.timeout", c.Backup.Timeout},
+		{"backup.database-timeout", c.Backup.DatabaseTimeout},
+		{"backup.lock-wait-timeout", c.Backup.LockWaitTimeout},
+		{"http.ready-max-age", c.HTTP.ReadyMaxAge},
+	} {
+		if timeout.value < 0 {
+			v.add(timeout.field, "must not be negative, got %s", timeout.value)
+		}
+	}
+	if c.Lock.Enabled && c.Lock.TTL <= 0 {
+		v.add("lock.ttl", "must be positive when lock is enabled, got %s", c.Lock.TTL)
+	}
+
+	if c.Backup.Encrypt {
+		if c.Encryption.GPG.KeyServer == "" {
+			v.add("encryption.gpg.key-server", "is required when backup.encrypt is enabled")
+		}
+		if c.Encryption.GPG.KeyID == "" {
+			v.add("encryption.gpg.key-id", "is required when backup.encrypt is enabled")
+		}
+	}
+
+	for _, required := range []struct {
+		enabled      bool
+		field, value string
+	}{
+		{c.Control.Enabled, "control.socket", c.Control.Socket},
+		{c.History.Enabled, "history.path", c.History.Path},
+		{c.HTTP.Enabled, "http.listen", c.HTTP.Listen},
+	} {
+		if required.enabled && required.value == "" {
+			v.add(required.field, "is required when %s is enabled", strings.Split(required.field, ".")[0])
+		}
+	}
+
+	for i, blackout := range c.Maintenance.Blackouts {
+		if _, err := blackout.Window(); err != nil {
+			v.add(fmt.Sprintf("maintenance.blackouts[%d]", i), "%s: %v", blackout.Name, err)
+		}
+	}
+
+	if vault := c.Secrets.Vault; vault.Address != "" {
+		v.checkURL("secrets.vault.address", vault.Address)
+		if vault.KVVersion != 1 && vault.KVVersion != 2 {
+			v.add("secrets.vault.kv-version", "must be 1 or 2, got %d", vault.KVVersion)
+		}
+	}
+
+	c.validateNotifiers(v)
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+// validateJob checks the settings a job can override; job is nil for the implicit job.
+// Errors in a job's own settings are reported under jobs.<name>, inherited ones under the top-level key.
+func (c *Config) validateJob(v *validator, job *JobConfig) {
+	cfg := c
+	field := func(key, jobKey string, overridden bool) string {
+		if job != nil && overridden {
+			return "jobs." + job.Name + "." + jobKey
+		}
+		return key
+	}
+	if job != nil {
+		cfg = c.ForJob(*job)
+		if job.RetentionCount < 0 {
+			v.add(field("", "retention-count", true), "must be greater than 0, got %d", job.RetentionCount)
+		}
+	}
+
+	if _, err := cron.ParseStandard(cfg.Backup.Cron); err != nil {
+		v.add(field("backup.cron", "cron", job != nil && job.Cron != ""),
+			"invalid cron expression %q: %v", cfg.Backup.Cron, err)
+	}
+	if cfg.Backup.RetentionCount <= 0 {
+		v.add("backup.retention-count", "must be greater than 0, got %d", cfg.Backup.RetentionCount)
+	}
+
+	switch {
+	case cfg.S3.Bucket == "":
+		v.add("s3.bucket", "is required")
+	case strings.ContainsAny(cfg.S3.Bucket, "/ "):
+		v.add(field("s3.bucket", "s3.bucket", job != nil && job.S3.Bucket != ""), "invalid bucket name %q", cfg.S3.Bucket)
+	}
+	if cfg.S3.Endpoint != "" {
+		v.checkURL(field("s3.endpoint", "s3.endpoint", job != nil && job.S3.Endpoint != ""), cfg.S3.Endpoint)
+	}
+
+	// Pings of a shared check would let one job's successes hide another job's missed runs
+	if c.Heartbeat.Enabled {
+		switch {
+		case job != nil && len(c.Jobs) > 1 && job.HeartbeatURL == "":
+			v.add(field("", "heartbeat-url", true), "is required when heartbeat is enabled with several jobs")
+		case cfg.Heartbeat.URL == "":
+			v.add("heartbeat.url", "is required when heartbeat is enabled")
+		default:
+			v.checkURL(field("heartbeat.url", "heartbeat-url", job != nil && job.HeartbeatURL != ""), cfg.Heartbeat.URL)
+		}
+	}
+}
+
+// validateNotifiers checks that every enabled notifier can deliver messages.
+func (c *Config) validateNotifiers(v *validator) {
+	if !c.Notifiers.Enabled {
+		return
+	}
+
+	legacy := []struct {
+		enabled bool
+		field   string
+		value   string
+	}{
+		{c.Notifiers.Discord.Enabled, "notifiers.discord.webhook", c.Notifiers.Discord.Webhook},
+		{c.Notifiers.Ntfy.Enabled, "notifiers.ntfy.url", c.Notifiers.Ntfy.URL},
+		{c.Notifiers.Gotify.Enabled, "notifiers.gotify.url", c.Notifiers.Gotify.URL},
+		{c.Notifiers.Teams.Enabled, "notifiers.teams.webhook", c.Notifiers.Teams.Webhook},
+	}
+	for _, n := range legacy {
+		switch {
+		case !n.enabled:
+		case n.value == "":
+			v.add(n.field, "is required when the notifier is enabled")
+		default:
+			v.checkURL(n.field, n.value)
+		}
+	}
+	if c.Notifiers.Gotify.Enabled && c.Notifiers.Gotify.Token == "" {
+		v.add("notifiers.gotify.token", "is required when the notifier is enabled")
+	}
+
+	seen := map[string]bool{}
+	for i, instance := range c.Notifiers.Instances {
+		field := fmt.Sprintf("notifiers.instances[%d]", i)
+		if seen[instance.Name] {
+			v.add(field+".name", "duplicate notifier name %q", instance.Name)
+		}
+		seen[instance.Name] = true
+		if err := instance.validate(); err != nil {
+			v.add(field, "%s: %v", instance.Name, err)
+		}
+	}
+}
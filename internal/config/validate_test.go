@@ -0,0 +1,156 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hibare/stashly/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() *Config {
+	return &Config{
+		S3: S3Config{Endpoint: "https://s3.amazonaws.com", Bucket: "backups"},
+		Backup: BackupConfig{
+			Cron:           constants.DefaultCron,
+			RetentionCount: constants.DefaultRetentionCount,
+			DateTimeLayout: constants.DefaultDateTimeLayout,
+			Timezone:       "UTC",
+			Overlap:        OverlapSkip,
+			Retry: RetryConfig{
+				MaxAttempts: constants.DefaultRetryMaxAttempts,
+				Multiplier:  constants.DefaultRetryMultiplier,
+			},
+		},
+	}
+}
+
+func TestConfig_Validate_Valid(t *testing.T) {
+	cfg := validConfig()
+	cfg.Backup.Encrypt = true
+	cfg.Encryption.GPG = GPGConfig{KeyServer: "keyserver.ubuntu.com", KeyID: "ABC123"}
+	cfg.Notifiers.Enabled = true
+	cfg.Notifiers.Discord = DiscordNotifierConfig{Enabled: true, Webhook: "https://discord.com/api/webhooks/1"}
+	cfg.Jobs = []JobConfig{{Name: "orders", Cron: "@hourly"}, {Name: "crm", S3: S3Config{Bucket: "crm-backups"}}}
+
+	require.NoError(t, cfg.Validate())
+}
+
+func TestConfig_Validate_AggregatesErrors(t *testing.T) {
+	cfg := validConfig()
+	cfg.Backup.Cron = "every night"
+	cfg.Backup.RetentionCount = 0
+	cfg.Backup.Timezone = "Asia/Kolkatta"
+	cfg.Backup.Overlap = "parallel"
+	cfg.S3.Bucket = ""
+	cfg.S3.Endpoint = "minio:9000"
+	cfg.Backup.Encrypt = true
+	cfg.Encryption.GPG.KeyServer = "keyserver.ubuntu.com"
+	cfg.Heartbeat = HeartbeatConfig{Enabled: true, URL: "https://"}
+
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+
+	fields := []string{}
+	for _, fe := range vErr.Errors {
+		fields = append(fields, fe.Field)
+	}
+	assert.Equal(t, []string{
+		"backup.cron",
+		"backup.retention-count",
+		"s3.bucket",
+		"s3.endpoint",
+		"heartbeat.url",
+		"backup.timezone",
+		"backup.overlap",
+		"encryption.gpg.key-id",
+	}, fields)
+	assert.Contains(t, vErr.Error(), "invalid configuration (8 errors): backup.cron: invalid cron expression")
+}
+
+func TestConfig_Validate_Jobs(t *testing.T) {
+	cfg := validConfig()
+	cfg.Jobs = []JobConfig{
+		{Name: "orders", Cron: "61 * * * *", RetentionCount: -1},
+		{Name: "crm", S3: S3Config{Endpoint: "ftp://files.example.com"}},
+		{Name: "audit"},
+	}
+	cfg.Backup.Cron = "bogus"
+
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+
+	fields := []string{}
+	for _, fe := range vErr.Errors {
+		fields = append(fields, fe.Field)
+	}
+	// The inherited top-level cron is reported once, not once per job
+	assert.Equal(t, []string{
+		"jobs.orders.retention-count",
+		"jobs.orders.cron",
+		"backup.cron",
+		"jobs.crm.s3.endpoint",
+	}, fields)
+}
+
+func TestConfig_Validate_ReportsSettingsLoadConfigKeeps(t *testing.T) {
+	cfg := validConfig()
+	cfg.Backup.Timezone = "Asia/Kolkatta"
+	cfg.Heartbeat.Enabled = true
+	cfg.Jobs = []JobConfig{{Name: "finance db"}}
+	cfg.Maintenance.Blackouts = []BlackoutConfig{{Name: "month-end", Cron: "0 0 1 * *", Duration: time.Hour, Action: "postpone"}}
+
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+	assert.Contains(t, vErr.Errors, FieldError{Field: "backup.timezone", Message: `unknown time zone "Asia/Kolkatta"`})
+	assert.Contains(t, vErr.Errors, FieldError{Field: "heartbeat.url", Message: "is required when heartbeat is enabled"})
+	assert.Contains(t, vErr.Errors, FieldError{Field: "jobs[0].name", Message: `invalid job name "finance db": use letters, digits, - and _`})
+	assert.Contains(t, vErr.Errors, FieldError{Field: "maintenance.blackouts[0]", Message: `month-end: invalid action "postpone"`})
+}
+
+func TestConfig_Validate_JobHeartbeat(t *testing.T) {
+	cfg := validConfig()
+	cfg.Heartbeat = HeartbeatConfig{Enabled: true, URL: "https://hc-ping.com/shared"}
+	cfg.Jobs = []JobConfig{
+		{Name: "orders", HeartbeatURL: "https://hc-ping.com/orders"},
+		{Name: "crm"},
+		{Name: "audit", HeartbeatURL: "hc-ping.com/audit"},
+	}
+
+	// With several jobs the shared URL is not enough; every job needs its own
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+	assert.Equal(t, []FieldError{
+		{Field: "jobs.crm.heartbeat-url", Message: "is required when heartbeat is enabled with several jobs"},
+		{Field: "jobs.audit.heartbeat-url", Message: `URL "hc-ping.com/audit" must start with http:// or https://`},
+	}, vErr.Errors)
+
+	// A single job may use the top-level URL
+	cfg.Jobs = []JobConfig{{Name: "crm"}}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, "https://hc-ping.com/shared", cfg.BackupJobs()[0].Heartbeat.URL)
+}
+
+func TestConfig_Validate_OutOfRangeSettings(t *testing.T) {
+	cfg := validConfig()
+	cfg.Backup.DateTimeLayout = "2006-01-02"
+	cfg.Backup.Retry = RetryConfig{MaxAttempts: 0, Multiplier: 0.5}
+	cfg.Backup.Timeout = -time.Minute
+	cfg.Backup.DatabaseTimeout = -time.Second
+	cfg.Lock = LockConfig{Enabled: true}
+	cfg.HTTP.ReadyMaxAge = -time.Hour
+
+	// Out-of-range values are reported instead of silently disabling the feature
+	var vErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &vErr)
+	assert.Equal(t, []FieldError{
+		{Field: "backup.date-time-layout", Message: `is not used and must be unset or "20060102150405", got "2006-01-02"`},
+		{Field: "backup.retry.max-attempts", Message: "must be at least 1, got 0"},
+		{Field: "backup.retry.multiplier", Message: "must be at least 1, got 0.5"},
+		{Field: "backup.timeout", Message: "must not be negative, got -1m0s"},
+		{Field: "backup.database-timeout", Message: "must not be negative, got -1s"},
+		{Field: "http.ready-max-age", Message: "must not be negative, got -1h0m0s"},
+		{Field: "lock.ttl", Message: "must be positive when lock is enabled, got 0s"},
+	}, vErr.Errors)
+}
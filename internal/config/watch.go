@@ -0,0 +1,29 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch calls onChange whenever the config file found for configPath is written, created or
+// replaced, e.g. by a Kubernetes ConfigMap update. Editors often write a file in several steps,
+// so callers should debounce. Watching stops when ctx is done; it fails if there is no config file.
+func Watch(ctx context.Context, configPath string, onChange func()) error {
+	v := newViper(configPath)
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+
+	v.OnConfigChange(func(e fsnotify.Event) {
+		if ctx.Err() != nil {
+			return
+		}
+		slog.DebugContext(ctx, "Config file changed", "file", e.Name, "op", e.Op.String())
+		onChange()
+	})
+	v.WatchConfig()
+	slog.InfoContext(ctx, "Watching config file for changes", "file", v.ConfigFileUsed())
+	return nil
+}
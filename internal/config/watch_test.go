@@ -0,0 +1,29 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	path := writeFile(t, "config.yaml", "backup:\n  cron: \"0 0 * * *\"\n")
+	changed := make(chan struct{}, 10)
+
+	require.NoError(t, Watch(t.Context(), path, func() { changed <- struct{}{} }))
+	require.NoError(t, os.WriteFile(path, []byte("backup:\n  cron: \"0 1 * * *\"\n"), 0600))
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("onChange was not called after the config file changed")
+	}
+}
+
+func TestWatch_NoConfigFile(t *testing.T) {
+	err := Watch(t.Context(), writeFile(t, "config.yaml", "")+".missing", func() {})
+	assert.Error(t, err)
+}
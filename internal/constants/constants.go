@@ -1,6 +1,8 @@
 // Package constants defines application-wide constant values.
 package constants
 
+import "time"
+
 const (
 	// ProgramIdentifier is the name used in notifications and logs.
 	ProgramIdentifier = "Stashly"
@@ -17,9 +19,66 @@ const (
 	//  DefaultCron is the default cron schedule for backups (daily at midnight).
 	DefaultCron = "0 0 * * *"
 
+	// DefaultTimezone is the default time zone cron schedules are evaluated in.
+	DefaultTimezone = "UTC"
+
+	// DefaultRetryMaxAttempts is the default number of attempts for a backup run (no retries).
+	DefaultRetryMaxAttempts = 1
+
+	// DefaultRetryInitialDelay is the default wait before the first retry.
+	DefaultRetryInitialDelay = 30 * time.Second
+
+	// DefaultRetryMultiplier is the default factor by which the retry delay grows.
+	DefaultRetryMultiplier = 2.0
+
+	// DefaultLockTTL is the default time after which a distributed lock that is no longer refreshed expires.
+	DefaultLockTTL = 15 * time.Minute
+
+	// DefaultControlSocket is the default path of the daemon's control socket.
+	DefaultControlSocket = "/tmp/stashly.sock"
+
+	// DefaultHTTPListen is the default address of the daemon's HTTP listener.
+	DefaultHTTPListen = ":9090"
+
+	// DefaultAPIListen is the default address of the REST API served by "stashly serve".
+	DefaultAPIListen = ":8080"
+
+	// DefaultHistoryPath is the default path of the local run history.
+	DefaultHistoryPath = "/var/lib/stashly/history.jsonl"
+
 	// DefaultPostgresHost is the default host for the postgres database.
 	DefaultPostgresHost = "127.0.0.1"
 
 	// DefaultPostgresPort is the default port for the postgres database.
 	DefaultPostgresPort = "5432"
+
+	// DefaultNtfyPrioritySuccess is the default ntfy priority for successful backups (default).
+	DefaultNtfyPrioritySuccess = 3
+
+	// DefaultNtfyPriorityFailure is the default ntfy priority for failed backups (urgent).
+	DefaultNtfyPriorityFailure = 5
+
+	// DefaultNtfyPriorityDeleteFailure is the default ntfy priority for failed backup deletions (high).
+	DefaultNtfyPriorityDeleteFailure = 4
+
+	// DefaultGotifyPrioritySuccess is the default Gotify priority for successful backups.
+	DefaultGotifyPrioritySuccess = 2
+
+	// DefaultGotifyPriorityFailure is the default Gotify priority for failed backups.
+	DefaultGotifyPriorityFailure = 8
+
+	// DefaultGotifyPriorityDeleteFailure is the default Gotify priority for failed backup deletions.
+	DefaultGotifyPriorityDeleteFailure = 5
+
+	// DefaultNotifierTimeout is the default timeout for notifier HTTP requests.
+	DefaultNotifierTimeout = 10 * time.Second
+
+	// DefaultHeartbeatProvider is the default heartbeat provider.
+	DefaultHeartbeatProvider = "healthchecks"
+
+	// DefaultHeartbeatTimeout is the default timeout for heartbeat pings.
+	DefaultHeartbeatTimeout = 10 * time.Second
+
+	// DefaultHeartbeatTailLines is the default number of run log lines sent with heartbeat pings.
+	DefaultHeartbeatTailLines = 100
 )
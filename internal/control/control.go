@@ -0,0 +1,175 @@
+// Package control serves a local Unix socket through which a running daemon can be asked to
+// start a backup or report the state of its jobs.
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hibare/stashly/internal/scheduler"
+)
+
+const (
+	triggerPath = "/trigger"
+	statusPath  = "/status"
+
+	// readHeaderTimeout bounds how long a client may take to send request headers.
+	readHeaderTimeout = 5 * time.Second
+)
+
+// ErrDaemonRunning is returned when another daemon is already listening on the socket.
+var ErrDaemonRunning = errors.New("another daemon is listening on the control socket")
+
+// Controller is the part of the scheduler exposed over the control socket.
+type Controller interface {
+	Trigger(job string) error
+	Status() []scheduler.JobStatus
+}
+
+// Server serves control requests on a Unix socket.
+type Server struct {
+	path string
+	srv  *http.Server
+}
+
+// NewServer creates a server for the socket at path, forwarding requests to ctrl.
+func NewServer(path string, ctrl Controller) *Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+triggerPath, func(w http.ResponseWriter, r *http.Request) {
+		job := r.URL.Query().Get("job")
+		if err := ctrl.Trigger(job); err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, scheduler.ErrUnknownJob):
+				status = http.StatusNotFound
+			case errors.Is(err, scheduler.ErrStopping):
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("GET "+statusPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ctrl.Status()); err != nil {
+			slog.Warn("Failed to write control status", "error", err)
+		}
+	})
+
+	return &Server{
+		path: path,
+		srv:  &http.Server{Handler: mux, ReadHeaderTimeout: readHeaderTimeout},
+	}
+}
+
+// Start listens on the socket and serves requests in the background. A socket left behind by a
+// daemon that is no longer running is replaced. The socket is only accessible to the current user.
+func (s *Server) Start(ctx context.Context) error {
+	if _, err := os.Stat(s.path); err == nil {
+		if conn, dErr := (&net.Dialer{}).DialContext(ctx, "unix", s.path); dErr == nil {
+			_ = conn.Close()
+			return fmt.Errorf("%w: %s", ErrDaemonRunning, s.path)
+		}
+		if rErr := os.Remove(s.path); rErr != nil {
+			return fmt.Errorf("error removing stale control socket: %w", rErr)
+		}
+	}
+
+	ln, err := (&net.ListenConfig{}).Listen(ctx, "unix", s.path)
+	if err != nil {
+		return fmt.Errorf("error listening on control socket: %w", err)
+	}
+	if err = os.Chmod(s.path, 0o600); err != nil {
+		_ = ln.Close()
+		return fmt.Errorf("error restricting control socket permissions: %w", err)
+	}
+
+	go func() {
+		if sErr := s.srv.Serve(ln); sErr != nil && !errors.Is(sErr, http.ErrServerClosed) {
+			slog.ErrorContext(ctx, "Control socket server stopped", "error", sErr)
+		}
+	}()
+	slog.InfoContext(ctx, "Listening on control socket", "socket", s.path)
+	return nil
+}
+
+// Close stops serving and removes the socket.
+func (s *Server) Close(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	if rErr := os.Remove(s.path); rErr != nil && !errors.Is(rErr, os.ErrNotExist) && err == nil {
+		err = rErr
+	}
+	return err
+}
+
+// Client talks to a daemon over its control socket.
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the socket at path.
+func NewClient(path string) *Client {
+	dialer := &net.Dialer{}
+	return &Client{http: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}}}
+}
+
+// Trigger asks the daemon to start an immediate run of the named job, or of every job if job is empty.
+func (c *Client) Trigger(ctx context.Context, job string) error {
+	target := "http://stashly" + triggerPath
+	if job != "" {
+		target += "?job=" + url.QueryEscape(job)
+	}
+	resp, err := c.do(ctx, http.MethodPost, target)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// Status returns the state of the daemon's jobs.
+func (c *Client) Status(ctx context.Context) ([]scheduler.JobStatus, error) {
+	resp, err := c.do(ctx, http.MethodGet, "http://stashly"+statusPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var statuses []scheduler.JobStatus
+	if err = json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		return nil, fmt.Errorf("error decoding status: %w", err)
+	}
+	return statuses, nil
+}
+
+func (c *Client) do(ctx context.Context, method, target string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error contacting daemon (is it running?): %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("daemon returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
@@ -0,0 +1,107 @@
+package control
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibare/stashly/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeController struct {
+	mu        sync.Mutex
+	triggered []string
+}
+
+func (f *fakeController) Trigger(job string) error {
+	if job == "missing" {
+		return scheduler.ErrUnknownJob
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.triggered = append(f.triggered, job)
+	return nil
+}
+
+func (f *fakeController) Status() []scheduler.JobStatus {
+	return []scheduler.JobStatus{{
+		Name:    "orders",
+		Cron:    "0 0 * * *",
+		Running: true,
+		NextRun: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}}
+}
+
+func startServer(t *testing.T, ctrl Controller) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stashly.sock")
+	srv := NewServer(path, ctrl)
+	require.NoError(t, srv.Start(t.Context()))
+	t.Cleanup(func() { _ = srv.Close(t.Context()) })
+	return path
+}
+
+func TestControl_Trigger(t *testing.T) {
+	ctrl := &fakeController{}
+	client := NewClient(startServer(t, ctrl))
+
+	require.NoError(t, client.Trigger(t.Context(), ""))
+	require.NoError(t, client.Trigger(t.Context(), "orders"))
+	assert.Equal(t, []string{"", "orders"}, ctrl.triggered)
+
+	err := client.Trigger(t.Context(), "missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestControl_Status(t *testing.T) {
+	client := NewClient(startServer(t, &fakeController{}))
+
+	statuses, err := client.Status(t.Context())
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "orders", statuses[0].Name)
+	assert.True(t, statuses[0].Running)
+	assert.True(t, statuses[0].LastStarted.IsZero())
+}
+
+func TestServer_Start_SocketPermissions(t *testing.T) {
+	path := startServer(t, &fakeController{})
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestServer_Start_ReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stashly.sock")
+
+	// A socket file nobody listens on, as left behind by a crashed daemon
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, ln.Close())
+
+	srv := NewServer(path, &fakeController{})
+	require.NoError(t, srv.Start(t.Context()))
+	require.NoError(t, srv.Close(t.Context()))
+	assert.NoFileExists(t, path)
+}
+
+func TestServer_Start_DaemonRunning(t *testing.T) {
+	path := startServer(t, &fakeController{})
+
+	err := NewServer(path, &fakeController{}).Start(t.Context())
+	require.ErrorIs(t, err, ErrDaemonRunning)
+}
+
+func TestClient_NoDaemon(t *testing.T) {
+	_, err := NewClient(filepath.Join(t.TempDir(), "missing.sock")).Status(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is it running?")
+}
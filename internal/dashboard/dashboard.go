@@ -0,0 +1,36 @@
+// Package dashboard embeds the web dashboard served by "stashly serve". It is a static page reading
+// the REST API, with no external assets so it works offline.
+package dashboard
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/hibare/stashly/internal/assets"
+)
+
+//go:embed static
+var static embed.FS
+
+// contentSecurityPolicy only allows the dashboard's own scripts, styles and API.
+const contentSecurityPolicy = "default-src 'self'; img-src 'self'; frame-ancestors 'none'"
+
+// Handler serves the dashboard's files.
+func Handler() http.Handler {
+	files, _ := fs.Sub(static, "static")
+	fileServer := http.FileServerFS(files)
+	startedAt := time.Now()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if r.URL.Path == "/favicon.png" {
+			http.ServeContent(w, r, "favicon.png", startedAt, bytes.NewReader(assets.Favicon))
+			return
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,207 @@
+"use strict";
+
+// The dashboard reads the REST API of "stashly serve". It signs in once with the API token, which the
+// server keeps in an HttpOnly cookie so download links work without the token in the URL.
+const API = "/api/v1";
+const REFRESH_MS = 30000;
+
+const $ = (id) => document.getElementById(id);
+
+async function api(method, path, body) {
+  const opts = { method, credentials: "same-origin", headers: {} };
+  if (body !== undefined) {
+    opts.headers["Content-Type"] = "application/json";
+    opts.body = JSON.stringify(body);
+  }
+  const resp = await fetch(API + path, opts);
+  if (resp.status === 401 && path !== "/session") {
+    showLogin();
+    throw new Error("not signed in");
+  }
+  if (!resp.ok) {
+    let msg = resp.statusText;
+    try {
+      msg = (await resp.json()).error || msg;
+    } catch (e) {
+      // Not a JSON error body
+    }
+    throw new Error(msg);
+  }
+  return resp.status === 204 || resp.status === 202 ? null : resp.json();
+}
+
+function el(tag, props, ...children) {
+  const node = document.createElement(tag);
+  Object.assign(node, props || {});
+  for (const child of children) {
+    if (child !== null && child !== undefined) {
+      node.append(child instanceof Node ? child : String(child));
+    }
+  }
+  return node;
+}
+
+function status(value) {
+  return el("span", { className: "status status-" + value.split(" ")[0] }, value);
+}
+
+function formatTime(value) {
+  if (!value) {
+    return "-";
+  }
+  return new Date(value).toLocaleString();
+}
+
+function formatBytes(n) {
+  if (!n) {
+    return "-";
+  }
+  const units = ["B", "KiB", "MiB", "GiB", "TiB"];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) {
+    n /= 1024;
+    i++;
+  }
+  return n.toFixed(i === 0 ? 0 : 1) + " " + units[i];
+}
+
+function formatDuration(start, end) {
+  const s = Math.round((new Date(end) - new Date(start)) / 1000);
+  if (!(s >= 0)) {
+    return "-";
+  }
+  if (s < 60) {
+    return s + "s";
+  }
+  const m = Math.floor(s / 60);
+  return m < 60 ? m + "m " + (s % 60) + "s" : Math.floor(m / 60) + "h " + (m % 60) + "m";
+}
+
+function jobState(job) {
+  if (job.running && job.queued) {
+    return "running (queued)";
+  }
+  if (job.running) {
+    return "running";
+  }
+  return job.deferred ? "deferred" : "idle";
+}
+
+function renderJobs(jobs) {
+  const select = $("backup-job");
+  const selected = select.value;
+  select.replaceChildren(...jobs.map((job) => el("option", { value: job.name }, job.name)));
+  if (jobs.some((job) => job.name === selected)) {
+    select.value = selected;
+  }
+
+  $("jobs").replaceChildren(...jobs.map((job) => {
+    const button = el("button", { type: "button", disabled: job.running && job.queued }, "Run now");
+    button.addEventListener("click", async () => {
+      button.disabled = true;
+      try {
+        await api("POST", "/jobs/" + encodeURIComponent(job.name) + "/trigger");
+        await refresh();
+      } catch (e) {
+        showError(e);
+        button.disabled = false;
+      }
+    });
+    return el("tr", null,
+      el("td", null, job.name),
+      el("td", null, el("code", null, job.cron)),
+      el("td", null, status(jobState(job))),
+      el("td", null, formatTime(job.next_run)),
+      el("td", null, formatTime(job.last_finished)),
+      el("td", { className: "error" }, job.last_error || ""),
+      el("td", null, button));
+  }));
+}
+
+function renderRuns(runs) {
+  if (runs.length === 0) {
+    $("runs").replaceChildren(el("tr", null, el("td", { colSpan: 7, className: "muted" }, "No runs recorded yet.")));
+    return;
+  }
+  $("runs").replaceChildren(...runs.map((run) => el("tr", null,
+    el("td", null, formatTime(run.started_at)),
+    el("td", null, run.job),
+    el("td", null, run.trigger || "-"),
+    el("td", { title: run.error || "" }, status(run.status)),
+    el("td", null, formatDuration(run.started_at, run.finished_at)),
+    el("td", null, formatBytes(run.bytes)),
+    el("td", null, el("ul", { className: "databases" }, ...(run.databases || []).map((db) =>
+      el("li", { title: db.error || "" }, db.name + " ", status(db.status), db.bytes ? " " + formatBytes(db.bytes) : "")))))));
+}
+
+async function renderBackups() {
+  const job = $("backup-job").value;
+  if (!job) {
+    return;
+  }
+  const path = "/jobs/" + encodeURIComponent(job) + "/backups";
+  const backups = await api("GET", path);
+  if (backups.length === 0) {
+    $("backups").replaceChildren(el("tr", null, el("td", { colSpan: 5, className: "muted" }, "No backups in storage.")));
+    return;
+  }
+  $("backups").replaceChildren(...backups.map((b) => el("tr", null,
+    el("td", null, el("code", null, b.id)),
+    el("td", null, formatTime(b.time)),
+    el("td", null, formatBytes(b.size)),
+    el("td", null, b.pin ? "\u{1F4CC} " + (b.pin.label || "pinned") : ""),
+    el("td", null, el("a", { href: API + path + "/" + encodeURIComponent(b.id) + "/download" }, "Download")))));
+}
+
+function showError(e) {
+  $("error").textContent = e ? "Error: " + e.message : "";
+}
+
+function showLogin() {
+  $("content").hidden = true;
+  $("logout").hidden = true;
+  $("login").hidden = false;
+}
+
+let timer = null;
+
+async function refresh() {
+  try {
+    const [jobs, runs] = await Promise.all([api("GET", "/jobs"), api("GET", "/runs?limit=50")]);
+    $("login").hidden = true;
+    $("content").hidden = false;
+    $("logout").hidden = false;
+    renderJobs(jobs);
+    renderRuns(runs);
+    await renderBackups();
+    $("updated").textContent = "Updated " + new Date().toLocaleTimeString();
+    showError(null);
+  } catch (e) {
+    if (e.message !== "not signed in") {
+      showError(e);
+    }
+  }
+  clearTimeout(timer);
+  timer = setTimeout(refresh, REFRESH_MS);
+}
+
+$("login").addEventListener("submit", async (ev) => {
+  ev.preventDefault();
+  $("login-error").textContent = "";
+  try {
+    await api("POST", "/session", { token: $("token").value });
+    $("token").value = "";
+    await refresh();
+  } catch (e) {
+    $("login-error").textContent = "Sign in failed: " + e.message;
+  }
+});
+
+$("logout").addEventListener("click", async () => {
+  await api("DELETE", "/session").catch(() => {});
+  showLogin();
+});
+
+$("backup-job").addEventListener("change", () => renderBackups().catch(showError));
+
+refresh();
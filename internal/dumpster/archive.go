@@ -0,0 +1,107 @@
+package dumpster
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// archiveDir writes a zip archive of the regular files below srcDir to dst, with entries relative to srcDir.
+// GoCommon's file.ArchiveDir and gpg.EncryptFile are not used because they write to the shared os.TempDir,
+// where concurrent runs collide and the workspace cleanup does not reach.
+func archiveDir(srcDir, dst string) (err error) {
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec // dst is inside the run workspace
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := out.Close(); err == nil {
+			err = cErr
+		}
+	}()
+
+	zw := zip.NewWriter(out)
+	defer func() {
+		if cErr := zw.Close(); err == nil {
+			err = cErr
+		}
+	}()
+
+	return filepath.WalkDir(srcDir, func(path string, entry os.DirEntry, wErr error) error {
+		if wErr != nil {
+			return wErr
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, rErr := filepath.Rel(srcDir, path)
+		if rErr != nil {
+			return rErr
+		}
+		w, cErr := zw.CreateHeader(&zip.FileHeader{Name: filepath.ToSlash(rel), Method: zip.Deflate})
+		if cErr != nil {
+			return cErr
+		}
+
+		in, oErr := os.Open(path) //nolint:gosec // path is below the run workspace
+		if oErr != nil {
+			return oErr
+		}
+		defer func() { _ = in.Close() }()
+		_, cErr = io.Copy(w, in)
+		return cErr
+	})
+}
+
+// encryptFile encrypts src for the armored public key and writes the armored message to dst.
+func encryptFile(publicKey, src, dst string) (err error) {
+	recipients, err := openpgp.ReadArmoredKeyRing(strings.NewReader(publicKey))
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(src) //nolint:gosec // src is inside the run workspace
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec // dst is inside the run workspace
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := out.Close(); err == nil {
+			err = cErr
+		}
+	}()
+
+	armored, err := armor.Encode(out, "PGP MESSAGE", nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := armored.Close(); err == nil {
+			err = cErr
+		}
+	}()
+
+	plaintext, err := openpgp.Encrypt(armored, recipients, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := plaintext.Close(); err == nil {
+			err = cErr
+		}
+	}()
+
+	_, err = io.Copy(plaintext, in)
+	return err
+}
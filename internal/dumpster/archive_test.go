@@ -0,0 +1,127 @@
+package dumpster
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/hibare/GoCommon/v2/pkg/crypto/gpg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveDir(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "db_exports")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "nested"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "db1.sql"), []byte("select 1;"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "nested", "db2.sql"), []byte("select 2;"), 0o600))
+
+	dst := src + ".zip"
+	require.NoError(t, archiveDir(src, dst))
+
+	assert.Equal(t, map[string]string{"db1.sql": "select 1;", "nested/db2.sql": "select 2;"}, readZip(t, dst))
+}
+
+func TestEncryptFile(t *testing.T) {
+	entity, pub, _ := newTestKey(t)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "db_exports.zip")
+	require.NoError(t, os.WriteFile(src, []byte("archive"), 0o600))
+
+	dst := src + ".gpg"
+	require.NoError(t, encryptFile(pub, src, dst))
+
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "-----BEGIN PGP MESSAGE-----"))
+
+	block, err := armor.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	md, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{entity}, nil, nil)
+	require.NoError(t, err)
+	plaintext, err := io.ReadAll(md.UnverifiedBody)
+	require.NoError(t, err)
+	assert.Equal(t, "archive", string(plaintext))
+}
+
+func TestEncryptFile_InvalidKey(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "db_exports.zip")
+	require.NoError(t, os.WriteFile(src, []byte("archive"), 0o600))
+
+	require.Error(t, encryptFile("not a key", src, src+".gpg"))
+	assert.NoFileExists(t, src+".gpg")
+}
+
+// TestArchiveAndEncrypt_RoundTrip checks that the output can be restored with the GoCommon gpg helper the
+// previous implementation used: decrypting and unzipping yields the dump files relative to the export directory.
+func TestArchiveAndEncrypt_RoundTrip(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir()) // gpg.DecryptFile writes to os.TempDir
+
+	_, pub, priv := newTestKey(t)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "db_exports")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "nested"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "db1.sql"), []byte("select 1;"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "nested", "db2.sql"), []byte("select 2;"), 0o600))
+
+	archivePath := src + ".zip"
+	require.NoError(t, archiveDir(src, archivePath))
+	encryptedPath := archivePath + "." + gpg.GPGPrefix
+	require.NoError(t, encryptFile(pub, archivePath, encryptedPath))
+
+	decryptedPath, err := (&gpg.GPG{PrivateKey: priv}).DecryptFile(encryptedPath)
+	require.NoError(t, err)
+	assert.Equal(t, "db_exports.zip", filepath.Base(decryptedPath))
+	assert.Equal(t, map[string]string{"db1.sql": "select 1;", "nested/db2.sql": "select 2;"}, readZip(t, decryptedPath))
+}
+
+// newTestKey generates an unprotected key pair and returns it with its armored public and private keys.
+func newTestKey(t *testing.T) (*openpgp.Entity, string, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("stashly", "", "stashly@example.com", nil)
+	require.NoError(t, err)
+
+	var pub, priv bytes.Buffer
+	w, err := armor.Encode(&pub, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+
+	w, err = armor.Encode(&priv, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+
+	return entity, pub.String(), priv.String()
+}
+
+// readZip returns the contents of the zip archive at path by entry name.
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	zr, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer func() { _ = zr.Close() }()
+
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, oErr := f.Open()
+		require.NoError(t, oErr)
+		data, rErr := io.ReadAll(rc)
+		require.NoError(t, rErr)
+		_ = rc.Close()
+		contents[f.Name] = string(data)
+	}
+	return contents
+}
@@ -0,0 +1,215 @@
+package dumpster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hibare/stashly/internal/constants"
+	"github.com/hibare/stashly/internal/storage"
+)
+
+// Errors returned by the backup operations.
+var (
+	ErrBackupNotFound = errors.New("backup not found")
+	ErrBackupPinned   = errors.New("backup is pinned")
+)
+
+// pinAttempts bounds how often a pin update is retried after losing to a concurrent writer.
+const pinAttempts = 5
+
+// Backup describes a backup in storage.
+type Backup struct {
+	// ID is the backup's key, a timestamp in constants.DefaultDateTimeLayout.
+	ID      string               `json:"id"`
+	Time    time.Time            `json:"time"`
+	Size    int64                `json:"size"`
+	Pin     *Pin                 `json:"pin,omitempty"`
+	Objects []storage.ObjectInfo `json:"objects,omitempty"`
+}
+
+// Pinned reports whether the backup is exempt from the retention policy and cannot be deleted.
+func (b *Backup) Pinned() bool {
+	return b.Pin != nil
+}
+
+// Pin marks a backup as kept regardless of the retention policy.
+type Pin struct {
+	Label    string    `json:"label,omitempty"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// pinsKey returns the key, relative to the storage root, of the instance's pins.
+func (d *Dumpster) pinsKey() string {
+	return path.Join(storage.MetadataDir, "pins", d.cfg.App.InstanceID+".json")
+}
+
+// pins returns the instance's pinned backups by ID, along with the ETag of the stored pins.
+func (d *Dumpster) pins(ctx context.Context) (map[string]Pin, string, error) {
+	data, etag, err := d.store.GetObject(ctx, d.pinsKey())
+	if errors.Is(err, storage.ErrNotExist) {
+		return map[string]Pin{}, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading pins: %w", err)
+	}
+
+	pins := map[string]Pin{}
+	if err = json.Unmarshal(data, &pins); err != nil {
+		return nil, "", fmt.Errorf("error decoding pins: %w", err)
+	}
+	return pins, etag, nil
+}
+
+// updatePins applies update to the stored pins. Concurrent updates are detected through the
+// ETag and retried.
+func (d *Dumpster) updatePins(ctx context.Context, update func(pins map[string]Pin)) error {
+	for range pinAttempts {
+		pins, etag, err := d.pins(ctx)
+		if err != nil {
+			return err
+		}
+		update(pins)
+
+		data, err := json.Marshal(pins)
+		if err != nil {
+			return err
+		}
+		opts := storage.PutOptions{IfMatch: etag, IfNoneMatch: etag == ""}
+		_, err = d.store.PutObject(ctx, d.pinsKey(), data, opts)
+		if !errors.Is(err, storage.ErrPreconditionFailed) {
+			return err
+		}
+	}
+	return fmt.Errorf("error updating pins: %w", storage.ErrPreconditionFailed)
+}
+
+// findBackup returns ErrBackupNotFound unless id is one of the dumps in storage.
+func (d *Dumpster) findBackup(ctx context.Context, id string) error {
+	keys, err := d.ListDumps(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(keys, id) {
+		return fmt.Errorf("%w: %s", ErrBackupNotFound, id)
+	}
+	return nil
+}
+
+// ListBackups returns the backups in storage with their sizes and pins, newest first.
+func (d *Dumpster) ListBackups(ctx context.Context) ([]Backup, error) {
+	keys, err := d.ListDumps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return []Backup{}, nil
+	}
+
+	pins, _, err := d.pins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := d.store.Objects(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sizes := map[string]int64{}
+	for _, obj := range objects {
+		id, _, _ := strings.Cut(obj.Key, "/")
+		sizes[id] += obj.Size
+	}
+
+	backups := make([]Backup, 0, len(keys))
+	for _, id := range keys {
+		b := Backup{ID: id, Size: sizes[id]}
+		b.Time, _ = time.ParseInLocation(constants.DefaultDateTimeLayout, id, time.Local)
+		if pin, ok := pins[id]; ok {
+			b.Pin = &pin
+		}
+		backups = append(backups, b)
+	}
+	return backups, nil
+}
+
+// GetBackup returns the backup with its objects.
+func (d *Dumpster) GetBackup(ctx context.Context, id string) (*Backup, error) {
+	// Only valid timestamps are looked up, so id cannot address other objects
+	t, err := time.ParseInLocation(constants.DefaultDateTimeLayout, id, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, id)
+	}
+	objects, err := d.store.Objects(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, id)
+	}
+
+	pins, _, err := d.pins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b := &Backup{ID: id, Time: t, Objects: objects}
+	for _, obj := range objects {
+		b.Size += obj.Size
+	}
+	if pin, ok := pins[id]; ok {
+		b.Pin = &pin
+	}
+	return b, nil
+}
+
+// OpenBackup opens the backup's archive for reading. The caller must close it.
+func (d *Dumpster) OpenBackup(ctx context.Context, id string) (io.ReadCloser, storage.ObjectInfo, error) {
+	b, err := d.GetBackup(ctx, id)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+	// A backup holds a single archive
+	obj := b.Objects[0]
+	r, err := d.store.Download(ctx, obj.Key)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+	return r, obj, nil
+}
+
+// DeleteBackup deletes the backup from storage. Pinned backups must be unpinned first.
+func (d *Dumpster) DeleteBackup(ctx context.Context, id string) error {
+	if err := d.findBackup(ctx, id); err != nil {
+		return err
+	}
+	pins, _, err := d.pins(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := pins[id]; ok {
+		return fmt.Errorf("%w: %s", ErrBackupPinned, id)
+	}
+	return d.store.Delete(id)
+}
+
+// PinBackup keeps the backup regardless of the retention policy, replacing any existing label.
+func (d *Dumpster) PinBackup(ctx context.Context, id, label string) error {
+	if err := d.findBackup(ctx, id); err != nil {
+		return err
+	}
+	return d.updatePins(ctx, func(pins map[string]Pin) {
+		pins[id] = Pin{Label: label, PinnedAt: time.Now()}
+	})
+}
+
+// UnpinBackup subjects the backup to the retention policy again.
+func (d *Dumpster) UnpinBackup(ctx context.Context, id string) error {
+	return d.updatePins(ctx, func(pins map[string]Pin) {
+		delete(pins, id)
+	})
+}
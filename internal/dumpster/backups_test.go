@@ -0,0 +1,147 @@
+package dumpster
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/exec"
+	"github.com/hibare/stashly/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testPinsKey = ".stashly/pins/host-a.json"
+
+func newBackupsDumpster(t *testing.T, keys []string) (*Dumpster, *storage.MockStorageIface) {
+	t.Helper()
+	cfg := &config.Config{App: config.AppConfig{InstanceID: "host-a"}, Backup: config.BackupConfig{RetentionCount: 1}}
+	mockStore := storage.NewMockStorageIface(t)
+	if keys != nil {
+		mockStore.On("List").Return(keys, nil)
+		mockStore.On("TrimPrefix", keys).Return(keys)
+	}
+	return NewDumpster(cfg, mockStore, exec.NewMockExecIface(t)), mockStore
+}
+
+func pinsData(t *testing.T, pins map[string]Pin) []byte {
+	t.Helper()
+	data, err := json.Marshal(pins)
+	require.NoError(t, err)
+	return data
+}
+
+func TestDumpster_ListBackups(t *testing.T) {
+	d, mockStore := newBackupsDumpster(t, []string{"20240101000000", "20240102000000"})
+	pinnedAt := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	mockStore.On("GetObject", mock.Anything, testPinsKey).
+		Return(pinsData(t, map[string]Pin{"20240101000000": {Label: "before migration", PinnedAt: pinnedAt}}), "etag", nil)
+	mockStore.On("Objects", mock.Anything, "").Return([]storage.ObjectInfo{
+		{Key: "20240101000000/export.zip.gpg", Size: 100},
+		{Key: "20240102000000/export.zip.gpg", Size: 200},
+	}, nil)
+
+	backups, err := d.ListBackups(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Backup{
+		{ID: "20240102000000", Time: time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local), Size: 200},
+		{
+			ID: "20240101000000", Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), Size: 100,
+			Pin: &Pin{Label: "before migration", PinnedAt: pinnedAt},
+		},
+	}, backups)
+}
+
+func TestDumpster_GetBackup(t *testing.T) {
+	d, mockStore := newBackupsDumpster(t, nil)
+	objects := []storage.ObjectInfo{{Key: "20240101000000/export.zip", Size: 42}}
+	mockStore.On("Objects", mock.Anything, "20240101000000").Return(objects, nil)
+	mockStore.On("Objects", mock.Anything, "20240109000000").Return([]storage.ObjectInfo{}, nil)
+	mockStore.On("GetObject", mock.Anything, testPinsKey).Return(nil, "", storage.ErrNotExist)
+
+	b, err := d.GetBackup(context.Background(), "20240101000000")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), b.Size)
+	assert.False(t, b.Pinned())
+	assert.Equal(t, objects, b.Objects)
+
+	_, err = d.GetBackup(context.Background(), "20240109000000")
+	require.ErrorIs(t, err, ErrBackupNotFound)
+
+	_, err = d.GetBackup(context.Background(), "../.stashly")
+	require.ErrorIs(t, err, ErrBackupNotFound)
+}
+
+func TestDumpster_OpenBackup(t *testing.T) {
+	d, mockStore := newBackupsDumpster(t, nil)
+	obj := storage.ObjectInfo{Key: "20240101000000/export.zip", Size: 4}
+	mockStore.On("Objects", mock.Anything, "20240101000000").Return([]storage.ObjectInfo{obj}, nil)
+	mockStore.On("GetObject", mock.Anything, testPinsKey).Return(nil, "", storage.ErrNotExist)
+	mockStore.On("Download", mock.Anything, obj.Key).Return(io.NopCloser(strings.NewReader("data")), nil)
+
+	r, info, err := d.OpenBackup(context.Background(), "20240101000000")
+	require.NoError(t, err)
+	defer func() { _ = r.Close() }()
+	assert.Equal(t, obj, info)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestDumpster_DeleteBackup(t *testing.T) {
+	d, mockStore := newBackupsDumpster(t, []string{"20240101000000", "20240102000000"})
+	mockStore.On("GetObject", mock.Anything, testPinsKey).
+		Return(pinsData(t, map[string]Pin{"20240101000000": {}}), "etag", nil)
+	mockStore.On("Delete", "20240102000000").Return(nil).Once()
+
+	require.NoError(t, d.DeleteBackup(context.Background(), "20240102000000"))
+	require.ErrorIs(t, d.DeleteBackup(context.Background(), "20240101000000"), ErrBackupPinned)
+	require.ErrorIs(t, d.DeleteBackup(context.Background(), "20240109000000"), ErrBackupNotFound)
+}
+
+func TestDumpster_PinBackup(t *testing.T) {
+	d, mockStore := newBackupsDumpster(t, []string{"20240101000000"})
+	mockStore.On("GetObject", mock.Anything, testPinsKey).Return(nil, "", storage.ErrNotExist)
+	mockStore.On("PutObject", mock.Anything, testPinsKey, mock.Anything, storage.PutOptions{IfNoneMatch: true}).
+		Run(func(args mock.Arguments) {
+			pins := map[string]Pin{}
+			require.NoError(t, json.Unmarshal(args.Get(2).([]byte), &pins))
+			assert.Equal(t, "release 1.0", pins["20240101000000"].Label)
+		}).
+		Return("", nil)
+
+	require.NoError(t, d.PinBackup(context.Background(), "20240101000000", "release 1.0"))
+	require.ErrorIs(t, d.PinBackup(context.Background(), "20240109000000", ""), ErrBackupNotFound)
+}
+
+func TestDumpster_UnpinBackup_RetriesConcurrentUpdate(t *testing.T) {
+	d, mockStore := newBackupsDumpster(t, nil)
+	pins := pinsData(t, map[string]Pin{"20240101000000": {}, "20240102000000": {}})
+	mockStore.On("GetObject", mock.Anything, testPinsKey).Return(pins, "v1", nil).Once()
+	mockStore.On("GetObject", mock.Anything, testPinsKey).Return(pins, "v2", nil).Once()
+	mockStore.On("PutObject", mock.Anything, testPinsKey, mock.Anything, storage.PutOptions{IfMatch: "v1"}).
+		Return("", storage.ErrPreconditionFailed).Once()
+	mockStore.On("PutObject", mock.Anything, testPinsKey,
+		pinsData(t, map[string]Pin{"20240102000000": {}}), storage.PutOptions{IfMatch: "v2"}).
+		Return("", nil).Once()
+
+	require.NoError(t, d.UnpinBackup(context.Background(), "20240101000000"))
+	mockStore.AssertExpectations(t)
+}
+
+func TestDumpster_PurgeDumps_KeepsPinned(t *testing.T) {
+	d, mockStore := newBackupsDumpster(t, []string{"20240101000000", "20240102000000", "20240103000000"})
+	mockStore.On("GetObject", mock.Anything, testPinsKey).
+		Return(pinsData(t, map[string]Pin{"20240101000000": {}}), "etag", nil)
+	mockStore.On("Delete", "20240102000000").Return(nil).Once()
+
+	resp, err := d.PurgeDumps(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &PurgeResponse{Deleted: 1, Remaining: 2}, resp)
+	mockStore.AssertExpectations(t)
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package dumpster
+
+import "errors"
+
+// freeSpace is not supported on this platform; the free-space check is skipped.
+func freeSpace(string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
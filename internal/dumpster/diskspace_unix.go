@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package dumpster
+
+import "syscall"
+
+// freeSpace returns the number of bytes available to unprivileged users on the filesystem holding path.
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil //nolint:gosec // block size is never negative
+}
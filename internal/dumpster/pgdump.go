@@ -8,30 +8,48 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hibare/GoCommon/v2/pkg/crypto/gpg"
 	"github.com/hibare/GoCommon/v2/pkg/datetime"
-	"github.com/hibare/GoCommon/v2/pkg/file"
 	"github.com/hibare/stashly/internal/config"
 	"github.com/hibare/stashly/internal/constants"
 	"github.com/hibare/stashly/internal/exec"
+	"github.com/hibare/stashly/internal/retry"
 	"github.com/hibare/stashly/internal/storage"
+	"github.com/hibare/stashly/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Errors reported when a backup or a single database dump exceeds its configured timeout.
+var (
+	ErrTimeout         = errors.New("backup timed out")
+	ErrDatabaseTimeout = errors.New("database dump timed out")
 )
 
 // DumpsterIface defines the interface for dumpster operations.
 // revive:disable-next-line exported
 type DumpsterIface interface {
-	Dump(ctx context.Context) (int, string, error)
+	Dump(ctx context.Context) (*DumpResponse, error)
 	ListDumps(ctx context.Context) ([]string, error)
-	PurgeDumps(ctx context.Context) error
+	PurgeDumps(ctx context.Context) (*PurgeResponse, error)
 }
 
+var _ DumpsterIface = (*Dumpster)(nil)
+
 // Dumpster handles PostgreSQL database dumps and interactions with storage backends.
 type Dumpster struct {
-	store          storage.StorageIface
-	cfg            *config.Config
-	exec           exec.ExecIface
+	store        storage.StorageIface
+	cfg          *config.Config
+	exec         exec.ExecIface
+	workspaceDir string
+
+	// workspace is the directory of the current run, holding the dumps in backupLocation,
+	// the archive and the encrypted archive.
+	workspace      string
 	backupLocation string
 }
 
@@ -45,42 +63,109 @@ func (d *Dumpster) getEnvVars() []string {
 }
 
 func (d *Dumpster) runPreChecks() error {
-	// Remove old backup location if exists
-	if err := os.RemoveAll(d.backupLocation); err != nil {
+	if err := d.checkBinaries(); err != nil {
 		return err
 	}
 
-	// Create backup location
-	if err := os.MkdirAll(d.backupLocation, 0750); err != nil {
-		return err
+	// Create a workspace unique to this run, so concurrent and aborted runs never share files
+	if err := os.MkdirAll(d.workspaceDir, 0750); err != nil {
+		return fmt.Errorf("error creating workspace directory: %w", err)
 	}
+	workspace, err := os.MkdirTemp(d.workspaceDir, "stashly-"+d.cfg.JobName()+"-")
+	if err != nil {
+		return fmt.Errorf("error creating run workspace: %w", err)
+	}
+	d.workspace = workspace
+	d.backupLocation = filepath.Join(workspace, constants.ExportDir)
 
-	// Check if required binaries are available
-	binaries := []string{"psql", "pg_dump"}
+	return os.Mkdir(d.backupLocation, 0750)
+}
 
-	for _, bin := range binaries {
-		if _, err := d.exec.LookPath(bin); err != nil {
-			return fmt.Errorf("%s not found in PATH: %w", bin, err)
-		}
+// cleanup removes the run workspace, unless it is to be kept.
+func (d *Dumpster) cleanup(ctx context.Context) {
+	if d.workspace == "" {
+		return
+	}
+	if d.cfg.Backup.KeepLocal {
+		slog.InfoContext(ctx, "Keeping local backup files", "workspace", d.workspace)
+		return
+	}
+	if err := os.RemoveAll(d.workspace); err != nil {
+		slog.WarnContext(ctx, "Failed to remove run workspace", "workspace", d.workspace, "error", err)
+	}
+}
+
+// checkFreeSpace fails if the workspace lacks free space for the estimated size of the dumps.
+func (d *Dumpster) checkFreeSpace(ctx context.Context, estimated int64) error {
+	if !d.cfg.Backup.SpaceCheck || estimated <= 0 {
+		return nil
+	}
+
+	free, err := freeSpace(d.workspace)
+	if err != nil {
+		slog.WarnContext(ctx, "Skipping free space check", "workspace", d.workspace, "error", err)
+		return nil
+	}
+
+	slog.DebugContext(ctx, "Checked free space", "workspace", d.workspace, "free", free, "estimated", estimated)
+	if free < uint64(estimated) {
+		return fmt.Errorf("insufficient free space in %s: %d bytes available, databases are estimated at %d bytes",
+			d.workspaceDir, free, estimated)
 	}
 	return nil
 }
 
+// DatabaseResult holds the outcome of dumping a single database.
+type DatabaseResult struct {
+	Name     string
+	Size     int64
+	Duration time.Duration
+	Err      error
+
+	// Attempts is the number of dump attempts, set only when the database was retried.
+	Attempts int
+}
+
+// TimedOut reports whether the dump failed because it exceeded backup.database-timeout.
+func (r *DatabaseResult) TimedOut() bool {
+	return errors.Is(r.Err, ErrDatabaseTimeout)
+}
+
 type exportResponse struct {
 	totalDatabases    int
 	exportedDatabases int
 	exportLocation    string
+	databases         []DatabaseResult
+	retries           int
 }
 
-func (d *Dumpster) export(ctx context.Context) (*exportResponse, error) {
-	totalDatabases := 0
-	exportedDatabases := 0
-	databases := []string{}
+// stagePolicy returns the retry policy for individual stages. Without per-stage retries every
+// stage is attempted once and the whole run is retried instead.
+func (d *Dumpster) stagePolicy() retry.Policy {
+	if !d.cfg.Backup.Retry.PerStage {
+		return retry.Policy{MaxAttempts: 1}
+	}
+	return d.cfg.Backup.Retry.Policy()
+}
 
-	envVars := d.getEnvVars()
+// Ping checks that the Postgres server accepts connections with the configured credentials.
+func (d *Dumpster) Ping(ctx context.Context) error {
+	if _, err := d.query(ctx, "SELECT 1"); err != nil {
+		return fmt.Errorf("error connecting to postgres: %w", err)
+	}
+	return nil
+}
 
-	// Get list of non-template databases using psql machine output
-	query := "SELECT datname FROM pg_database WHERE datistemplate = false AND datname NOT IN ('postgres','defaultdb');"
+// listDatabases returns the names of the databases to dump and their estimated total size in bytes.
+func (d *Dumpster) listDatabases(ctx context.Context, envVars []string) (_ []string, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "list databases")
+	defer func() { tracing.End(span, err) }()
+
+	databases := []string{}
+	var estimated int64
+
+	// Get list of non-template databases and their sizes using psql machine output
+	query := "SELECT datname, pg_database_size(datname) FROM pg_database WHERE datistemplate = false AND datname NOT IN ('postgres','defaultdb');"
 
 	output, err := d.exec.Command(ctx, "psql", "-At", "-c", query).
 		WithEnv(envVars).
@@ -89,7 +174,7 @@ func (d *Dumpster) export(ctx context.Context) (*exportResponse, error) {
 		Output()
 
 	if err != nil {
-		return nil, fmt.Errorf("error getting list of databases: %w", err)
+		return nil, 0, fmt.Errorf("error getting list of databases: %w", err)
 	}
 
 	for _, line := range strings.Split(string(output), "\n") {
@@ -97,102 +182,343 @@ func (d *Dumpster) export(ctx context.Context) (*exportResponse, error) {
 		if line == "" {
 			continue
 		}
-		databases = append(databases, line)
-		totalDatabases++
+		name, size, _ := strings.Cut(line, "|")
+		if !d.cfg.Backup.Databases.Match(name) {
+			slog.DebugContext(ctx, "Skipping database excluded by filter", "database", name)
+			continue
+		}
+		databases = append(databases, name)
+		if n, pErr := strconv.ParseInt(size, 10, 64); pErr == nil {
+			estimated += n
+		}
+	}
+	span.SetAttributes(attribute.Int("stashly.databases", len(databases)), attribute.Int64("stashly.estimated_bytes", estimated))
+	return databases, estimated, nil
+}
+
+// dumpDatabase dumps a single database into the backup location.
+func (d *Dumpster) dumpDatabase(ctx context.Context, db string, envVars []string) (result DatabaseResult) {
+	ctx, span := tracing.Start(ctx, "pg_dump", attribute.String("db.namespace", db))
+	defer func() {
+		span.SetAttributes(attribute.Int64("stashly.dump_bytes", result.Size))
+		tracing.End(span, result.Err)
+	}()
+	slog.InfoContext(ctx, "Processing database", "database", db)
+
+	dumpCtx := ctx
+	if timeout := d.cfg.Backup.DatabaseTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		dumpCtx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w after %s", ErrDatabaseTimeout, timeout))
+		defer cancel()
+	}
+
+	args := []string{"--no-owner", "--no-acl", "--dbname=" + db}
+	if timeout := d.cfg.Backup.LockWaitTimeout; timeout > 0 {
+		args = append(args, "--lock-wait-timeout="+strconv.FormatInt(timeout.Milliseconds(), 10))
+	}
+
+	start := time.Now()
+	outFile := filepath.Join(d.backupLocation, db+".sql")
+	out, err := d.exec.Command(dumpCtx, "pg_dump", append(args, "--file="+outFile)...).
+		WithEnv(envVars).
+		WithDir(d.backupLocation).
+		CombinedOutput()
+	result = DatabaseResult{Name: db, Duration: time.Since(start)}
+	if err != nil {
+		// A timed-out dump is reported as such, unless the whole run was cancelled
+		if dumpCtx.Err() != nil && ctx.Err() == nil {
+			err = context.Cause(dumpCtx)
+		}
+		slog.WarnContext(ctx, "Error dumping database", "database", db, "error", err, "output", string(out))
+		result.Err = err
+		return result
+	}
+	if info, sErr := os.Stat(outFile); sErr == nil {
+		result.Size = info.Size()
+	}
+	slog.InfoContext(ctx, "Successfully dumped database", "database", db)
+	return result
+}
+
+func (d *Dumpster) export(ctx context.Context) (_ *exportResponse, err error) {
+	ctx, span := tracing.Start(ctx, "dumpster.export")
+	defer func() { tracing.End(span, err) }()
+
+	envVars := d.getEnvVars()
+	policy := d.stagePolicy()
+
+	var databases []string
+	var estimated int64
+	err = policy.Do(ctx, "list databases", func(ctx context.Context) error {
+		var lErr error
+		databases, estimated, lErr = d.listDatabases(ctx, envVars)
+		return lErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = d.checkFreeSpace(ctx, estimated); err != nil {
+		return nil, err
 	}
 
 	slog.DebugContext(ctx, "Databases to be dumped", "databases", databases, "location", d.backupLocation)
 
-	for _, db := range databases {
-		slog.InfoContext(ctx, "Processing database", "database", db)
+	// newResponse reports the databases dumped so far, so failed runs keep their per-database results
+	retries := 0
+	newResponse := func(results []DatabaseResult) *exportResponse {
+		exportedDatabases := 0
+		for _, result := range results {
+			if result.Err == nil {
+				exportedDatabases++
+			}
+		}
+		return &exportResponse{
+			totalDatabases:    len(databases),
+			exportedDatabases: exportedDatabases,
+			exportLocation:    d.backupLocation,
+			databases:         results,
+			retries:           retries,
+		}
+	}
 
-		outFile := filepath.Join(d.backupLocation, db+".sql")
-		out, cErr := d.exec.Command(ctx, "pg_dump", "--no-owner", "--no-acl", "--dbname="+db, "--file="+outFile).
-			WithEnv(envVars).
-			WithDir(d.backupLocation).
-			CombinedOutput()
-		if cErr != nil {
-			slog.WarnContext(ctx, "Error dumping database", "database", db, "error", cErr, "output", string(out))
-			continue
+	results := make([]DatabaseResult, len(databases))
+	for i, db := range databases {
+		if ctx.Err() != nil {
+			return newResponse(results[:i]), ctx.Err()
+		}
+		results[i] = d.dumpDatabase(ctx, db, envVars)
+		if ctx.Err() != nil {
+			return newResponse(results[:i+1]), ctx.Err()
+		}
+	}
+
+	// Dump only the failed databases again
+	for attempt := 2; attempt <= policy.Attempts(); attempt++ {
+		failed := []int{}
+		for i, result := range results {
+			if result.Err != nil {
+				failed = append(failed, i)
+			}
+		}
+		if len(failed) == 0 {
+			break
+		}
+
+		slog.WarnContext(ctx, "Retrying failed databases", "count", len(failed), "attempt", attempt,
+			"max_attempts", policy.Attempts(), "delay", policy.Delay(attempt-1))
+		if wErr := policy.Wait(ctx, attempt-1); wErr != nil {
+			return newResponse(results), wErr
+		}
+		retries++
+
+		for _, i := range failed {
+			results[i] = d.dumpDatabase(ctx, results[i].Name, envVars)
+			results[i].Attempts = attempt
+			if ctx.Err() != nil {
+				return newResponse(results), ctx.Err()
+			}
 		}
-		exportedDatabases++
-		slog.InfoContext(ctx, "Successfully dumped database", "database", db)
 	}
 
-	return &exportResponse{
-		totalDatabases:    totalDatabases,
-		exportedDatabases: exportedDatabases,
-		exportLocation:    d.backupLocation,
-	}, nil
+	return newResponse(results), nil
 }
 
 // DumpResponse holds information about the dump operation.
 type DumpResponse struct {
 	TotalDatabases    int
 	ExportedDatabases int
+	Databases         []DatabaseResult
 	DumpLocation      string
 	ArchiveLocation   string
+	ArchiveSize       int64
 	StorageKey        string
+	Destination       string
+	StartedAt         time.Time
+	Duration          time.Duration
+
+	// Retries is the number of retries the run needed, whole-run or per stage.
+	Retries int
+}
+
+// FailedDatabases returns the names of databases that could not be dumped.
+func (r *DumpResponse) FailedDatabases() []string {
+	failed := []string{}
+	for _, db := range r.Databases {
+		if db.Err != nil {
+			failed = append(failed, db.Name)
+		}
+	}
+	return failed
+}
+
+// TimedOutDatabases returns the names of databases whose dump exceeded the database timeout.
+func (r *DumpResponse) TimedOutDatabases() []string {
+	timedOut := []string{}
+	for _, db := range r.Databases {
+		if db.TimedOut() {
+			timedOut = append(timedOut, db.Name)
+		}
+	}
+	return timedOut
+}
+
+// Uploaded reports whether the backup reached storage. Failed runs return the partial details
+// of the databases they dumped without a storage key.
+func (r *DumpResponse) Uploaded() bool {
+	return r.StorageKey != ""
+}
+
+// Partial reports whether only some of the discovered databases were exported.
+func (r *DumpResponse) Partial() bool {
+	return r.ExportedDatabases > 0 && r.ExportedDatabases < r.TotalDatabases
 }
 
 // CreateDump creates a PostgreSQL dump, optionally encrypts it, uploads it to storage, and returns details.
-func (d *Dumpster) CreateDump(ctx context.Context) (*DumpResponse, error) {
+// Runs that fail after the databases were listed return the details gathered so far along with the error.
+// Failed runs are retried according to the retry policy, either as a whole or per stage.
+// Each attempt runs in its own workspace, which is removed afterwards unless backup.keep-local is set.
+// The run, including retries, fails with ErrTimeout once backup.timeout has elapsed.
+func (d *Dumpster) CreateDump(ctx context.Context) (resp *DumpResponse, err error) {
+	ctx, span := tracing.Start(ctx, "dumpster.CreateDump")
+	defer func() { tracing.End(span, err) }()
+
+	timeout := d.cfg.Backup.Timeout
+	if timeout <= 0 {
+		return d.createDumpWithRetries(ctx)
+	}
+
+	runCtx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w after %s", ErrTimeout, timeout))
+	defer cancel()
+	resp, err = d.createDumpWithRetries(runCtx)
+	if err != nil && runCtx.Err() != nil && ctx.Err() == nil {
+		return resp, context.Cause(runCtx)
+	}
+	return resp, err
+}
+
+func (d *Dumpster) createDumpWithRetries(ctx context.Context) (*DumpResponse, error) {
+	if d.cfg.Backup.Retry.PerStage {
+		return d.createDump(ctx)
+	}
+
+	var dumpResp *DumpResponse
+	attempts := 0
+	err := d.cfg.Backup.Retry.Policy().Do(ctx, "backup", func(ctx context.Context) error {
+		attempts++
+		var cErr error
+		dumpResp, cErr = d.createDump(ctx)
+		return cErr
+	})
+	if dumpResp != nil {
+		dumpResp.Retries = attempts - 1
+	}
+	return dumpResp, err
+}
+
+func (d *Dumpster) createDump(ctx context.Context) (*DumpResponse, error) {
+	startedAt := time.Now()
+	policy := d.stagePolicy()
+	d.workspace = ""
+	defer d.cleanup(ctx)
 	if err := d.runPreChecks(); err != nil {
 		return nil, err
 	}
+	slog.DebugContext(ctx, "Created run workspace", "workspace", d.workspace)
 
 	resp, err := d.export(ctx)
-	if err != nil {
+	if resp == nil {
 		return nil, err
 	}
 
 	dumpResp := &DumpResponse{
 		TotalDatabases:    resp.totalDatabases,
 		ExportedDatabases: resp.exportedDatabases,
+		Databases:         resp.databases,
 		DumpLocation:      resp.exportLocation,
+		StartedAt:         startedAt,
+		Retries:           resp.retries,
+	}
+	fail := func(err error) (*DumpResponse, error) {
+		dumpResp.Duration = time.Since(startedAt)
+		return dumpResp, err
 	}
 
+	if err != nil {
+		return fail(err)
+	}
 	if resp.exportedDatabases <= 0 {
-		return nil, errors.New("no databases were exported")
+		return fail(errors.New("no databases were exported"))
 	}
 
-	archivePath, _, _, _, err := file.ArchiveDir(resp.exportLocation, nil)
+	archivePath := resp.exportLocation + ".zip"
+	_, span := tracing.Start(ctx, "archive")
+	err = archiveDir(resp.exportLocation, archivePath)
+	tracing.End(span, err)
 	if err != nil {
-		return nil, err
+		return fail(fmt.Errorf("error archiving dumps: %w", err))
 	}
 
 	uploadFilePath := archivePath
 
 	if d.cfg.Backup.Encrypt {
-		gpgKey, gErr := gpg.DownloadGPGPubKey(d.cfg.Encryption.GPG.KeyID, d.cfg.Encryption.GPG.KeyServer)
+		var gpgKey gpg.GPG
+		gErr := policy.Do(ctx, "download gpg key", func(ctx context.Context) error {
+			_, span := tracing.Start(ctx, "download gpg key", attribute.String("stashly.gpg_key_id", d.cfg.Encryption.GPG.KeyID))
+			var dErr error
+			gpgKey, dErr = gpg.DownloadGPGPubKey(d.cfg.Encryption.GPG.KeyID, d.cfg.Encryption.GPG.KeyServer)
+			tracing.End(span, dErr)
+			return dErr
+		})
 		if gErr != nil {
 			slog.WarnContext(ctx, "Error downloading gpg key", "error", gErr)
-			return nil, gErr
+			return fail(gErr)
 		}
+		// The key is read from memory; drop the copy written to the temp directory
+		_ = os.Remove(gpgKey.PublicKeyPath)
 
-		encryptedFilePath, gErr := gpgKey.EncryptFile(archivePath)
+		encryptedFilePath := archivePath + ".gpg"
+		_, span = tracing.Start(ctx, "encrypt")
+		gErr = encryptFile(gpgKey.PublicKey, archivePath, encryptedFilePath)
+		tracing.End(span, gErr)
 		if gErr != nil {
 			slog.WarnContext(ctx, "Error encrypting archive file", "error", gErr)
-			return nil, gErr
+			return fail(gErr)
 		}
 		uploadFilePath = encryptedFilePath
 	}
 
+	// Failed uploads are retried without dumping again
 	slog.InfoContext(ctx, "Uploading backup", "file", uploadFilePath, "storage", d.store.Name())
-	key, err := d.store.Upload(uploadFilePath)
+	var key string
+	uploads := 0
+	err = policy.Do(ctx, "upload", func(ctx context.Context) error {
+		uploads++
+		var uErr error
+		key, uErr = d.store.Upload(ctx, uploadFilePath)
+		return uErr
+	})
+	dumpResp.Retries += uploads - 1
 	if err != nil {
-		return nil, err
+		return fail(err)
 	}
 
 	slog.InfoContext(ctx, "Backup uploaded", "location", key)
+	if info, sErr := os.Stat(uploadFilePath); sErr == nil {
+		dumpResp.ArchiveSize = info.Size()
+	}
 	dumpResp.ArchiveLocation = archivePath
 	dumpResp.StorageKey = key
+	dumpResp.Destination = d.store.Name()
+	dumpResp.Duration = time.Since(startedAt)
 	return dumpResp, nil
 }
 
 // ListDumps lists available dumps in the storage backend, sorted by date.
 func (d *Dumpster) ListDumps(ctx context.Context) ([]string, error) {
+	_, span := tracing.Start(ctx, "storage.List")
 	keys, err := d.store.List()
+	tracing.End(span, err)
 	if err != nil {
 		return nil, err
 	}
@@ -202,21 +528,71 @@ func (d *Dumpster) ListDumps(ctx context.Context) ([]string, error) {
 		return []string{}, nil
 	}
 
-	keys = d.store.TrimPrefix(keys)
+	// Skip anything that is not a backup timestamp, such as objects put there by other tools.
+	keys = slices.DeleteFunc(d.store.TrimPrefix(keys), func(key string) bool {
+		_, pErr := time.Parse(constants.DefaultDateTimeLayout, key)
+		return pErr != nil
+	})
 	keys = datetime.SortDateTimes(keys)
 	return keys, nil
 }
 
-// PurgeDumps deletes old dumps from storage based on the retention policy.
-func (d *Dumpster) PurgeDumps(ctx context.Context) error {
+// LastDump returns the time of the newest dump in storage, or the zero time if there is none.
+// Dump keys are timestamps in local time.
+func (d *Dumpster) LastDump(ctx context.Context) (time.Time, error) {
 	keys, err := d.ListDumps(ctx)
 	if err != nil {
-		return err
+		return time.Time{}, err
+	}
+	if len(keys) == 0 {
+		return time.Time{}, nil
 	}
+	return time.ParseInLocation(constants.DefaultDateTimeLayout, keys[0], time.Local)
+}
+
+// PurgeResponse summarises a purge of old dumps.
+type PurgeResponse struct {
+	// Deleted is the number of dumps removed from storage.
+	Deleted int
+	// Remaining is the number of dumps left in storage.
+	Remaining int
+}
+
+// PurgeDumps deletes old dumps from storage based on the retention policy. Pinned dumps are never deleted
+// and do not count towards the retention count.
+// On a failed deletion the response still counts the dumps deleted before it.
+func (d *Dumpster) PurgeDumps(ctx context.Context) (resp *PurgeResponse, err error) {
+	ctx, span := tracing.Start(ctx, "dumpster.PurgeDumps", attribute.Int("stashly.retention_count", d.cfg.Backup.RetentionCount))
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(attribute.Int("stashly.deleted", resp.Deleted), attribute.Int("stashly.remaining", resp.Remaining))
+		}
+		tracing.End(span, err)
+	}()
 
+	keys, err := d.ListDumps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp = &PurgeResponse{Remaining: len(keys)}
 	if len(keys) <= d.cfg.Backup.RetentionCount {
 		slog.InfoContext(ctx, "No backups to delete")
-		return nil
+		return resp, nil
+	}
+
+	// Pinned backups are kept on top of the retained ones
+	pins, _, err := d.pins(ctx)
+	if err != nil {
+		return resp, err
+	}
+	keys = slices.DeleteFunc(keys, func(key string) bool {
+		_, ok := pins[key]
+		return ok
+	})
+	if len(keys) <= d.cfg.Backup.RetentionCount {
+		slog.InfoContext(ctx, "No backups to delete", "pinned", len(pins))
+		return resp, nil
 	}
 
 	keysToDelete := keys[d.cfg.Backup.RetentionCount:]
@@ -224,34 +600,45 @@ func (d *Dumpster) PurgeDumps(ctx context.Context) error {
 
 	for _, key := range keysToDelete {
 		slog.InfoContext(ctx, "Deleting backup", "key", key)
-		if sErr := d.store.Delete(key); sErr != nil {
+		_, dSpan := tracing.Start(ctx, "storage.Delete", attribute.String("stashly.key", key))
+		sErr := d.store.Delete(key)
+		tracing.End(dSpan, sErr)
+		if sErr != nil {
 			slog.ErrorContext(ctx, "Error deleting backup", "key", key, "error", sErr)
-			return fmt.Errorf("error deleting backup %s: %w", key, sErr)
+			return resp, fmt.Errorf("error deleting backup %s: %w", key, sErr)
 		}
+		resp.Deleted++
+		resp.Remaining--
 	}
 	slog.InfoContext(ctx, "Deletion completed successfully")
-	return nil
+	return resp, nil
 }
 
-// Dump creates a dump and purges old dumps based on retention policy.
+// Dump creates a dump and purges old dumps based on retention policy. Like CreateDump, it returns
+// the details of the dump along with any error.
 func (d *Dumpster) Dump(ctx context.Context) (*DumpResponse, error) {
 	resp, err := d.CreateDump(ctx)
 	if err != nil {
-		return nil, err
+		return resp, err
 	}
 
-	if pErr := d.PurgeDumps(ctx); pErr != nil {
-		return nil, pErr
+	if _, pErr := d.PurgeDumps(ctx); pErr != nil {
+		return resp, pErr
 	}
 	return resp, nil
 }
 
 // NewDumpster creates a new Dumpster instance with the provided configuration, storage backend, and executor.
+// Runs use workspaces below backup.workspace-dir, or the system temp directory if it is not set.
 func NewDumpster(cfg *config.Config, store storage.StorageIface, exec exec.ExecIface) *Dumpster {
+	workspaceDir := cfg.Backup.WorkspaceDir
+	if workspaceDir == "" {
+		workspaceDir = os.TempDir()
+	}
 	return &Dumpster{
-		store:          store,
-		cfg:            cfg,
-		exec:           exec,
-		backupLocation: filepath.Join(os.TempDir(), constants.ExportDir),
+		store:        store,
+		cfg:          cfg,
+		exec:         exec,
+		workspaceDir: workspaceDir,
 	}
 }
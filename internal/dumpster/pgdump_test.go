@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/hibare/stashly/internal/config"
 	"github.com/hibare/stashly/internal/exec"
@@ -12,6 +15,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestNewDumpster(t *testing.T) {
@@ -25,7 +33,15 @@ func TestNewDumpster(t *testing.T) {
 	assert.Equal(t, cfg, dumpster.cfg)
 	assert.Equal(t, mockStore, dumpster.store)
 	assert.Equal(t, mockExec, dumpster.exec)
-	assert.Contains(t, dumpster.backupLocation, "export")
+	assert.Equal(t, os.TempDir(), dumpster.workspaceDir)
+}
+
+func TestNewDumpster_WorkspaceDir(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+
+	dumpster := NewDumpster(&config.Config{Backup: config.BackupConfig{WorkspaceDir: "/var/lib/stashly"}}, mockStore, mockExec)
+	assert.Equal(t, "/var/lib/stashly", dumpster.workspaceDir)
 }
 
 func TestDumpster_getEnvVars(t *testing.T) {
@@ -54,7 +70,7 @@ func TestDumpster_getEnvVars(t *testing.T) {
 }
 
 func TestDumpster_runPreChecks_Success(t *testing.T) {
-	cfg := &config.Config{}
+	cfg := &config.Config{Backup: config.BackupConfig{WorkspaceDir: t.TempDir()}}
 	mockStore := storage.NewMockStorageIface(t)
 	mockExec := exec.NewMockExecIface(t)
 
@@ -68,9 +84,9 @@ func TestDumpster_runPreChecks_Success(t *testing.T) {
 
 	require.NoError(t, err)
 	mockExec.AssertExpectations(t)
-
-	// Cleanup
-	_ = os.RemoveAll(dumpster.backupLocation)
+	assert.Equal(t, cfg.Backup.WorkspaceDir, filepath.Dir(dumpster.workspace))
+	assert.Equal(t, filepath.Join(dumpster.workspace, "db_exports"), dumpster.backupLocation)
+	assert.DirExists(t, dumpster.backupLocation)
 }
 
 func TestDumpster_runPreChecks_BinaryNotFound(t *testing.T) {
@@ -109,20 +125,20 @@ func TestDumpster_CreateDump_Success(t *testing.T) {
 	// Mock successful database listing
 	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
-	mockCmd.On("WithDir", dumpster.backupLocation).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
 	mockCmd.On("Output").Return([]byte("db1\n"), nil)
 
 	// Mock successful pg_dump
 	mockExec.On("Command", mock.Anything, "pg_dump", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
-	mockCmd.On("WithDir", dumpster.backupLocation).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
 	mockCmd.On("CombinedOutput").Return([]byte(""), nil)
 
 	// Mock successful storage upload
 	mockStore.On("Name").Return("test-storage")
-	mockStore.On("Upload", mock.Anything).Return("backup-2024-01-01.tar.gz", nil)
+	mockStore.On("Upload", mock.Anything, mock.Anything).Return("backup-2024-01-01.tar.gz", nil)
 
 	resp, err := dumpster.CreateDump(context.Background())
 
@@ -132,13 +148,52 @@ func TestDumpster_CreateDump_Success(t *testing.T) {
 	assert.Equal(t, 1, resp.ExportedDatabases)
 	assert.Equal(t, dumpster.backupLocation, resp.DumpLocation)
 	assert.Equal(t, "backup-2024-01-01.tar.gz", resp.StorageKey)
+	assert.True(t, resp.Uploaded())
+	assert.Equal(t, "test-storage", resp.Destination)
+	require.Len(t, resp.Databases, 1)
+	assert.Equal(t, "db1", resp.Databases[0].Name)
+	assert.NoError(t, resp.Databases[0].Err)
+	assert.Empty(t, resp.FailedDatabases())
+	assert.False(t, resp.Partial())
 
 	mockExec.AssertExpectations(t)
 	mockCmd.AssertExpectations(t)
 	mockStore.AssertExpectations(t)
+}
+
+func TestDumpster_CreateDump_UploadError(t *testing.T) {
+	cfg := &config.Config{}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockExec.On("LookPath", "psql").Return("/usr/bin/psql", nil)
+	mockExec.On("LookPath", "pg_dump").Return("/usr/bin/pg_dump", nil)
+
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+	mockCmd.On("Output").Return([]byte("db1\n"), nil)
+
+	mockExec.On("Command", mock.Anything, "pg_dump", mock.Anything).Return(mockCmd)
+	mockCmd.On("CombinedOutput").Return([]byte(""), nil)
 
-	// Cleanup
-	_ = os.RemoveAll(dumpster.backupLocation)
+	mockStore.On("Name").Return("test-storage")
+	mockStore.On("Upload", mock.Anything, mock.Anything).Return("", errors.New("access denied"))
+
+	resp, err := dumpster.CreateDump(context.Background())
+
+	// The per-database results survive the failed upload
+	require.ErrorContains(t, err, "access denied")
+	require.NotNil(t, resp)
+	assert.Equal(t, 1, resp.ExportedDatabases)
+	require.Len(t, resp.Databases, 1)
+	assert.NoError(t, resp.Databases[0].Err)
+	assert.False(t, resp.Uploaded())
+	assert.Positive(t, resp.Duration)
 }
 
 func TestDumpster_CreateDump_NoDatabasesExported(t *testing.T) {
@@ -156,14 +211,16 @@ func TestDumpster_CreateDump_NoDatabasesExported(t *testing.T) {
 	// Mock successful database listing but no databases
 	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
-	mockCmd.On("WithDir", dumpster.backupLocation).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
 	mockCmd.On("Output").Return([]byte(""), nil)
 
 	resp, err := dumpster.CreateDump(context.Background())
 
 	require.Error(t, err)
-	require.Nil(t, resp)
+	require.NotNil(t, resp)
+	assert.Zero(t, resp.TotalDatabases)
+	assert.Empty(t, resp.StorageKey)
 	assert.Contains(t, err.Error(), "no databases were exported")
 
 	mockExec.AssertExpectations(t)
@@ -189,22 +246,27 @@ func TestDumpster_CreateDump_PgDumpError(t *testing.T) {
 	// Mock successful database listing
 	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
-	mockCmd.On("WithDir", dumpster.backupLocation).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
 	mockCmd.On("Output").Return([]byte("db1\n"), nil)
 
 	// Mock failed pg_dump
 	mockExec.On("Command", mock.Anything, "pg_dump", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
-	mockCmd.On("WithDir", dumpster.backupLocation).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
 	mockCmd.On("CombinedOutput").Return([]byte("permission denied"), errors.New("access denied"))
 
 	resp, err := dumpster.CreateDump(context.Background())
 
+	// The failed run still reports which databases failed and why
 	require.Error(t, err)
-	require.Nil(t, resp)
+	require.NotNil(t, resp)
 	assert.Contains(t, err.Error(), "no databases were exported")
+	assert.Equal(t, 1, resp.TotalDatabases)
+	assert.Zero(t, resp.ExportedDatabases)
+	assert.Equal(t, []string{"db1"}, resp.FailedDatabases())
+	assert.Empty(t, resp.StorageKey)
 
 	mockExec.AssertExpectations(t)
 	mockCmd.AssertExpectations(t)
@@ -218,7 +280,7 @@ func TestDumpster_ListDumps_Success(t *testing.T) {
 	dumpster := NewDumpster(cfg, mockStore, mockExec)
 
 	// Mock successful storage listing
-	keys := []string{"backup-2024-01-01.tar.gz", "backup-2024-01-02.tar.gz"}
+	keys := []string{"20240101000000", "20240102000000"}
 	mockStore.On("List").Return(keys, nil)
 	mockStore.On("TrimPrefix", keys).Return(keys)
 
@@ -232,6 +294,55 @@ func TestDumpster_ListDumps_Success(t *testing.T) {
 	mockStore.AssertExpectations(t)
 }
 
+func TestDumpster_ListDumps_SkipsNonBackupKeys(t *testing.T) {
+	cfg := &config.Config{}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	keys := []string{"20240101000000", ".stashly", "20240102000000", "notes"}
+	mockStore.On("List").Return(keys, nil)
+	mockStore.On("TrimPrefix", keys).Return(keys)
+
+	dumps, err := dumpster.ListDumps(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20240102000000", "20240101000000"}, dumps)
+}
+
+func TestDumpster_LastDump(t *testing.T) {
+	cfg := &config.Config{}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	keys := []string{"20240101000000", "20240103120000", "20240102000000"}
+	mockStore.On("List").Return(keys, nil)
+	mockStore.On("TrimPrefix", keys).Return(keys)
+
+	last, err := dumpster.LastDump(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 3, 12, 0, 0, 0, time.Local), last)
+}
+
+func TestDumpster_LastDump_NoDumps(t *testing.T) {
+	cfg := &config.Config{}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockStore.On("List").Return([]string{}, nil)
+
+	last, err := dumpster.LastDump(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+}
+
 func TestDumpster_ListDumps_Empty(t *testing.T) {
 	cfg := &config.Config{}
 	mockStore := storage.NewMockStorageIface(t)
@@ -281,17 +392,19 @@ func TestDumpster_PurgeDumps_Success(t *testing.T) {
 	dumpster := NewDumpster(cfg, mockStore, mockExec)
 
 	// Mock successful storage listing
-	keys := []string{"backup-2024-01-01.tar.gz", "backup-2024-01-02.tar.gz", "backup-2024-01-03.tar.gz"}
+	keys := []string{"20240101000000", "20240102000000", "20240103000000"}
 	mockStore.On("List").Return(keys, nil)
 	mockStore.On("TrimPrefix", keys).Return(keys)
 
+	mockStore.On("GetObject", mock.Anything, ".stashly/pins/.json").Return(nil, "", storage.ErrNotExist)
 	// Mock successful deletion of old backup
 	// Note: The actual key will be transformed by datetime.SortDateTimes
 	mockStore.On("Delete", mock.Anything).Return(nil)
 
-	err := dumpster.PurgeDumps(context.Background())
+	resp, err := dumpster.PurgeDumps(context.Background())
 
 	require.NoError(t, err)
+	assert.Equal(t, &PurgeResponse{Deleted: 1, Remaining: 2}, resp)
 
 	mockStore.AssertExpectations(t)
 }
@@ -308,13 +421,14 @@ func TestDumpster_PurgeDumps_NoDeletionNeeded(t *testing.T) {
 	dumpster := NewDumpster(cfg, mockStore, mockExec)
 
 	// Mock storage listing with fewer keys than retention count
-	keys := []string{"backup-2024-01-01.tar.gz", "backup-2024-01-02.tar.gz"}
+	keys := []string{"20240101000000", "20240102000000"}
 	mockStore.On("List").Return(keys, nil)
 	mockStore.On("TrimPrefix", keys).Return(keys)
 
-	err := dumpster.PurgeDumps(context.Background())
+	resp, err := dumpster.PurgeDumps(context.Background())
 
 	require.NoError(t, err)
+	assert.Equal(t, &PurgeResponse{Remaining: 2}, resp)
 
 	mockStore.AssertExpectations(t)
 }
@@ -331,18 +445,20 @@ func TestDumpster_PurgeDumps_DeleteError(t *testing.T) {
 	dumpster := NewDumpster(cfg, mockStore, mockExec)
 
 	// Mock successful storage listing
-	keys := []string{"backup-2024-01-01.tar.gz", "backup-2024-01-02.tar.gz", "backup-2024-01-03.tar.gz"}
+	keys := []string{"20240101000000", "20240102000000", "20240103000000"}
 	mockStore.On("List").Return(keys, nil)
 	mockStore.On("TrimPrefix", keys).Return(keys)
 
+	mockStore.On("GetObject", mock.Anything, ".stashly/pins/.json").Return(nil, "", storage.ErrNotExist)
 	// Mock failed deletion
 	// Note: The actual key will be transformed by datetime.SortDateTimes
 	mockStore.On("Delete", mock.Anything).Return(errors.New("delete failed"))
 
-	err := dumpster.PurgeDumps(context.Background())
+	resp, err := dumpster.PurgeDumps(context.Background())
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "error deleting backup")
+	assert.Equal(t, &PurgeResponse{Remaining: 3}, resp)
 
 	mockStore.AssertExpectations(t)
 }
@@ -366,25 +482,26 @@ func TestDumpster_Dump_Success(t *testing.T) {
 	// Mock successful database listing
 	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
-	mockCmd.On("WithDir", dumpster.backupLocation).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
 	mockCmd.On("Output").Return([]byte("db1\n"), nil)
 
 	// Mock successful pg_dump
 	mockExec.On("Command", mock.Anything, "pg_dump", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
-	mockCmd.On("WithDir", dumpster.backupLocation).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
 	mockCmd.On("CombinedOutput").Return([]byte(""), nil)
 
 	// Mock successful storage upload
 	mockStore.On("Name").Return("test-storage")
-	mockStore.On("Upload", mock.Anything).Return("backup-2024-01-01.tar.gz", nil)
+	mockStore.On("Upload", mock.Anything, mock.Anything).Return("backup-2024-01-01.tar.gz", nil)
 
 	// Mock successful purge
-	keys := []string{"backup-2024-01-01.tar.gz"}
+	keys := []string{"20240101000000"}
 	mockStore.On("List").Return(keys, nil)
 	mockStore.On("TrimPrefix", keys).Return(keys)
+	mockStore.On("GetObject", mock.Anything, ".stashly/pins/.json").Return(nil, "", storage.ErrNotExist)
 	mockStore.On("Delete", mock.Anything).Return(nil)
 
 	resp, err := dumpster.Dump(context.Background())
@@ -397,9 +514,6 @@ func TestDumpster_Dump_Success(t *testing.T) {
 	mockExec.AssertExpectations(t)
 	mockCmd.AssertExpectations(t)
 	mockStore.AssertExpectations(t)
-
-	// Cleanup
-	_ = os.RemoveAll(dumpster.backupLocation)
 }
 
 func TestDumpster_Dump_CreateDumpError(t *testing.T) {
@@ -440,33 +554,516 @@ func TestDumpster_Dump_PurgeError(t *testing.T) {
 	// Mock successful database listing
 	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
-	mockCmd.On("WithDir", dumpster.backupLocation).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
 	mockCmd.On("Output").Return([]byte("db1\n"), nil)
 
 	// Mock successful pg_dump
 	mockExec.On("Command", mock.Anything, "pg_dump", mock.Anything).Return(mockCmd)
 	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
-	mockCmd.On("WithDir", dumpster.backupLocation).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
 	mockCmd.On("CombinedOutput").Return([]byte(""), nil)
 
 	// Mock successful storage upload
 	mockStore.On("Name").Return("test-storage")
-	mockStore.On("Upload", mock.Anything).Return("backup-2024-01-01.tar.gz", nil)
+	mockStore.On("Upload", mock.Anything, mock.Anything).Return("backup-2024-01-01.tar.gz", nil)
 
 	// Mock failed purge
 	mockStore.On("List").Return(nil, errors.New("storage error"))
 
 	resp, err := dumpster.Dump(context.Background())
 
+	// The backup itself was uploaded
 	require.Error(t, err)
-	require.Nil(t, resp)
+	require.NotNil(t, resp)
+	assert.Equal(t, "backup-2024-01-01.tar.gz", resp.StorageKey)
 	assert.Contains(t, err.Error(), "storage error")
 
 	mockExec.AssertExpectations(t)
 	mockCmd.AssertExpectations(t)
 	mockStore.AssertExpectations(t)
+}
+
+func TestDumpResponse_Partial(t *testing.T) {
+	resp := &DumpResponse{
+		TotalDatabases:    2,
+		ExportedDatabases: 1,
+		Databases: []DatabaseResult{
+			{Name: "db1"},
+			{Name: "db2", Err: errors.New("dump failed")},
+		},
+	}
+
+	assert.True(t, resp.Partial())
+	assert.Equal(t, []string{"db2"}, resp.FailedDatabases())
+}
+
+func TestDumpster_CreateDump_Cancelled(t *testing.T) {
+	cfg := &config.Config{}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Mock successful pre-checks
+	mockExec.On("LookPath", "psql").Return("/usr/bin/psql", nil)
+	mockExec.On("LookPath", "pg_dump").Return("/usr/bin/pg_dump", nil)
+
+	// Database listing succeeds, then the run is cancelled before any pg_dump starts
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+	mockCmd.On("Output").Run(func(mock.Arguments) { cancel() }).Return([]byte("db1\ndb2\n"), nil)
+
+	resp, err := dumpster.CreateDump(ctx)
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, resp)
+	assert.Equal(t, 2, resp.TotalDatabases)
+	assert.Empty(t, resp.Databases, "no database was dumped before the cancellation")
+	assert.NoDirExists(t, dumpster.backupLocation)
+
+	mockExec.AssertNotCalled(t, "Command", mock.Anything, "pg_dump", mock.Anything)
+}
+
+func TestDumpster_CreateDump_DatabaseFilter(t *testing.T) {
+	cfg := &config.Config{
+		Backup: config.BackupConfig{
+			Databases: config.DatabaseFilterConfig{
+				Include: []string{"app_*"},
+				Exclude: []string{"*_test"},
+			},
+		},
+	}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockExec.On("LookPath", "psql").Return("/usr/bin/psql", nil)
+	mockExec.On("LookPath", "pg_dump").Return("/usr/bin/pg_dump", nil)
+
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+	mockCmd.On("Output").Return([]byte("app_prod\napp_test\ncrm\n"), nil)
+
+	// Only app_prod passes the filter
+	mockExec.On("Command", mock.Anything, "pg_dump", mock.MatchedBy(func(args []string) bool {
+		return slices.Contains(args, "--dbname=app_prod")
+	})).Return(mockCmd).Once()
+	mockCmd.On("CombinedOutput").Return([]byte(""), nil)
+
+	mockStore.On("Name").Return("test-storage")
+	mockStore.On("Upload", mock.Anything, mock.Anything).Return("backup.zip", nil)
+
+	resp, err := dumpster.CreateDump(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.TotalDatabases)
+	require.Len(t, resp.Databases, 1)
+	assert.Equal(t, "app_prod", resp.Databases[0].Name)
+
+	mockExec.AssertExpectations(t)
+}
+
+func TestDumpster_CreateDump_PerStageRetry(t *testing.T) {
+	cfg := &config.Config{
+		Backup: config.BackupConfig{
+			Retry: config.RetryConfig{MaxAttempts: 3, InitialDelay: time.Millisecond, Multiplier: 1, PerStage: true},
+		},
+	}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	listCmd := exec.NewMockCmdIface(t)
+	okCmd := exec.NewMockCmdIface(t)
+	failCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockExec.On("LookPath", "psql").Return("/usr/bin/psql", nil)
+	mockExec.On("LookPath", "pg_dump").Return("/usr/bin/pg_dump", nil)
+
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(listCmd)
+	listCmd.On("WithEnv", mock.Anything).Return(listCmd)
+	listCmd.On("WithDir", mock.Anything).Return(listCmd)
+	listCmd.On("WithStderr", os.Stderr).Return(listCmd)
+	listCmd.On("Output").Return([]byte("app\nbilling\n"), nil)
+
+	dbArg := func(db string) interface{} {
+		return mock.MatchedBy(func(args []string) bool { return slices.Contains(args, "--dbname="+db) })
+	}
+	okCmd.On("WithEnv", mock.Anything).Return(okCmd)
+	okCmd.On("WithDir", mock.Anything).Return(okCmd)
+	okCmd.On("CombinedOutput").Return([]byte(""), nil)
+	failCmd.On("WithEnv", mock.Anything).Return(failCmd)
+	failCmd.On("WithDir", mock.Anything).Return(failCmd)
+	failCmd.On("CombinedOutput").Return([]byte("connection refused"), errors.New("exit status 1"))
+
+	// app succeeds at once; billing fails once and is the only database dumped again
+	mockExec.On("Command", mock.Anything, "pg_dump", dbArg("app")).Return(okCmd).Once()
+	mockExec.On("Command", mock.Anything, "pg_dump", dbArg("billing")).Return(failCmd).Once()
+	mockExec.On("Command", mock.Anything, "pg_dump", dbArg("billing")).Return(okCmd).Once()
+
+	// The first upload fails and is retried without dumping again
+	mockStore.On("Name").Return("test-storage")
+	mockStore.On("Upload", mock.Anything, mock.Anything).Return("", errors.New("connection reset")).Once()
+	mockStore.On("Upload", mock.Anything, mock.Anything).Return("backup.zip", nil).Once()
+
+	resp, err := dumpster.CreateDump(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.ExportedDatabases)
+	assert.False(t, resp.Partial())
+	assert.Equal(t, 2, resp.Retries)
+	assert.Equal(t, 2, resp.Databases[1].Attempts)
+	assert.Equal(t, "backup.zip", resp.StorageKey)
+
+	mockExec.AssertExpectations(t)
+	mockStore.AssertExpectations(t)
+}
+
+func TestDumpster_CreateDump_WholeRunRetry(t *testing.T) {
+	cfg := &config.Config{
+		Backup: config.BackupConfig{
+			Retry: config.RetryConfig{MaxAttempts: 2, InitialDelay: time.Millisecond, Multiplier: 1},
+		},
+	}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	listCmd := exec.NewMockCmdIface(t)
+	dumpCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockExec.On("LookPath", "psql").Return("/usr/bin/psql", nil)
+	mockExec.On("LookPath", "pg_dump").Return("/usr/bin/pg_dump", nil)
+
+	// The first attempt cannot list databases; the second one succeeds
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(listCmd)
+	listCmd.On("WithEnv", mock.Anything).Return(listCmd)
+	listCmd.On("WithDir", mock.Anything).Return(listCmd)
+	listCmd.On("WithStderr", os.Stderr).Return(listCmd)
+	listCmd.On("Output").Return([]byte(nil), errors.New("connection refused")).Once()
+	listCmd.On("Output").Return([]byte("app\n"), nil).Once()
+
+	mockExec.On("Command", mock.Anything, "pg_dump", mock.Anything).Return(dumpCmd).Once()
+	dumpCmd.On("WithEnv", mock.Anything).Return(dumpCmd)
+	dumpCmd.On("WithDir", mock.Anything).Return(dumpCmd)
+	dumpCmd.On("CombinedOutput").Return([]byte(""), nil)
+
+	mockStore.On("Name").Return("test-storage")
+	mockStore.On("Upload", mock.Anything, mock.Anything).Return("backup.zip", nil).Once()
+
+	resp, err := dumpster.CreateDump(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Retries)
+	assert.Equal(t, 1, resp.ExportedDatabases)
+
+	mockExec.AssertExpectations(t)
+	listCmd.AssertExpectations(t)
+	mockStore.AssertExpectations(t)
+}
+
+func TestDumpster_CreateDump_RetriesExhausted(t *testing.T) {
+	cfg := &config.Config{
+		Backup: config.BackupConfig{
+			Retry: config.RetryConfig{MaxAttempts: 2, InitialDelay: time.Millisecond, PerStage: true},
+		},
+	}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockExec.On("LookPath", "psql").Return("/usr/bin/psql", nil)
+	mockExec.On("LookPath", "pg_dump").Return("/usr/bin/pg_dump", nil)
+
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+	mockCmd.On("Output").Return([]byte(nil), errors.New("connection refused")).Twice()
+
+	resp, err := dumpster.CreateDump(context.Background())
+
+	require.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Contains(t, err.Error(), "error getting list of databases")
+	mockCmd.AssertExpectations(t)
+}
+
+func TestDumpster_CreateDump_Workspace(t *testing.T) {
+	for _, keepLocal := range []bool{false, true} {
+		t.Run(map[bool]string{false: "removed", true: "kept"}[keepLocal], func(t *testing.T) {
+			cfg := &config.Config{Backup: config.BackupConfig{WorkspaceDir: t.TempDir(), KeepLocal: keepLocal}}
+			mockStore := storage.NewMockStorageIface(t)
+			mockExec := exec.NewMockExecIface(t)
+			mockCmd := exec.NewMockCmdIface(t)
+
+			dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+			mockExec.On("LookPath", mock.Anything).Return("/usr/bin/true", nil)
+			mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
+			mockExec.On("Command", mock.Anything, "pg_dump", mock.Anything).Return(mockCmd)
+			mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+			mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+			mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+			mockCmd.On("Output").Return([]byte("db1|1024\n"), nil)
+			mockCmd.On("CombinedOutput").Return([]byte(""), nil)
+
+			var uploaded string
+			mockStore.On("Name").Return("test-storage")
+			mockStore.On("Upload", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				uploaded = args.String(1)
+			}).Return("20240101000000/db_exports.zip", nil)
+
+			_, err := dumpster.CreateDump(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, "db_exports.zip", filepath.Base(uploaded))
+			assert.Equal(t, dumpster.workspace, filepath.Dir(uploaded))
+			assert.Equal(t, cfg.Backup.WorkspaceDir, filepath.Dir(dumpster.workspace))
+			if keepLocal {
+				assert.FileExists(t, uploaded)
+			} else {
+				assert.NoDirExists(t, dumpster.workspace)
+			}
+		})
+	}
+}
+
+func TestDumpster_CreateDump_WorkspaceRemovedOnFailure(t *testing.T) {
+	cfg := &config.Config{Backup: config.BackupConfig{WorkspaceDir: t.TempDir()}}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockExec.On("LookPath", mock.Anything).Return("/usr/bin/true", nil)
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+	mockCmd.On("Output").Return([]byte(nil), errors.New("connection refused"))
+
+	_, err := dumpster.CreateDump(context.Background())
+	require.Error(t, err)
+
+	entries, rErr := os.ReadDir(cfg.Backup.WorkspaceDir)
+	require.NoError(t, rErr)
+	assert.Empty(t, entries)
+}
+
+func TestDumpster_CreateDump_InsufficientSpace(t *testing.T) {
+	cfg := &config.Config{Backup: config.BackupConfig{WorkspaceDir: t.TempDir(), SpaceCheck: true}}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
 
-	// Cleanup
-	_ = os.RemoveAll(dumpster.backupLocation)
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockExec.On("LookPath", mock.Anything).Return("/usr/bin/true", nil)
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+	// No filesystem has an exabyte free
+	mockCmd.On("Output").Return([]byte("db1|1152921504606846976\n"), nil)
+
+	_, err := dumpster.CreateDump(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "insufficient free space")
+	mockExec.AssertNotCalled(t, "Command", mock.Anything, "pg_dump", mock.Anything)
+}
+
+func TestDumpster_listDatabases_Sizes(t *testing.T) {
+	cfg := &config.Config{Backup: config.BackupConfig{
+		Databases: config.DatabaseFilterConfig{Exclude: []string{"skip"}},
+	}}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+	mockCmd.On("Output").Return([]byte("db1|100\nskip|5000\ndb2|250\n"), nil)
+
+	databases, estimated, err := dumpster.listDatabases(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db1", "db2"}, databases)
+	assert.Equal(t, int64(350), estimated)
+}
+
+func TestDumpster_dumpDatabase_Timeout(t *testing.T) {
+	cfg := &config.Config{Backup: config.BackupConfig{
+		DatabaseTimeout: 20 * time.Millisecond,
+		LockWaitTimeout: 30 * time.Second,
+	}}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+	dumpster.backupLocation = t.TempDir()
+
+	var dumpCtx context.Context
+	var args []string
+	mockExec.On("Command", mock.Anything, "pg_dump", mock.Anything).Run(func(a mock.Arguments) {
+		dumpCtx = a.Get(0).(context.Context)
+		args = a.Get(2).([]string)
+	}).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	// pg_dump hangs waiting for a lock until it is killed
+	mockCmd.On("CombinedOutput").Run(func(mock.Arguments) { <-dumpCtx.Done() }).
+		Return([]byte(nil), errors.New("signal: killed"))
+
+	result := dumpster.dumpDatabase(context.Background(), "ledger", nil)
+	require.ErrorIs(t, result.Err, ErrDatabaseTimeout)
+	assert.True(t, result.TimedOut())
+	assert.Contains(t, args, "--lock-wait-timeout=30000")
+
+	resp := &DumpResponse{Databases: []DatabaseResult{{Name: "app"}, result}}
+	assert.Equal(t, []string{"ledger"}, resp.TimedOutDatabases())
+	assert.Equal(t, []string{"ledger"}, resp.FailedDatabases())
+}
+
+func TestDumpster_dumpDatabase_NoTimeouts(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(&config.Config{}, mockStore, mockExec)
+	dumpster.backupLocation = t.TempDir()
+
+	var args []string
+	mockExec.On("Command", mock.Anything, "pg_dump", mock.Anything).Run(func(a mock.Arguments) {
+		_, hasDeadline := a.Get(0).(context.Context).Deadline()
+		assert.False(t, hasDeadline)
+		args = a.Get(2).([]string)
+	}).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("CombinedOutput").Return([]byte(nil), errors.New("exit status 1"))
+
+	result := dumpster.dumpDatabase(context.Background(), "app", nil)
+	require.Error(t, result.Err)
+	assert.False(t, result.TimedOut())
+	for _, arg := range args {
+		assert.NotContains(t, arg, "--lock-wait-timeout")
+	}
+}
+
+func TestDumpster_CreateDump_RunTimeout(t *testing.T) {
+	cfg := &config.Config{Backup: config.BackupConfig{
+		Timeout: 20 * time.Millisecond,
+		Retry:   config.RetryConfig{MaxAttempts: 3, InitialDelay: time.Millisecond, Multiplier: 1},
+	}}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	var listCtx context.Context
+	mockExec.On("LookPath", mock.Anything).Return("/usr/bin/true", nil)
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Run(func(a mock.Arguments) {
+		listCtx = a.Get(0).(context.Context)
+	}).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+	mockCmd.On("Output").Run(func(mock.Arguments) { <-listCtx.Done() }).Return([]byte(nil), errors.New("signal: killed"))
+
+	_, err := dumpster.CreateDump(context.Background())
+	require.ErrorIs(t, err, ErrTimeout)
+	mockExec.AssertNumberOfCalls(t, "Command", 1)
+}
+
+func TestDumpster_CreateDump_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	cfg := &config.Config{}
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+	failedCmd := exec.NewMockCmdIface(t)
+
+	dumpster := NewDumpster(cfg, mockStore, mockExec)
+
+	mockExec.On("LookPath", mock.Anything).Return("/usr/bin/true", nil)
+	mockExec.On("Command", mock.Anything, "psql", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithDir", mock.Anything).Return(mockCmd)
+	mockCmd.On("WithStderr", os.Stderr).Return(mockCmd)
+	mockCmd.On("Output").Return([]byte("app|10\nbilling|20\n"), nil)
+
+	mockExec.On("Command", mock.Anything, "pg_dump", mock.MatchedBy(func(args []string) bool {
+		return slices.Contains(args, "--dbname=app")
+	})).Return(mockCmd)
+	mockCmd.On("CombinedOutput").Return([]byte(nil), nil)
+	mockExec.On("Command", mock.Anything, "pg_dump", mock.MatchedBy(func(args []string) bool {
+		return slices.Contains(args, "--dbname=billing")
+	})).Return(failedCmd)
+	failedCmd.On("WithEnv", mock.Anything).Return(failedCmd)
+	failedCmd.On("WithDir", mock.Anything).Return(failedCmd)
+	failedCmd.On("CombinedOutput").Return([]byte(nil), errors.New("connection refused"))
+
+	mockStore.On("Name").Return("test-storage")
+	mockStore.On("Upload", mock.Anything, mock.Anything).Return("backup.zip", nil)
+
+	_, err := dumpster.CreateDump(context.Background())
+	require.NoError(t, err)
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	require.Len(t, spans["dumpster.CreateDump"], 1)
+	require.Len(t, spans["dumpster.export"], 1)
+	require.Len(t, spans["archive"], 1)
+	require.Len(t, spans["pg_dump"], 2)
+
+	export := spans["dumpster.export"][0]
+	assert.Equal(t, spans["dumpster.CreateDump"][0].SpanContext().SpanID(), export.Parent().SpanID())
+	for _, span := range spans["pg_dump"] {
+		assert.Equal(t, export.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Contains(t, span.Attributes(), attribute.String("db.namespace", map[codes.Code]string{
+			codes.Unset: "app",
+			codes.Error: "billing",
+		}[span.Status().Code]))
+	}
+}
+
+func TestDumpster_Ping(t *testing.T) {
+	cfg := &config.Config{Postgres: config.PostgresConfig{Host: "db", Port: "5432", User: "postgres"}}
+	mockExec := exec.NewMockExecIface(t)
+	mockCmd := exec.NewMockCmdIface(t)
+	dumpster := NewDumpster(cfg, storage.NewMockStorageIface(t), mockExec)
+
+	mockExec.On("Command", mock.Anything, "psql", []string{"-At", "-c", "SELECT 1"}).Return(mockCmd)
+	mockCmd.On("WithEnv", mock.Anything).Return(mockCmd)
+	mockCmd.On("CombinedOutput").Return([]byte("1\n"), nil).Once()
+	require.NoError(t, dumpster.Ping(context.Background()))
+
+	mockCmd.On("CombinedOutput").Return([]byte("psql: error: connection refused\n"), errors.New("exit status 2")).Once()
+	err := dumpster.Ping(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "error connecting to postgres: exit status 2: psql: error: connection refused", err.Error())
 }
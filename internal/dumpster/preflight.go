@@ -0,0 +1,254 @@
+package dumpster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibare/GoCommon/v2/pkg/crypto/gpg"
+	"github.com/hibare/stashly/internal/storage"
+)
+
+// Names of the preflight checks, in the order they run.
+const (
+	CheckBinaries   = "binaries"
+	CheckPostgres   = "postgres"
+	CheckVersion    = "pg_dump version"
+	CheckDatabases  = "database privileges"
+	CheckStorage    = "storage"
+	CheckGPGKey     = "gpg key"
+	CheckFreeSpace  = "free space"
+	preflightPrefix = "doctor"
+)
+
+// ErrSkipped marks a preflight check that did not run, because it does not apply or an earlier check failed.
+var ErrSkipped = errors.New("skipped")
+
+// pgDumpVersionRe extracts the major version from "pg_dump (PostgreSQL) 16.2".
+var pgDumpVersionRe = regexp.MustCompile(`\(PostgreSQL\) (\d+)`)
+
+// PreflightResult is the outcome of a single preflight check.
+type PreflightResult struct {
+	Name string
+	// Detail describes what was checked or found.
+	Detail string
+	// Err is nil if the check passed, wraps ErrSkipped if it did not run.
+	Err error
+}
+
+// Skipped reports whether the check did not run.
+func (r *PreflightResult) Skipped() bool {
+	return errors.Is(r.Err, ErrSkipped)
+}
+
+// Preflight checks every stage of a backup without dumping anything: the client binaries and their
+// compatibility with the server, the credentials and CONNECT privilege on each selected database,
+// write, read, list and delete permissions in storage, the GPG key and the free space in the workspace.
+func (d *Dumpster) Preflight(ctx context.Context) []PreflightResult {
+	results := []PreflightResult{}
+	add := func(name, detail string, err error) {
+		results = append(results, PreflightResult{Name: name, Detail: detail, Err: err})
+	}
+
+	binErr := d.checkBinaries()
+	add(CheckBinaries, "psql, pg_dump", binErr)
+
+	var (
+		serverMajor int
+		pgErr       error
+		estimated   int64
+	)
+	if binErr != nil {
+		pgErr = fmt.Errorf("%w: client binaries missing", ErrSkipped)
+		add(CheckPostgres, "", pgErr)
+	} else {
+		serverMajor, pgErr = d.serverMajorVersion(ctx)
+		add(CheckPostgres, fmt.Sprintf("%s@%s:%s", d.cfg.Postgres.User, d.cfg.Postgres.Host, d.cfg.Postgres.Port), pgErr)
+	}
+
+	if pgErr != nil {
+		skipped := fmt.Errorf("%w: postgres unavailable", ErrSkipped)
+		add(CheckVersion, "", skipped)
+		add(CheckDatabases, "", skipped)
+	} else {
+		detail, err := d.checkVersion(ctx, serverMajor)
+		add(CheckVersion, detail, err)
+
+		var databases int
+		databases, estimated, err = d.checkDatabases(ctx)
+		add(CheckDatabases, fmt.Sprintf("%d databases selected", databases), err)
+	}
+
+	add(CheckStorage, d.store.Name(), d.checkStorage(ctx))
+
+	if d.cfg.Backup.Encrypt {
+		add(CheckGPGKey, d.cfg.Encryption.GPG.KeyID, d.checkGPGKey())
+	} else {
+		add(CheckGPGKey, "", fmt.Errorf("%w: encryption disabled", ErrSkipped))
+	}
+
+	detail, err := d.checkWorkspace(estimated)
+	add(CheckFreeSpace, detail, err)
+	return results
+}
+
+// checkBinaries fails unless the Postgres client binaries are in PATH.
+func (d *Dumpster) checkBinaries() error {
+	for _, bin := range []string{"psql", "pg_dump"} {
+		if _, err := d.exec.LookPath(bin); err != nil {
+			return fmt.Errorf("%s not found in PATH: %w", bin, err)
+		}
+	}
+	return nil
+}
+
+// query runs a statement with psql and returns its unaligned output.
+func (d *Dumpster) query(ctx context.Context, sql string) (string, error) {
+	out, err := d.exec.Command(ctx, "psql", "-At", "-c", sql).
+		WithEnv(d.getEnvVars()).
+		CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// serverMajorVersion connects to the server and returns its major version.
+func (d *Dumpster) serverMajorVersion(ctx context.Context) (int, error) {
+	out, err := d.query(ctx, "SHOW server_version_num")
+	if err != nil {
+		return 0, fmt.Errorf("error connecting to postgres: %w", err)
+	}
+	num, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected server version %q", strings.TrimSpace(out))
+	}
+	return num / 10000, nil
+}
+
+// checkVersion fails if pg_dump is older than the server, which pg_dump refuses to dump.
+func (d *Dumpster) checkVersion(ctx context.Context, serverMajor int) (string, error) {
+	out, err := d.exec.Command(ctx, "pg_dump", "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error running pg_dump --version: %w", err)
+	}
+	m := pgDumpVersionRe.FindStringSubmatch(string(out))
+	if m == nil {
+		return "", fmt.Errorf("unexpected pg_dump version %q", strings.TrimSpace(string(out)))
+	}
+	clientMajor, _ := strconv.Atoi(m[1])
+
+	detail := fmt.Sprintf("pg_dump %d, server %d", clientMajor, serverMajor)
+	if clientMajor < serverMajor {
+		return detail, fmt.Errorf("pg_dump %d cannot dump a PostgreSQL %d server; install postgresql-client %d or newer",
+			clientMajor, serverMajor, serverMajor)
+	}
+	return detail, nil
+}
+
+// checkDatabases fails if no database is selected or the user lacks the CONNECT privilege on any of them.
+// It returns the number of selected databases and their estimated total size.
+func (d *Dumpster) checkDatabases(ctx context.Context) (int, int64, error) {
+	out, err := d.query(ctx, "SELECT datname, has_database_privilege(datname, 'CONNECT'), pg_database_size(datname) "+
+		"FROM pg_database WHERE datistemplate = false AND datname NOT IN ('postgres','defaultdb');")
+	if err != nil {
+		return 0, 0, fmt.Errorf("error listing databases: %w", err)
+	}
+
+	var (
+		selected  int
+		estimated int64
+		denied    []string
+	)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) != 3 || !d.cfg.Backup.Databases.Match(fields[0]) {
+			continue
+		}
+		selected++
+		if fields[1] != "t" {
+			denied = append(denied, fields[0])
+		}
+		if n, pErr := strconv.ParseInt(fields[2], 10, 64); pErr == nil {
+			estimated += n
+		}
+	}
+
+	switch {
+	case selected == 0:
+		return 0, 0, errors.New("no databases match the database filters")
+	case len(denied) > 0:
+		return selected, estimated, fmt.Errorf("no CONNECT privilege on %s", strings.Join(denied, ", "))
+	default:
+		return selected, estimated, nil
+	}
+}
+
+// checkStorage writes, reads, lists and deletes a probe object in storage.
+func (d *Dumpster) checkStorage(ctx context.Context) error {
+	if err := d.store.Init(); err != nil {
+		return fmt.Errorf("init: %w", err)
+	}
+
+	key := path.Join(storage.MetadataDir, preflightPrefix, d.cfg.App.InstanceID+".probe")
+	data := []byte("stashly doctor " + time.Now().UTC().Format(time.RFC3339))
+	if _, err := d.store.PutObject(ctx, key, data, storage.PutOptions{}); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	// The probe is removed even if a later step fails
+	defer func() { _ = d.store.DeleteObject(context.WithoutCancel(ctx), key, storage.DeleteOptions{}) }()
+
+	got, _, err := d.store.GetObject(ctx, key)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	if string(got) != string(data) {
+		return errors.New("read: probe object content does not match")
+	}
+	if _, err = d.store.List(); err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if err = d.store.DeleteObject(ctx, key, storage.DeleteOptions{}); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+// checkGPGKey downloads the public key used to encrypt backups.
+func (d *Dumpster) checkGPGKey() error {
+	key, err := gpg.DownloadGPGPubKey(d.cfg.Encryption.GPG.KeyID, d.cfg.Encryption.GPG.KeyServer)
+	if err != nil {
+		return fmt.Errorf("error downloading gpg key from %s: %w", d.cfg.Encryption.GPG.KeyServer, err)
+	}
+	_ = os.Remove(key.PublicKeyPath)
+	return nil
+}
+
+// checkWorkspace fails if the workspace directory cannot be created or lacks space for the estimated dumps.
+func (d *Dumpster) checkWorkspace(estimated int64) (string, error) {
+	if err := os.MkdirAll(d.workspaceDir, 0750); err != nil {
+		return d.workspaceDir, fmt.Errorf("error creating workspace directory: %w", err)
+	}
+	free, err := freeSpace(d.workspaceDir)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return d.workspaceDir, fmt.Errorf("%w: not supported on this platform", ErrSkipped)
+	}
+	if err != nil {
+		return d.workspaceDir, err
+	}
+
+	detail := fmt.Sprintf("%s: %d bytes free, databases estimated at %d bytes", d.workspaceDir, free, estimated)
+	if free < uint64(estimated) { //nolint:gosec // estimated is never negative
+		return detail, errors.New("insufficient free space for the estimated dumps")
+	}
+	return detail, nil
+}
@@ -0,0 +1,125 @@
+package dumpster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/exec"
+	"github.com/hibare/stashly/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const probeKey = ".stashly/doctor/test.probe"
+
+func preflightConfig(t *testing.T) *config.Config {
+	t.Helper()
+	return &config.Config{
+		App:      config.AppConfig{InstanceID: "test"},
+		Postgres: config.PostgresConfig{Host: "db", Port: "5432", User: "postgres"},
+		Backup:   config.BackupConfig{WorkspaceDir: t.TempDir()},
+	}
+}
+
+// mockPostgres answers the psql and pg_dump invocations of a preflight.
+func mockPostgres(mockExec *exec.MockExecIface, t *testing.T, serverVersion, pgDumpVersion, databases string) {
+	t.Helper()
+	mockExec.On("LookPath", "psql").Return("/usr/bin/psql", nil)
+	mockExec.On("LookPath", "pg_dump").Return("/usr/bin/pg_dump", nil)
+
+	versionCmd := exec.NewMockCmdIface(t)
+	mockExec.On("Command", mock.Anything, "psql", []string{"-At", "-c", "SHOW server_version_num"}).Return(versionCmd)
+	versionCmd.On("WithEnv", mock.Anything).Return(versionCmd)
+	versionCmd.On("CombinedOutput").Return([]byte(serverVersion+"\n"), nil)
+
+	pgDumpCmd := exec.NewMockCmdIface(t)
+	mockExec.On("Command", mock.Anything, "pg_dump", []string{"--version"}).Return(pgDumpCmd)
+	pgDumpCmd.On("CombinedOutput").Return([]byte(pgDumpVersion+"\n"), nil)
+
+	dbCmd := exec.NewMockCmdIface(t)
+	mockExec.On("Command", mock.Anything, "psql", mock.MatchedBy(func(args []string) bool {
+		return len(args) == 3 && args[2] != "SHOW server_version_num"
+	})).Return(dbCmd)
+	dbCmd.On("WithEnv", mock.Anything).Return(dbCmd)
+	dbCmd.On("CombinedOutput").Return([]byte(databases), nil)
+}
+
+// mockProbe makes the store read back whatever the probe wrote.
+func mockProbe(mockStore *storage.MockStorageIface) {
+	mockStore.On("Init").Return(nil)
+	mockStore.On("Name").Return("s3 (bucket)")
+	get := mockStore.On("GetObject", mock.Anything, probeKey)
+	mockStore.On("PutObject", mock.Anything, probeKey, mock.Anything, storage.PutOptions{}).
+		Run(func(args mock.Arguments) { get.ReturnArguments = mock.Arguments{args.Get(2), "etag", nil} }).
+		Return("", nil)
+	mockStore.On("List").Return([]string{}, nil)
+	mockStore.On("DeleteObject", mock.Anything, probeKey, storage.DeleteOptions{}).Return(nil)
+}
+
+func resultsByName(results []PreflightResult) map[string]PreflightResult {
+	byName := map[string]PreflightResult{}
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+	return byName
+}
+
+func TestDumpster_Preflight_Success(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockPostgres(mockExec, t, "160002", "pg_dump (PostgreSQL) 17.1", "app|t|1024\nanalytics|t|2048\n")
+	mockProbe(mockStore)
+
+	results := NewDumpster(preflightConfig(t), mockStore, mockExec).Preflight(context.Background())
+
+	names := []string{}
+	for _, r := range results {
+		names = append(names, r.Name)
+		if r.Name == CheckGPGKey {
+			assert.True(t, r.Skipped())
+			continue
+		}
+		require.NoError(t, r.Err, r.Name)
+	}
+	assert.Equal(t, []string{CheckBinaries, CheckPostgres, CheckVersion, CheckDatabases, CheckStorage, CheckGPGKey, CheckFreeSpace}, names)
+
+	byName := resultsByName(results)
+	assert.Equal(t, "pg_dump 17, server 16", byName[CheckVersion].Detail)
+	assert.Equal(t, "2 databases selected", byName[CheckDatabases].Detail)
+	assert.Contains(t, byName[CheckFreeSpace].Detail, "databases estimated at 3072 bytes")
+}
+
+func TestDumpster_Preflight_Failures(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockPostgres(mockExec, t, "170000", "pg_dump (PostgreSQL) 15.4", "app|t|1024\nreporting|f|2048\n")
+	mockStore.On("Init").Return(nil)
+	mockStore.On("Name").Return("s3 (bucket)")
+	mockStore.On("PutObject", mock.Anything, probeKey, mock.Anything, storage.PutOptions{}).Return("", errors.New("access denied"))
+
+	byName := resultsByName(NewDumpster(preflightConfig(t), mockStore, mockExec).Preflight(context.Background()))
+
+	require.EqualError(t, byName[CheckVersion].Err,
+		"pg_dump 15 cannot dump a PostgreSQL 17 server; install postgresql-client 17 or newer")
+	require.EqualError(t, byName[CheckDatabases].Err, "no CONNECT privilege on reporting")
+	require.EqualError(t, byName[CheckStorage].Err, "write: access denied")
+}
+
+func TestDumpster_Preflight_BinariesMissing(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	mockExec := exec.NewMockExecIface(t)
+	mockExec.On("LookPath", "psql").Return("", errors.New("binary not found"))
+	mockProbe(mockStore)
+
+	byName := resultsByName(NewDumpster(preflightConfig(t), mockStore, mockExec).Preflight(context.Background()))
+
+	require.ErrorContains(t, byName[CheckBinaries].Err, "psql not found in PATH")
+	for _, name := range []string{CheckPostgres, CheckVersion, CheckDatabases} {
+		r := byName[name]
+		assert.True(t, r.Skipped(), name)
+	}
+	require.NoError(t, byName[CheckStorage].Err)
+}
@@ -0,0 +1,210 @@
+// Package events defines the backup lifecycle events delivered to notifiers.
+package events
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibare/stashly/internal/dumpster"
+)
+
+// Type identifies the kind of backup lifecycle event.
+type Type string
+
+const (
+	// TypeStarted is emitted when a backup run begins.
+	TypeStarted Type = "started"
+
+	// TypeSuccess is emitted when every discovered database was dumped and uploaded.
+	TypeSuccess Type = "success"
+
+	// TypePartial is emitted when a backup was uploaded but some databases failed to dump.
+	TypePartial Type = "partial"
+
+	// TypeFailure is emitted when a backup run fails.
+	TypeFailure Type = "failure"
+
+	// TypeCancelled is emitted when a running backup is cancelled, e.g. during shutdown.
+	TypeCancelled Type = "cancelled"
+
+	// TypeSkipped is emitted when a scheduled backup is skipped because a blackout window is active.
+	TypeSkipped Type = "skipped"
+
+	// TypeDeferred is emitted when a scheduled backup is deferred until a blackout window ends.
+	TypeDeferred Type = "deferred"
+
+	// TypePurgeSuccess is emitted when old backups were purged according to the retention policy.
+	TypePurgeSuccess Type = "purge-success"
+
+	// TypePurgeFailure is emitted when purging old backups fails.
+	TypePurgeFailure Type = "purge-failure"
+
+	// TypeVerifyFailure is emitted when an uploaded backup fails verification.
+	TypeVerifyFailure Type = "verify-failure"
+
+	// TypeTest is sent by the doctor command to check that a notifier delivers messages.
+	// It is delivered regardless of the notifier's subscriptions.
+	TypeTest Type = "test"
+)
+
+// Types lists all known event types.
+var Types = []Type{
+	TypeStarted,
+	TypeSuccess,
+	TypePartial,
+	TypeFailure,
+	TypeCancelled,
+	TypeSkipped,
+	TypeDeferred,
+	TypePurgeSuccess,
+	TypePurgeFailure,
+	TypeVerifyFailure,
+}
+
+// DefaultTypes lists the event types delivered to notifiers unless configured otherwise.
+var DefaultTypes = []Type{
+	TypeSuccess,
+	TypePartial,
+	TypeFailure,
+	TypeCancelled,
+	TypeSkipped,
+	TypeDeferred,
+	TypePurgeFailure,
+	TypeVerifyFailure,
+}
+
+var titles = map[Type]string{
+	TypeStarted:       "PG-DB Backup Started",
+	TypeSuccess:       "PG-DB Backup Successful",
+	TypePartial:       "PG-DB Backup Partially Successful",
+	TypeFailure:       "PG-DB Backup Failed",
+	TypeCancelled:     "PG-DB Backup Cancelled",
+	TypeSkipped:       "PG-DB Backup Skipped",
+	TypeDeferred:      "PG-DB Backup Deferred",
+	TypePurgeSuccess:  "PG-DB Backup Purge Successful",
+	TypePurgeFailure:  "PG-DB Backup Deletion Failed",
+	TypeVerifyFailure: "PG-DB Backup Verification Failed",
+	TypeTest:          "PG-DB Backup Test Notification",
+}
+
+// Title returns a human-readable heading for the event type.
+func (t Type) Title() string {
+	if title, ok := titles[t]; ok {
+		return title
+	}
+	return "PG-DB Backup " + string(t)
+}
+
+// ParseType parses an event type name.
+func ParseType(s string) (Type, error) {
+	t := Type(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := titles[t]; !ok {
+		return "", fmt.Errorf("unknown event type %q", s)
+	}
+	return t, nil
+}
+
+// Event describes something that happened during a backup run.
+type Event struct {
+	Type      Type
+	Timestamp time.Time
+	RunID     string
+	Job       string
+	Dump      *dumpster.DumpResponse
+	Err       error
+
+	// Window is the blackout window that skipped or deferred the run, active until Until.
+	Window string
+	Until  time.Time
+}
+
+// New creates an event of the given type stamped with the current time.
+func New(t Type, runID string, dump *dumpster.DumpResponse, err error) Event {
+	return Event{
+		Type:      t,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Dump:      dump,
+		Err:       err,
+	}
+}
+
+// Field is a labelled value describing an event; notifiers render fields in their native format.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields returns the details of the event worth reporting, in display order.
+func (e Event) Fields() []Field {
+	fields := []Field{}
+	if e.Job != "" {
+		fields = append(fields, Field{Name: "Job", Value: e.Job})
+	}
+	if e.RunID != "" {
+		fields = append(fields, Field{Name: "Run ID", Value: e.RunID})
+	}
+
+	if d := e.Dump; d != nil {
+		if d.StorageKey != "" {
+			fields = append(fields, Field{Name: "Key", Value: d.StorageKey})
+		}
+		fields = append(fields, Field{
+			Name:  "Databases",
+			Value: strconv.Itoa(d.ExportedDatabases) + "/" + strconv.Itoa(d.TotalDatabases),
+		})
+		// Timed-out databases are listed apart from other failures
+		failed := []string{}
+		for _, db := range d.Databases {
+			if db.Err != nil && !db.TimedOut() {
+				failed = append(failed, db.Name)
+			}
+		}
+		if len(failed) > 0 {
+			fields = append(fields, Field{Name: "Failed Databases", Value: strings.Join(failed, ", ")})
+		}
+		if timedOut := d.TimedOutDatabases(); len(timedOut) > 0 {
+			fields = append(fields, Field{Name: "Timed Out Databases", Value: strings.Join(timedOut, ", ")})
+		}
+		if d.Duration > 0 {
+			fields = append(fields, Field{Name: "Duration", Value: d.Duration.Round(time.Second).String()})
+		}
+		if d.Retries > 0 {
+			fields = append(fields, Field{Name: "Retries", Value: strconv.Itoa(d.Retries)})
+		}
+		if d.ArchiveSize > 0 {
+			fields = append(fields, Field{Name: "Size", Value: FormatBytes(d.ArchiveSize)})
+		}
+		if d.Destination != "" {
+			fields = append(fields, Field{Name: "Destination", Value: d.Destination})
+		}
+	}
+
+	if e.Window != "" {
+		fields = append(fields, Field{Name: "Blackout", Value: e.Window})
+	}
+	if !e.Until.IsZero() {
+		fields = append(fields, Field{Name: "Until", Value: e.Until.Format(time.RFC3339)})
+	}
+
+	if e.Err != nil {
+		fields = append(fields, Field{Name: "Error", Value: e.Err.Error()})
+	}
+	return fields
+}
+
+// FormatBytes formats a byte count using binary units (KiB, MiB, ...).
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
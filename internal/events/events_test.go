@@ -0,0 +1,106 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseType(t *testing.T) {
+	for _, typ := range Types {
+		parsed, err := ParseType(string(typ))
+		require.NoError(t, err)
+		assert.Equal(t, typ, parsed)
+	}
+
+	parsed, err := ParseType(" Purge-Failure ")
+	require.NoError(t, err)
+	assert.Equal(t, TypePurgeFailure, parsed)
+
+	_, err = ParseType("exploded")
+	require.Error(t, err)
+}
+
+func TestEvent_Fields(t *testing.T) {
+	dump := &dumpster.DumpResponse{
+		TotalDatabases:    3,
+		ExportedDatabases: 2,
+		Databases: []dumpster.DatabaseResult{
+			{Name: "app"},
+			{Name: "billing", Err: errors.New("connection refused")},
+			{Name: "crm"},
+		},
+		StorageKey:  "backups/host/20240101000000/db_exports.zip",
+		ArchiveSize: 3 * 1024 * 1024,
+		Destination: "s3 (bucket)",
+		Duration:    2*time.Minute + 400*time.Millisecond,
+		Retries:     1,
+	}
+
+	ev := New(TypePartial, "run-1", dump, nil)
+	assert.Equal(t, []Field{
+		{Name: "Run ID", Value: "run-1"},
+		{Name: "Key", Value: "backups/host/20240101000000/db_exports.zip"},
+		{Name: "Databases", Value: "2/3"},
+		{Name: "Failed Databases", Value: "billing"},
+		{Name: "Duration", Value: "2m0s"},
+		{Name: "Retries", Value: "1"},
+		{Name: "Size", Value: "3.0 MiB"},
+		{Name: "Destination", Value: "s3 (bucket)"},
+	}, ev.Fields())
+}
+
+func TestEvent_FieldsTimedOut(t *testing.T) {
+	dump := &dumpster.DumpResponse{
+		TotalDatabases:    3,
+		ExportedDatabases: 1,
+		Databases: []dumpster.DatabaseResult{
+			{Name: "app"},
+			{Name: "billing", Err: errors.New("connection refused")},
+			{Name: "ledger", Err: fmt.Errorf("%w after 30m0s", dumpster.ErrDatabaseTimeout)},
+		},
+	}
+
+	ev := New(TypePartial, "run-1", dump, nil)
+	assert.Equal(t, []Field{
+		{Name: "Run ID", Value: "run-1"},
+		{Name: "Databases", Value: "1/3"},
+		{Name: "Failed Databases", Value: "billing"},
+		{Name: "Timed Out Databases", Value: "ledger"},
+	}, ev.Fields())
+}
+
+func TestEvent_FieldsWithError(t *testing.T) {
+	ev := New(TypeFailure, "run-2", nil, errors.New("boom"))
+	ev.Job = "orders"
+	assert.Equal(t, []Field{
+		{Name: "Job", Value: "orders"},
+		{Name: "Run ID", Value: "run-2"},
+		{Name: "Error", Value: "boom"},
+	}, ev.Fields())
+	assert.Equal(t, "PG-DB Backup Failed", ev.Type.Title())
+}
+
+func TestEvent_FieldsBlackout(t *testing.T) {
+	ev := New(TypeDeferred, "", nil, nil)
+	ev.Job = "finance"
+	ev.Window = "month-end-close"
+	ev.Until = time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, []Field{
+		{Name: "Job", Value: "finance"},
+		{Name: "Blackout", Value: "month-end-close"},
+		{Name: "Until", Value: "2024-01-31T23:00:00Z"},
+	}, ev.Fields())
+	assert.Equal(t, "PG-DB Backup Deferred", ev.Type.Title())
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", FormatBytes(512))
+	assert.Equal(t, "1.5 KiB", FormatBytes(1536))
+	assert.Equal(t, "2.0 GiB", FormatBytes(2*1024*1024*1024))
+}
@@ -5,8 +5,13 @@ import (
 	"context"
 	"os"
 	"os/exec"
+	"time"
 )
 
+// waitDelay bounds how long a command may hold its output open after its context is done and the
+// process was killed, so a cancelled or timed-out command never blocks its caller.
+const waitDelay = 10 * time.Second
+
 // ExecIface defines the interface for building and running commands.
 // revive:disable-next-line exported
 type ExecIface interface {
@@ -33,6 +38,7 @@ type Exec struct{}
 func (Exec) Command(ctx context.Context, name string, args ...string) CmdIface {
 	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Env = os.Environ() // default to system env
+	cmd.WaitDelay = waitDelay
 	return &Cmd{cmd: cmd}
 }
 
@@ -0,0 +1,139 @@
+// Package health serves liveness and readiness probes and checks them from the healthcheck command.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds how long a single check may take before it is reported as failed.
+const checkTimeout = 10 * time.Second
+
+// Statuses reported for the probe and each check.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check reports an error if the component it probes is unhealthy.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Response is the JSON body of a probe.
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Handler runs the checks concurrently on every request. It responds 200 if all pass and
+// 503 otherwise, with the outcome of each check in the body.
+func Handler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := Run(r.Context(), checks...)
+		code := http.StatusOK
+		if resp.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// Run runs the checks concurrently and collects their outcomes.
+func Run(ctx context.Context, checks ...Check) Response {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	resp := Response{Status: StatusOK, Checks: make(map[string]string, len(checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := StatusOK
+			if err := c.Check(ctx); err != nil {
+				result = fmt.Sprintf("%s: %s", StatusFail, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[c.Name] = result
+			if result != StatusOK {
+				resp.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return resp
+}
+
+// ErrUnhealthy is returned by Probe when the endpoint reports a failure.
+var ErrUnhealthy = errors.New("unhealthy")
+
+// Probe requests url and returns the reported checks, along with ErrUnhealthy unless the status is 200.
+func Probe(ctx context.Context, url string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	var resp Response
+	if dErr := json.NewDecoder(httpResp.Body).Decode(&resp); dErr != nil && httpResp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("error decoding response: %w", dErr)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return &resp, fmt.Errorf("%w: %s", ErrUnhealthy, httpResp.Status)
+	}
+	return &resp, nil
+}
+
+// LocalURL returns the URL of path on the listen address, with an unspecified host replaced by localhost,
+// so ":9090" becomes "http://127.0.0.1:9090/healthz".
+func LocalURL(listen, path string) (string, error) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %q: %w", listen, err)
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port) + path, nil
+}
+
+// MaxAge returns a check failing if the time returned by last is older than maxAge. A zero time,
+// meaning nothing has happened yet, is measured from since instead.
+func MaxAge(maxAge time.Duration, since time.Time, last func(ctx context.Context) (time.Time, error)) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		t, err := last(ctx)
+		if err != nil {
+			return err
+		}
+		if t.IsZero() {
+			if age := time.Since(since); age > maxAge {
+				return fmt.Errorf("no successful backup in %s", age.Round(time.Second))
+			}
+			return nil
+		}
+		if age := time.Since(t); age > maxAge {
+			return fmt.Errorf("last successful backup is %s old (max %s)", age.Round(time.Second), maxAge)
+		}
+		return nil
+	}
+}
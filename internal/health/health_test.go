@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(context.Context) error { return nil }
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler(
+		Check{Name: "scheduler", Check: ok},
+		Check{Name: "postgres", Check: func(context.Context) error { return errors.New("connection refused") }},
+	))
+	defer srv.Close()
+
+	resp, err := Probe(t.Context(), srv.URL)
+	require.ErrorIs(t, err, ErrUnhealthy)
+	assert.Equal(t, StatusFail, resp.Status)
+	assert.Equal(t, map[string]string{
+		"scheduler": StatusOK,
+		"postgres":  "fail: connection refused",
+	}, resp.Checks)
+}
+
+func TestHandler_Healthy(t *testing.T) {
+	srv := httptest.NewServer(Handler(Check{Name: "scheduler", Check: ok}))
+	defer srv.Close()
+
+	resp, err := Probe(t.Context(), srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, StatusOK, resp.Status)
+
+	rec := httptest.NewRecorder()
+	Handler(Check{Name: "scheduler", Check: ok}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"status":"ok","checks":{"scheduler":"ok"}}`, rec.Body.String())
+}
+
+func TestLocalURL(t *testing.T) {
+	tests := map[string]string{
+		":9090":          "http://127.0.0.1:9090/healthz",
+		"0.0.0.0:9090":   "http://127.0.0.1:9090/healthz",
+		"[::]:9090":      "http://127.0.0.1:9090/healthz",
+		"10.0.0.5:9100":  "http://10.0.0.5:9100/healthz",
+		"localhost:9090": "http://localhost:9090/healthz",
+	}
+	for listen, want := range tests {
+		got, err := LocalURL(listen, "/healthz")
+		require.NoError(t, err, listen)
+		assert.Equal(t, want, got, listen)
+	}
+
+	_, err := LocalURL("9090", "/healthz")
+	require.Error(t, err)
+}
+
+func TestMaxAge(t *testing.T) {
+	at := func(ts time.Time) func(context.Context) (time.Time, error) {
+		return func(context.Context) (time.Time, error) { return ts, nil }
+	}
+	now := time.Now()
+
+	require.NoError(t, MaxAge(time.Hour, now, at(now.Add(-30*time.Minute)))(t.Context()))
+	require.ErrorContains(t, MaxAge(time.Hour, now, at(now.Add(-2*time.Hour)))(t.Context()),
+		"last successful backup is 2h0m0s old (max 1h0m0s)")
+
+	// Without any backup the age is measured from the start of the daemon.
+	require.NoError(t, MaxAge(time.Hour, now.Add(-time.Minute), at(time.Time{}))(t.Context()))
+	require.ErrorContains(t, MaxAge(time.Hour, now.Add(-2*time.Hour), at(time.Time{}))(t.Context()),
+		"no successful backup in 2h0m0s")
+
+	require.ErrorIs(t, MaxAge(time.Hour, now, func(context.Context) (time.Time, error) {
+		return time.Time{}, assert.AnError
+	})(t.Context()), assert.AnError)
+}
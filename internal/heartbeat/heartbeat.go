@@ -0,0 +1,173 @@
+// Package heartbeat sends dead-man's-switch pings to external monitors such as
+// healthchecks.io, Uptime Kuma and Cronitor so that missing backups raise alerts.
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hibare/stashly/internal/config"
+)
+
+// Supported heartbeat providers.
+const (
+	ProviderHealthchecks = "healthchecks"
+	ProviderUptimeKuma   = "uptime-kuma"
+	ProviderCronitor     = "cronitor"
+)
+
+// maxMessageLength limits log text sent as a query parameter.
+const maxMessageLength = 1000
+
+// ErrUnknownProvider is returned when the configured provider is not supported.
+var ErrUnknownProvider = errors.New("unknown heartbeat provider")
+
+// state is a point in the run lifecycle reported to the monitor.
+type state int
+
+const (
+	stateStart state = iota
+	stateSuccess
+	stateFail
+)
+
+// Pinger pings the configured monitor URL.
+type Pinger struct {
+	cfg    config.HeartbeatConfig
+	client *http.Client
+}
+
+// NewPinger creates a Pinger for the heartbeat configuration.
+func NewPinger(cfg config.HeartbeatConfig) *Pinger {
+	return &Pinger{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Start reports that a run has started.
+func (p *Pinger) Start(ctx context.Context, runID string) error {
+	return p.ping(ctx, stateStart, runID, "")
+}
+
+// Success reports that a run has completed successfully.
+func (p *Pinger) Success(ctx context.Context, runID, log string) error {
+	return p.ping(ctx, stateSuccess, runID, log)
+}
+
+// Fail reports that a run has failed.
+func (p *Pinger) Fail(ctx context.Context, runID, log string) error {
+	return p.ping(ctx, stateFail, runID, log)
+}
+
+func (p *Pinger) ping(ctx context.Context, s state, runID, log string) error {
+	if !p.cfg.Enabled {
+		return nil
+	}
+
+	req, err := p.request(ctx, s, runID, log)
+	if err != nil {
+		return err
+	}
+	if req == nil {
+		return nil
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send heartbeat: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected heartbeat status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// request builds the provider specific ping request; it returns nil when the provider has no equivalent for the state.
+func (p *Pinger) request(ctx context.Context, s state, runID, log string) (*http.Request, error) {
+	base, err := url.Parse(p.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid heartbeat url: %w", err)
+	}
+
+	switch p.cfg.Provider {
+	case ProviderHealthchecks, "":
+		// https://healthchecks.io/docs/http_api/
+		switch s {
+		case stateStart:
+			base = base.JoinPath("start")
+		case stateFail:
+			base = base.JoinPath("fail")
+		case stateSuccess:
+		}
+		if runID != "" {
+			q := base.Query()
+			q.Set("rid", runID)
+			base.RawQuery = q.Encode()
+		}
+		return http.NewRequestWithContext(ctx, http.MethodPost, base.String(), strings.NewReader(log))
+
+	case ProviderUptimeKuma:
+		// Push monitors only understand up/down.
+		if s == stateStart {
+			return nil, nil //nolint:nilnil // no start ping for push monitors
+		}
+		q := base.Query()
+		if s == stateFail {
+			q.Set("status", "down")
+			q.Set("msg", lastLine(log))
+		} else {
+			q.Set("status", "up")
+			q.Set("msg", "OK")
+		}
+		base.RawQuery = q.Encode()
+		return http.NewRequestWithContext(ctx, http.MethodGet, base.String(), nil)
+
+	case ProviderCronitor:
+		// https://cronitor.io/docs/telemetry-api
+		q := base.Query()
+		switch s {
+		case stateStart:
+			q.Set("state", "run")
+		case stateSuccess:
+			q.Set("state", "complete")
+		case stateFail:
+			q.Set("state", "fail")
+		}
+		if runID != "" {
+			q.Set("series", runID)
+		}
+		if log != "" {
+			q.Set("message", truncate(log))
+		}
+		base.RawQuery = q.Encode()
+		return http.NewRequestWithContext(ctx, http.MethodGet, base.String(), nil)
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, p.cfg.Provider)
+	}
+}
+
+// truncate keeps the end of the log, which usually holds the error, within maxMessageLength.
+func truncate(log string) string {
+	if len(log) <= maxMessageLength {
+		return log
+	}
+	return log[len(log)-maxMessageLength:]
+}
+
+func lastLine(log string) string {
+	log = strings.TrimSpace(log)
+	if i := strings.LastIndex(log, "\n"); i >= 0 {
+		log = log[i+1:]
+	}
+	return truncate(log)
+}
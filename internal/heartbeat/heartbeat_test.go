@@ -0,0 +1,129 @@
+package heartbeat
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ping struct {
+	method string
+	path   string
+	query  map[string]string
+	body   string
+}
+
+func newRecordingServer(t *testing.T) (*httptest.Server, func() []ping) {
+	t.Helper()
+	var mu sync.Mutex
+	var pings []ping
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := map[string]string{}
+		for k := range r.URL.Query() {
+			query[k] = r.URL.Query().Get(k)
+		}
+		mu.Lock()
+		pings = append(pings, ping{method: r.Method, path: r.URL.Path, query: query, body: string(body)})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []ping {
+		mu.Lock()
+		defer mu.Unlock()
+		return pings
+	}
+}
+
+func newTestPinger(provider, url string) *Pinger {
+	return NewPinger(config.HeartbeatConfig{
+		Enabled:  true,
+		Provider: provider,
+		URL:      url,
+		Timeout:  time.Second,
+	})
+}
+
+func TestPinger_Healthchecks(t *testing.T) {
+	server, pings := newRecordingServer(t)
+	p := newTestPinger(ProviderHealthchecks, server.URL+"/ping/abc")
+
+	ctx := t.Context()
+	require.NoError(t, p.Start(ctx, "run-1"))
+	require.NoError(t, p.Success(ctx, "run-1", "all good"))
+	require.NoError(t, p.Fail(ctx, "run-1", "line 1\nboom"))
+
+	got := pings()
+	require.Len(t, got, 3)
+	assert.Equal(t, "/ping/abc/start", got[0].path)
+	assert.Equal(t, "run-1", got[0].query["rid"])
+	assert.Equal(t, "/ping/abc", got[1].path)
+	assert.Equal(t, "all good", got[1].body)
+	assert.Equal(t, "/ping/abc/fail", got[2].path)
+	assert.Equal(t, http.MethodPost, got[2].method)
+	assert.Equal(t, "line 1\nboom", got[2].body)
+}
+
+func TestPinger_UptimeKuma(t *testing.T) {
+	server, pings := newRecordingServer(t)
+	p := newTestPinger(ProviderUptimeKuma, server.URL+"/api/push/token")
+
+	ctx := t.Context()
+	require.NoError(t, p.Start(ctx, "run-1"))
+	require.NoError(t, p.Success(ctx, "run-1", "all good"))
+	require.NoError(t, p.Fail(ctx, "run-1", "line 1\npg_dump failed"))
+
+	got := pings()
+	require.Len(t, got, 2, "start is not reported to push monitors")
+	assert.Equal(t, "up", got[0].query["status"])
+	assert.Equal(t, "down", got[1].query["status"])
+	assert.Equal(t, "pg_dump failed", got[1].query["msg"])
+}
+
+func TestPinger_Cronitor(t *testing.T) {
+	server, pings := newRecordingServer(t)
+	p := newTestPinger(ProviderCronitor, server.URL+"/p/key/stashly")
+
+	ctx := t.Context()
+	require.NoError(t, p.Start(ctx, "run-1"))
+	require.NoError(t, p.Fail(ctx, "run-1", strings.Repeat("x", 2*maxMessageLength)))
+
+	got := pings()
+	require.Len(t, got, 2)
+	assert.Equal(t, "run", got[0].query["state"])
+	assert.Equal(t, "run-1", got[0].query["series"])
+	assert.Equal(t, "fail", got[1].query["state"])
+	assert.Len(t, got[1].query["message"], maxMessageLength)
+}
+
+func TestPinger_Disabled(t *testing.T) {
+	server, pings := newRecordingServer(t)
+	p := NewPinger(config.HeartbeatConfig{URL: server.URL})
+
+	require.NoError(t, p.Start(t.Context(), "run-1"))
+	assert.Empty(t, pings())
+}
+
+func TestPinger_Errors(t *testing.T) {
+	p := newTestPinger("carrier-pigeon", "https://example.com")
+	require.ErrorIs(t, p.Start(t.Context(), "run-1"), ErrUnknownProvider)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	p = newTestPinger(ProviderHealthchecks, server.URL)
+	err := p.Success(t.Context(), "run-1", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected heartbeat status code: 404")
+}
@@ -0,0 +1,236 @@
+// Package history records completed backup runs in a local JSON-lines file and, optionally,
+// as objects in the storage backend.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/storage"
+)
+
+// Status is the outcome of a run.
+type Status string
+
+// Run statuses.
+const (
+	StatusSuccess   Status = "success"
+	StatusPartial   Status = "partial"
+	StatusFailure   Status = "failure"
+	StatusCancelled Status = "cancelled"
+	StatusSkipped   Status = "skipped"
+)
+
+// Database statuses.
+const (
+	DatabaseOK       = "ok"
+	DatabaseFailed   = "failed"
+	DatabaseTimedOut = "timed-out"
+)
+
+// DatabaseEntry is the outcome of dumping a single database.
+type DatabaseEntry struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Bytes  int64  `json:"bytes,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Entry describes a single backup run.
+type Entry struct {
+	RunID      string          `json:"run_id,omitempty"`
+	Job        string          `json:"job"`
+	Instance   string          `json:"instance,omitempty"`
+	Trigger    string          `json:"trigger"`
+	Status     Status          `json:"status"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Databases  []DatabaseEntry `json:"databases,omitempty"`
+	Bytes      int64           `json:"bytes,omitempty"`
+	Key        string          `json:"key,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// Failed reports whether the run was attempted and did not fully succeed. Skipped runs, which were
+// never attempted, are not failures.
+func (e *Entry) Failed() bool {
+	switch e.Status {
+	case StatusPartial, StatusFailure, StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Duration returns how long the run took.
+func (e *Entry) Duration() time.Duration {
+	return e.FinishedAt.Sub(e.StartedAt)
+}
+
+// SetDump records the details of a completed dump: per-database results, archive size and key.
+func (e *Entry) SetDump(dump *dumpster.DumpResponse) {
+	e.Databases = make([]DatabaseEntry, 0, len(dump.Databases))
+	for _, db := range dump.Databases {
+		entry := DatabaseEntry{Name: db.Name, Status: DatabaseOK, Bytes: db.Size}
+		if db.Err != nil {
+			entry.Status = DatabaseFailed
+			if db.TimedOut() {
+				entry.Status = DatabaseTimedOut
+			}
+			entry.Error = db.Err.Error()
+		}
+		e.Databases = append(e.Databases, entry)
+	}
+	e.Bytes = dump.ArchiveSize
+	e.Key = dump.StorageKey
+}
+
+// Filter selects history entries.
+type Filter struct {
+	// Since and Until bound the run start time; zero values are open.
+	Since time.Time
+	Until time.Time
+
+	// Job selects a single job; empty selects all jobs.
+	Job string
+
+	// FailedOnly selects runs that did not fully succeed.
+	FailedOnly bool
+}
+
+// Match reports whether the entry is selected by the filter.
+func (f *Filter) Match(e Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.StartedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.StartedAt.Before(f.Until):
+		return false
+	case f.Job != "" && e.Job != f.Job:
+		return false
+	case f.FailedOnly && !e.Failed():
+		return false
+	default:
+		return true
+	}
+}
+
+// File is a history stored as one JSON object per line.
+type File struct {
+	path string
+}
+
+// NewFile returns the history stored at path.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Append adds the entry to the end of the history, creating the file if needed.
+func (f *File) Append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(f.path), 0o750); err != nil {
+		return fmt.Errorf("error creating history directory: %w", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening history: %w", err)
+	}
+
+	// A single write keeps lines from concurrent jobs intact
+	_, err = file.Write(append(data, '\n'))
+	if cErr := file.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// Entries returns the entries selected by filter, oldest first. Lines that cannot be parsed are skipped.
+// A missing file is an empty history.
+func (f *File) Entries(filter Filter) ([]Entry, error) {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening history: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if uErr := json.Unmarshal(scanner.Bytes(), &e); uErr != nil {
+			slog.Warn("Skipping unreadable history entry", "path", f.path, "line", line, "error", uErr)
+			continue
+		}
+		if filter.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history: %w", err)
+	}
+	return entries, nil
+}
+
+// ObjectKey returns the key, relative to the storage root, under which the entry is uploaded.
+func ObjectKey(e Entry) string {
+	name := e.StartedAt.UTC().Format("20060102T150405Z")
+	if e.RunID != "" {
+		name += "-" + e.RunID
+	}
+	return path.Join(storage.MetadataDir, "history", e.Job, name+".json")
+}
+
+// Upload writes the entry as an object in the storage backend.
+func Upload(ctx context.Context, store storage.StorageIface, e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = store.PutObject(ctx, ObjectKey(e), data, storage.PutOptions{})
+	return err
+}
+
+// ParseTime parses a filter bound: an age relative to now ("36h", "7d"), a date ("2024-07-01",
+// midnight in local time) or an RFC 3339 timestamp. An empty value is the zero time.
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use an age such as 7d or 36h, a date or an RFC 3339 timestamp", value)
+}
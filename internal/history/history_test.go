@@ -0,0 +1,129 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_AppendAndEntries(t *testing.T) {
+	file := NewFile(filepath.Join(t.TempDir(), "state", "history.jsonl"))
+
+	// A missing history is empty
+	entries, err := file.Entries(Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	runs := []Entry{
+		{RunID: "1", Job: "nightly", Status: StatusSuccess, StartedAt: day, FinishedAt: day.Add(time.Minute)},
+		{RunID: "2", Job: "nightly", Status: StatusPartial, StartedAt: day.AddDate(0, 0, 1)},
+		{RunID: "3", Job: "orders", Status: StatusFailure, StartedAt: day.AddDate(0, 0, 2), Error: "boom"},
+		{RunID: "4", Job: "orders", Status: StatusSkipped, StartedAt: day.AddDate(0, 0, 3)},
+		{RunID: "5", Job: "orders", Status: StatusCancelled, StartedAt: day.AddDate(0, 0, 4)},
+	}
+	for _, run := range runs {
+		require.NoError(t, file.Append(run))
+	}
+
+	entries, err = file.Entries(Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	assert.Equal(t, "boom", entries[2].Error)
+	assert.Equal(t, time.Minute, entries[0].Duration())
+
+	ids := func(filter Filter) []string {
+		selected, fErr := file.Entries(filter)
+		require.NoError(t, fErr)
+		out := []string{}
+		for _, e := range selected {
+			out = append(out, e.RunID)
+		}
+		return out
+	}
+	// Skipped runs were never attempted, so they are not failures
+	assert.Equal(t, []string{"2", "3", "5"}, ids(Filter{FailedOnly: true}))
+	assert.Equal(t, []string{"1", "2"}, ids(Filter{Job: "nightly"}))
+	assert.Equal(t, []string{"2"}, ids(Filter{Since: day.AddDate(0, 0, 1), Until: day.AddDate(0, 0, 2)}))
+}
+
+func TestFile_EntriesSkipsUnreadableLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"run_id\":\"1\",\"job\":\"a\"}\nnot json\n\n{\"run_id\":\"2\",\"job\":\"a\"}\n"), 0o600))
+
+	entries, err := NewFile(path).Entries(Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "2", entries[1].RunID)
+}
+
+func TestEntry_SetDump(t *testing.T) {
+	var e Entry
+	e.SetDump(&dumpster.DumpResponse{
+		Databases: []dumpster.DatabaseResult{
+			{Name: "app", Size: 1024},
+			{Name: "crm", Err: errors.New("connection refused")},
+			{Name: "ledger", Err: fmt.Errorf("%w after 30m0s", dumpster.ErrDatabaseTimeout)},
+		},
+		ArchiveSize: 512,
+		StorageKey:  "backups/20240701000000/db_exports.zip",
+	})
+
+	assert.Equal(t, []DatabaseEntry{
+		{Name: "app", Status: DatabaseOK, Bytes: 1024},
+		{Name: "crm", Status: DatabaseFailed, Error: "connection refused"},
+		{Name: "ledger", Status: DatabaseTimedOut, Error: "database dump timed out after 30m0s"},
+	}, e.Databases)
+	assert.Equal(t, int64(512), e.Bytes)
+	assert.Equal(t, "backups/20240701000000/db_exports.zip", e.Key)
+}
+
+func TestUpload(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	e := Entry{RunID: "run-1", Job: "nightly", Status: StatusSuccess, StartedAt: time.Date(2024, 7, 1, 2, 3, 4, 0, time.UTC)}
+
+	mockStore.On("PutObject", mock.Anything, ".stashly/history/nightly/20240701T020304Z-run-1.json", mock.Anything, storage.PutOptions{}).
+		Run(func(args mock.Arguments) {
+			var got Entry
+			require.NoError(t, json.Unmarshal(args.Get(2).([]byte), &got))
+			assert.Equal(t, "run-1", got.RunID)
+		}).Return("", nil)
+
+	require.NoError(t, Upload(context.Background(), mockStore, e))
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{value: "", want: time.Time{}},
+		{value: "7d", want: now.AddDate(0, 0, -7)},
+		{value: "36h", want: now.Add(-36 * time.Hour)},
+		{value: "2024-07-01", want: time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local)},
+		{value: "2024-07-01T00:00:00Z", want: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s", got)
+		})
+	}
+
+	_, err := ParseTime("last week", now)
+	require.Error(t, err)
+}
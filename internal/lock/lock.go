@@ -0,0 +1,222 @@
+// Package lock implements a distributed lock stored as an object in the storage backend,
+// so that replicas sharing a bucket do not back up the same server concurrently.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibare/stashly/internal/storage"
+)
+
+// refreshDivisor controls how often a held lock is refreshed, as a fraction of its TTL.
+const refreshDivisor = 3
+
+var (
+	// ErrLocked is returned when the lock is held by someone else.
+	ErrLocked = errors.New("lock is held")
+
+	// ErrLost is the cause of a held lock's loss: another owner took it over, or it expired
+	// because it could not be refreshed.
+	ErrLost = errors.New("lock was lost")
+)
+
+// Info is the content of a lock object.
+type Info struct {
+	// Owner identifies the instance holding the lock.
+	Owner string `json:"owner"`
+
+	// ID is unique to a single acquisition, so releasing never deletes a lock that was taken over.
+	ID string `json:"id"`
+
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the lock has expired at now.
+func (i *Info) Expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
+
+// Locker acquires a named lock object in the storage backend.
+type Locker struct {
+	store storage.StorageIface
+	key   string
+	owner string
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// NewLocker creates a locker for the lock called name. Locks that are not refreshed expire after ttl.
+func NewLocker(store storage.StorageIface, name, owner string, ttl time.Duration) *Locker {
+	return &Locker{
+		store: store,
+		key:   path.Join(storage.MetadataDir, "locks", name+".json"),
+		owner: owner,
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+// Acquire takes the lock, or returns an error wrapping ErrLocked if another owner holds it.
+// Expired locks are taken over. The returned lock is refreshed in the background until released.
+func (l *Locker) Acquire(ctx context.Context) (*Lock, error) {
+	now := l.now()
+	info := Info{
+		Owner:      l.owner,
+		ID:         uuid.NewString(),
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(l.ttl),
+	}
+
+	opts := storage.PutOptions{IfNoneMatch: true}
+	data, etag, err := l.store.GetObject(ctx, l.key)
+	switch {
+	case errors.Is(err, storage.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("error reading lock: %w", err)
+	default:
+		var held Info
+		if uErr := json.Unmarshal(data, &held); uErr != nil {
+			slog.WarnContext(ctx, "Ignoring unreadable lock", "key", l.key, "error", uErr)
+		} else if !held.Expired(now) {
+			return nil, fmt.Errorf("%w by %s until %s", ErrLocked, held.Owner, held.ExpiresAt.Format(time.RFC3339))
+		} else {
+			slog.WarnContext(ctx, "Taking over expired lock", "key", l.key, "owner", held.Owner, "expired_at", held.ExpiresAt)
+		}
+		opts = storage.PutOptions{IfMatch: etag}
+	}
+
+	etag, err = l.write(ctx, info, opts)
+	if err != nil {
+		if errors.Is(err, storage.ErrPreconditionFailed) {
+			return nil, fmt.Errorf("%w: acquired concurrently by another owner", ErrLocked)
+		}
+		return nil, fmt.Errorf("error writing lock: %w", err)
+	}
+
+	lock := &Lock{
+		locker: l,
+		info:   info,
+		etag:   etag,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	go lock.keepAlive(context.WithoutCancel(ctx))
+	return lock, nil
+}
+
+// write stores info as the lock object and returns its new ETag.
+func (l *Locker) write(ctx context.Context, info Info, opts storage.PutOptions) (string, error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	return l.store.PutObject(ctx, l.key, data, opts)
+}
+
+// Lock is a held lock.
+type Lock struct {
+	locker *Locker
+
+	mu   sync.Mutex
+	info Info
+
+	// etag is the ETag of our last write; refreshes only succeed while the object is unchanged.
+	etag string
+
+	once sync.Once
+	stop chan struct{}
+	done chan struct{}
+	lost chan struct{}
+}
+
+// Info returns the current content of the lock object.
+func (lk *Lock) Info() Info {
+	lk.mu.Lock()
+	defer lk.mu.Unlock()
+	return lk.info
+}
+
+// Lost returns a channel that is closed when the lock is lost before being released (see ErrLost).
+// Work guarded by the lock should stop then, as another owner may take it over.
+func (lk *Lock) Lost() <-chan struct{} {
+	return lk.lost
+}
+
+// keepAlive extends the lock's expiry until Release is called or the lock is lost.
+func (lk *Lock) keepAlive(ctx context.Context) {
+	defer close(lk.done)
+
+	ticker := time.NewTicker(lk.locker.ttl / refreshDivisor)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lk.stop:
+			return
+		case <-ticker.C:
+			info := lk.Info()
+			now := lk.locker.now()
+			expired := info.Expired(now)
+			info.ExpiresAt = now.Add(lk.locker.ttl)
+			etag, err := lk.locker.write(ctx, info, storage.PutOptions{IfMatch: lk.etag})
+			switch {
+			case errors.Is(err, storage.ErrPreconditionFailed):
+				slog.WarnContext(ctx, "Lock was taken over by another owner", "key", lk.locker.key)
+				close(lk.lost)
+				return
+			case err != nil && expired:
+				slog.WarnContext(ctx, "Lock expired before it could be refreshed", "key", lk.locker.key, "error", err)
+				close(lk.lost)
+				return
+			case err != nil:
+				slog.WarnContext(ctx, "Failed to refresh lock", "key", lk.locker.key, "error", err)
+				continue
+			}
+			lk.etag = etag
+			lk.mu.Lock()
+			lk.info = info
+			lk.mu.Unlock()
+		}
+	}
+}
+
+// Release stops refreshing the lock and deletes it, unless another owner has taken it over.
+func (lk *Lock) Release(ctx context.Context) error {
+	lk.once.Do(func() { close(lk.stop) })
+	<-lk.done
+
+	data, etag, err := lk.locker.store.GetObject(ctx, lk.locker.key)
+	if errors.Is(err, storage.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading lock: %w", err)
+	}
+
+	var held Info
+	if err = json.Unmarshal(data, &held); err == nil && held.ID != lk.Info().ID {
+		slog.WarnContext(ctx, "Lock was taken over by another owner; not releasing", "key", lk.locker.key, "owner", held.Owner)
+		return nil
+	}
+
+	// Delete only the version just read, so a take-over between the read and the delete is kept
+	err = lk.locker.store.DeleteObject(ctx, lk.locker.key, storage.DeleteOptions{IfMatch: etag})
+	switch {
+	case errors.Is(err, storage.ErrPreconditionFailed):
+		slog.WarnContext(ctx, "Lock was taken over by another owner; not releasing", "key", lk.locker.key)
+		return nil
+	case errors.Is(err, storage.ErrNotExist):
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,191 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibare/stashly/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const lockKey = ".stashly/locks/orders.json"
+
+func lockData(t *testing.T, info Info) []byte {
+	t.Helper()
+	data, err := json.Marshal(info)
+	require.NoError(t, err)
+	return data
+}
+
+func TestLocker_Acquire_Free(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	locker := NewLocker(mockStore, "orders", "replica-a", time.Hour)
+
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(nil, "", storage.ErrNotExist)
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, storage.PutOptions{IfNoneMatch: true}).Return("", nil)
+
+	lock, err := locker.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "replica-a", lock.Info().Owner)
+	assert.NotEmpty(t, lock.Info().ID)
+
+	// Release deletes our own lock
+	mockStore.ExpectedCalls = nil
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(lockData(t, lock.Info()), "etag", nil)
+	mockStore.On("DeleteObject", mock.Anything, lockKey, storage.DeleteOptions{IfMatch: "etag"}).Return(nil)
+
+	require.NoError(t, lock.Release(context.Background()))
+	mockStore.AssertExpectations(t)
+}
+
+func TestLocker_Acquire_Held(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	locker := NewLocker(mockStore, "orders", "replica-a", time.Hour)
+
+	held := Info{Owner: "replica-b", ID: "other", ExpiresAt: time.Now().Add(time.Minute)}
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(lockData(t, held), "etag", nil)
+
+	lock, err := locker.Acquire(context.Background())
+	require.ErrorIs(t, err, ErrLocked)
+	assert.Nil(t, lock)
+	assert.Contains(t, err.Error(), "replica-b")
+}
+
+func TestLocker_Acquire_TakesOverExpired(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	locker := NewLocker(mockStore, "orders", "replica-a", time.Hour)
+
+	expired := Info{Owner: "replica-b", ID: "other", ExpiresAt: time.Now().Add(-time.Minute)}
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(lockData(t, expired), "etag-1", nil)
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, storage.PutOptions{IfMatch: "etag-1"}).Return("", nil)
+
+	lock, err := locker.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "replica-a", lock.Info().Owner)
+	lock.once.Do(func() { close(lock.stop) })
+}
+
+func TestLocker_Acquire_Race(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	locker := NewLocker(mockStore, "orders", "replica-a", time.Hour)
+
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(nil, "", storage.ErrNotExist)
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, mock.Anything).Return("", storage.ErrPreconditionFailed)
+
+	_, err := locker.Acquire(context.Background())
+	require.ErrorIs(t, err, ErrLocked)
+}
+
+func TestLock_ReleaseTakenOver(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	locker := NewLocker(mockStore, "orders", "replica-a", time.Hour)
+
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(nil, "", storage.ErrNotExist).Once()
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, mock.Anything).Return("", nil)
+
+	lock, err := locker.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// Another owner took over the lock; it must not be deleted
+	other := Info{Owner: "replica-b", ID: "other", ExpiresAt: time.Now().Add(time.Hour)}
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(lockData(t, other), "etag", nil)
+
+	require.NoError(t, lock.Release(context.Background()))
+	mockStore.AssertNotCalled(t, "DeleteObject", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLock_ReleaseTakenOverBeforeDelete(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	locker := NewLocker(mockStore, "orders", "replica-a", time.Hour)
+
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(nil, "", storage.ErrNotExist).Once()
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, mock.Anything).Return("", nil)
+
+	lock, err := locker.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// The lock is still ours when read, but another owner takes it over before the delete
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(lockData(t, lock.Info()), "etag-1", nil)
+	mockStore.On("DeleteObject", mock.Anything, lockKey, storage.DeleteOptions{IfMatch: "etag-1"}).
+		Return(storage.ErrPreconditionFailed)
+
+	require.NoError(t, lock.Release(context.Background()))
+	mockStore.AssertExpectations(t)
+}
+
+func TestLock_KeepAlive(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	locker := NewLocker(mockStore, "orders", "replica-a", 30*time.Millisecond)
+
+	// Each refresh is conditional on the ETag of the previous write
+	refreshed := make(chan struct{}, 10)
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(nil, "", storage.ErrNotExist)
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, storage.PutOptions{IfNoneMatch: true}).Return("etag-1", nil).Once()
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, storage.PutOptions{IfMatch: "etag-1"}).Return("etag-2", nil).Once()
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, storage.PutOptions{IfMatch: "etag-2"}).
+		Run(func(mock.Arguments) { refreshed <- struct{}{} }).Return("etag-2", nil)
+
+	lock, err := locker.Acquire(context.Background())
+	require.NoError(t, err)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("lock was not refreshed")
+	}
+
+	require.NoError(t, lock.Release(context.Background()))
+}
+
+func TestLock_KeepAlive_TakenOver(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	locker := NewLocker(mockStore, "orders", "replica-a", 30*time.Millisecond)
+
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(nil, "", storage.ErrNotExist).Once()
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, storage.PutOptions{IfNoneMatch: true}).Return("etag-1", nil).Once()
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, storage.PutOptions{IfMatch: "etag-1"}).
+		Return("", storage.ErrPreconditionFailed).Once()
+
+	lock, err := locker.Acquire(context.Background())
+	require.NoError(t, err)
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lock was not reported lost")
+	}
+
+	// The lock is not refreshed or deleted once it is lost
+	other := Info{Owner: "replica-b", ID: "other", ExpiresAt: time.Now().Add(time.Hour)}
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(lockData(t, other), "etag-3", nil)
+	require.NoError(t, lock.Release(context.Background()))
+	mockStore.AssertExpectations(t)
+}
+
+func TestLock_KeepAlive_Expired(t *testing.T) {
+	mockStore := storage.NewMockStorageIface(t)
+	locker := NewLocker(mockStore, "orders", "replica-a", 30*time.Millisecond)
+
+	mockStore.On("GetObject", mock.Anything, lockKey).Return(nil, "", storage.ErrNotExist)
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, storage.PutOptions{IfNoneMatch: true}).Return("etag-1", nil).Once()
+	mockStore.On("PutObject", mock.Anything, lockKey, mock.Anything, storage.PutOptions{IfMatch: "etag-1"}).
+		Return("", errors.New("connection refused"))
+
+	lock, err := locker.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// Failed refreshes are retried until the lock expires
+	select {
+	case <-lock.Lost():
+		info := lock.Info()
+		assert.True(t, info.Expired(time.Now()))
+	case <-time.After(time.Second):
+		t.Fatal("lock was not reported lost")
+	}
+	require.NoError(t, lock.Release(context.Background()))
+}
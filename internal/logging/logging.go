@@ -0,0 +1,52 @@
+// Package logging extends the default slog logger with per-run context features and trace correlation.
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextHandler wraps a slog.Handler and copies records into the run log tail found in the context.
+type contextHandler struct {
+	next slog.Handler
+}
+
+// Enabled reports whether the wrapped handler handles records at the given level.
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle records the entry in the run log tail, if any, and passes it to the wrapped handler
+// with the trace and span IDs of the span in the context, if any.
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if tail := TailFromContext(ctx); tail != nil {
+		tail.add(r)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r = r.Clone()
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a new handler whose wrapped handler has the given attributes.
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+// WithGroup returns a new handler whose wrapped handler has the given group.
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
+
+// Install wraps the default slog handler so context-aware features apply to all logging.
+// It must be called again whenever the default logger is replaced.
+func Install() {
+	current := slog.Default().Handler()
+	if _, ok := current.(*contextHandler); ok {
+		return
+	}
+	slog.SetDefault(slog.New(&contextHandler{next: current}))
+}
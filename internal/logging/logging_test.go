@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextHandler_CapturesTail(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(&contextHandler{next: slog.NewTextHandler(&out, nil)})
+
+	tail := NewTail(2)
+	ctx := WithTail(t.Context(), tail)
+
+	logger.InfoContext(ctx, "first")
+	logger.InfoContext(ctx, "second", "database", "app")
+	logger.ErrorContext(ctx, "third")
+	logger.InfoContext(context.Background(), "not captured")
+
+	lines := strings.Split(tail.String(), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "INFO second database=app")
+	assert.Contains(t, lines[1], "ERROR third")
+
+	// Everything still reaches the wrapped handler
+	assert.Contains(t, out.String(), "first")
+	assert.Contains(t, out.String(), "not captured")
+}
+
+func TestContextHandler_TraceIDs(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(&contextHandler{next: slog.NewTextHandler(&out, nil)})
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	tail := NewTail(1)
+	ctx := WithTail(trace.ContextWithSpanContext(t.Context(), sc), tail)
+
+	logger.InfoContext(ctx, "traced")
+	logger.InfoContext(t.Context(), "untraced")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Contains(t, lines[0], "trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7")
+	assert.NotContains(t, lines[1], "trace_id")
+	assert.NotContains(t, tail.String(), "trace_id", "the tail sent with heartbeats stays readable")
+}
+
+func TestInstall_Idempotent(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	Install()
+	first := slog.Default().Handler()
+	Install()
+
+	assert.Same(t, first, slog.Default().Handler())
+}
+
+func TestTailFromContext_Missing(t *testing.T) {
+	assert.Nil(t, TailFromContext(t.Context()))
+}
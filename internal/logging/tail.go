@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+type tailKey struct{}
+
+// Tail keeps the most recent log lines of a single run.
+type Tail struct {
+	mu    sync.Mutex
+	max   int
+	lines []string
+}
+
+// NewTail creates a tail that keeps at most max lines.
+func NewTail(maxLines int) *Tail {
+	return &Tail{max: maxLines}
+}
+
+func (t *Tail) add(r slog.Record) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", r.Time.Format(time.RFC3339), r.Level, r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, b.String())
+	if t.max > 0 && len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+}
+
+// String returns the captured lines joined by newlines.
+func (t *Tail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.lines, "\n")
+}
+
+// WithTail returns a context whose log records are captured in the tail.
+func WithTail(ctx context.Context, t *Tail) context.Context {
+	return context.WithValue(ctx, tailKey{}, t)
+}
+
+// TailFromContext returns the tail attached to the context, or nil.
+func TailFromContext(ctx context.Context) *Tail {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(tailKey{}).(*Tail)
+	return t
+}
@@ -0,0 +1,182 @@
+// Package metrics exports Prometheus metrics describing backup runs, purges and the schedule.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/history"
+	"github.com/hibare/stashly/internal/scheduler"
+)
+
+const namespace = "stashly"
+
+// durationBuckets covers runs from a few seconds to several hours.
+var durationBuckets = prometheus.ExponentialBuckets(1, 2, 16)
+
+// Scheduler is the part of the scheduler reporting upcoming runs.
+type Scheduler interface {
+	Status() []scheduler.JobStatus
+}
+
+// Metrics records backup metrics in its own registry. All methods are no-ops on a nil *Metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	lastSuccess     *prometheus.GaugeVec
+	runDuration     *prometheus.HistogramVec
+	uploadedBytes   *prometheus.CounterVec
+	dumpDuration    *prometheus.GaugeVec
+	databaseSize    *prometheus.GaugeVec
+	failedDatabases *prometheus.GaugeVec
+	purged          *prometheus.CounterVec
+	storageObjects  *prometheus.GaugeVec
+}
+
+// New creates the metrics and registers them, along with Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time the job's last successful backup finished.",
+		}, []string{"job"}),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "run_duration_seconds",
+			Help:      "Duration of backup runs by final status.",
+			Buckets:   durationBuckets,
+		}, []string{"job", "status"}),
+		uploadedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "uploaded_bytes_total",
+			Help:      "Bytes of backup archives uploaded to storage.",
+		}, []string{"job"}),
+		dumpDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "database_dump_duration_seconds",
+			Help:      "Duration of the last successful dump of each database.",
+		}, []string{"job", "database"}),
+		databaseSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "database_dump_size_bytes",
+			Help:      "Size of the last successful dump of each database.",
+		}, []string{"job", "database"}),
+		failedDatabases: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "failed_databases",
+			Help:      "Number of databases that failed or timed out in the job's last run.",
+		}, []string{"job"}),
+		purged: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "purged_backups_total",
+			Help:      "Backups deleted from storage by the retention policy.",
+		}, []string{"job"}),
+		storageObjects: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "storage_backups",
+			Help:      "Number of the job's backups in storage after the last purge.",
+		}, []string{"job"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.lastSuccess, m.runDuration, m.uploadedBytes, m.dumpDuration, m.databaseSize,
+		m.failedDatabases, m.purged, m.storageObjects,
+	)
+	return m
+}
+
+// WatchScheduler exports the next scheduled run of each of the scheduler's jobs.
+func (m *Metrics) WatchScheduler(s Scheduler) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(&nextRunCollector{sched: s})
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRun records a finished backup run. dump is nil when the run failed before producing a backup.
+func (m *Metrics) ObserveRun(job string, status history.Status, duration time.Duration, dump *dumpster.DumpResponse) {
+	if m == nil {
+		return
+	}
+	m.runDuration.WithLabelValues(job, string(status)).Observe(duration.Seconds())
+	if status == history.StatusSuccess {
+		m.lastSuccess.WithLabelValues(job).SetToCurrentTime()
+	}
+	if dump == nil {
+		return
+	}
+
+	m.uploadedBytes.WithLabelValues(job).Add(float64(dump.ArchiveSize))
+	failed := 0
+	for _, db := range dump.Databases {
+		if db.Err != nil {
+			failed++
+			continue
+		}
+		m.dumpDuration.WithLabelValues(job, db.Name).Set(db.Duration.Seconds())
+		m.databaseSize.WithLabelValues(job, db.Name).Set(float64(db.Size))
+	}
+	m.failedDatabases.WithLabelValues(job).Set(float64(failed))
+}
+
+// ObservePurge records the outcome of a purge, which may be partial if a deletion failed.
+func (m *Metrics) ObservePurge(job string, purge *dumpster.PurgeResponse) {
+	if m == nil || purge == nil {
+		return
+	}
+	m.purged.WithLabelValues(job).Add(float64(purge.Deleted))
+	m.storageObjects.WithLabelValues(job).Set(float64(purge.Remaining))
+}
+
+// nextRunCollector reads the next run of each job from the scheduler at scrape time.
+type nextRunCollector struct {
+	sched Scheduler
+}
+
+var nextRunDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "next_run_timestamp_seconds"),
+	"Unix time of the job's next scheduled run.",
+	[]string{"job"}, nil,
+)
+
+func (c *nextRunCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nextRunDesc
+}
+
+func (c *nextRunCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, st := range c.sched.Status() {
+		if st.NextRun.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(nextRunDesc, prometheus.GaugeValue,
+			float64(st.NextRun.UnixNano())/float64(time.Second), st.Name)
+	}
+}
+
+type metricsKey struct{}
+
+// WithMetrics returns a context carrying m, so runs started from it record their metrics.
+func WithMetrics(ctx context.Context, m *Metrics) context.Context {
+	return context.WithValue(ctx, metricsKey{}, m)
+}
+
+// FromContext returns the metrics carried by ctx, or nil if there are none.
+func FromContext(ctx context.Context) *Metrics {
+	m, _ := ctx.Value(metricsKey{}).(*Metrics)
+	return m
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/history"
+	"github.com/hibare/stashly/internal/scheduler"
+)
+
+type fakeScheduler struct{}
+
+func (fakeScheduler) Status() []scheduler.JobStatus {
+	return []scheduler.JobStatus{
+		{Name: "orders", NextRun: time.Unix(1704153600, 0)},
+		{Name: "paused"},
+	}
+}
+
+func TestMetrics_ObserveRun(t *testing.T) {
+	m := New()
+	dump := &dumpster.DumpResponse{
+		ArchiveSize: 2048,
+		Databases: []dumpster.DatabaseResult{
+			{Name: "app", Size: 1024, Duration: 3 * time.Second},
+			{Name: "billing", Err: errors.New("connection refused")},
+		},
+	}
+
+	m.ObserveRun("orders", history.StatusPartial, time.Minute, dump)
+	m.ObserveRun("orders", history.StatusPartial, time.Minute, dump)
+
+	assert.InDelta(t, 4096, testutil.ToFloat64(m.uploadedBytes.WithLabelValues("orders")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(m.failedDatabases.WithLabelValues("orders")), 0)
+	assert.InDelta(t, 3, testutil.ToFloat64(m.dumpDuration.WithLabelValues("orders", "app")), 0)
+	assert.InDelta(t, 1024, testutil.ToFloat64(m.databaseSize.WithLabelValues("orders", "app")), 0)
+	assert.Equal(t, 1, testutil.CollectAndCount(m.databaseSize), "failed databases have no size")
+	assert.Equal(t, 0, testutil.CollectAndCount(m.lastSuccess), "partial runs are not successes")
+	assert.Equal(t, 1, testutil.CollectAndCount(m.runDuration))
+
+	m.ObserveRun("orders", history.StatusSuccess, time.Minute, nil)
+	assert.InDelta(t, float64(time.Now().Unix()), testutil.ToFloat64(m.lastSuccess.WithLabelValues("orders")), 5)
+}
+
+func TestMetrics_ObservePurge(t *testing.T) {
+	m := New()
+
+	m.ObservePurge("orders", &dumpster.PurgeResponse{Deleted: 2, Remaining: 7})
+	m.ObservePurge("orders", &dumpster.PurgeResponse{Deleted: 1, Remaining: 7})
+	m.ObservePurge("orders", nil)
+
+	assert.InDelta(t, 3, testutil.ToFloat64(m.purged.WithLabelValues("orders")), 0)
+	assert.InDelta(t, 7, testutil.ToFloat64(m.storageObjects.WithLabelValues("orders")), 0)
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.WatchScheduler(fakeScheduler{})
+	m.ObserveRun("orders", history.StatusSuccess, time.Second, &dumpster.DumpResponse{})
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `stashly_next_run_timestamp_seconds{job="orders"} 1.7041536e+09`)
+	assert.NotContains(t, body, `job="paused"`)
+	assert.Contains(t, body, `stashly_run_duration_seconds_count{job="orders",status="success"} 1`)
+	assert.Contains(t, body, `stashly_last_success_timestamp_seconds{job="orders"}`)
+	assert.True(t, strings.Contains(body, "go_goroutines"), "runtime metrics are exported")
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	m := FromContext(context.Background())
+	require.Nil(t, m)
+
+	assert.NotPanics(t, func() {
+		m.WatchScheduler(fakeScheduler{})
+		m.ObserveRun("orders", history.StatusFailure, time.Second, nil)
+		m.ObservePurge("orders", &dumpster.PurgeResponse{Deleted: 1})
+	})
+}
+
+func TestWithMetrics(t *testing.T) {
+	m := New()
+	assert.Same(t, m, FromContext(WithMetrics(context.Background(), m)))
+}
@@ -4,89 +4,71 @@ package discord
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	"github.com/hibare/GoCommon/v2/pkg/notifiers/discord"
 	"github.com/hibare/stashly/internal/config"
 	"github.com/hibare/stashly/internal/constants"
+	"github.com/hibare/stashly/internal/events"
 )
 
 const (
+	infoColor            = 3901635
 	successColor         = 1498748
+	partialColor         = 16098851
 	failureColor         = 14554702
 	deletionFailureColor = 14590998
 )
 
 // Discord sends notifications to a Discord channel via webhook.
 type Discord struct {
-	Cfg *config.Config
+	Cfg        config.DiscordNotifierConfig
+	InstanceID string
 }
 
 // Enabled checks if the Discord notifier is enabled in the configuration.
 func (d *Discord) Enabled() bool {
-	return d.Cfg.Notifiers.Discord.Enabled
+	return d.Cfg.Enabled
 }
 
-// NotifyBackupSuccess sends a success notification to the Discord channel.
-func (d *Discord) NotifyBackupSuccess(_ context.Context, databases int, key string) error {
-	message := discord.Message{
-		Embeds: []discord.Embed{
-			{
-				Color: successColor,
-				Fields: []discord.EmbedField{
-					{
-						Name:   "Key",
-						Value:  key,
-						Inline: false,
-					},
-					{
-						Name:   "Databases",
-						Value:  strconv.Itoa(databases),
-						Inline: false,
-					},
-				},
-			},
-		},
-		Components: []discord.Component{},
-		Username:   constants.ProgramIdentifier,
-		Content:    fmt.Sprintf("**PG-DB Backup Successful** - *%s*", d.Cfg.App.InstanceID),
+func color(t events.Type) int {
+	switch t {
+	case events.TypeStarted:
+		return infoColor
+	case events.TypeSuccess, events.TypePurgeSuccess:
+		return successColor
+	case events.TypePartial, events.TypeCancelled:
+		return partialColor
+	case events.TypeFailure, events.TypeVerifyFailure:
+		return failureColor
+	case events.TypePurgeFailure:
+		return deletionFailureColor
+	default:
+		return infoColor
 	}
-
-	return message.Send(d.Cfg.Notifiers.Discord.Webhook)
 }
 
-// NotifyBackupFailure sends a failure notification to the Discord channel.
-func (d *Discord) NotifyBackupFailure(_ context.Context, err error) error {
-	message := discord.Message{
-		Embeds: []discord.Embed{
-			{
-				Title:       "Error",
-				Description: err.Error(),
-				Color:       failureColor,
-			},
-		},
-		Components: []discord.Component{},
-		Username:   constants.ProgramIdentifier,
-		Content:    fmt.Sprintf("**PG-DB Backup Failed** - *%s*", d.Cfg.App.InstanceID),
+// Notify sends the event to the Discord channel.
+func (d *Discord) Notify(_ context.Context, ev events.Event) error {
+	fields := []discord.EmbedField{}
+	for _, f := range ev.Fields() {
+		fields = append(fields, discord.EmbedField{
+			Name:   f.Name,
+			Value:  f.Value,
+			Inline: false,
+		})
 	}
 
-	return message.Send(d.Cfg.Notifiers.Discord.Webhook)
-}
-
-// NotifyBackupDeleteFailure sends a deletion failure notification to the Discord channel.
-func (d *Discord) NotifyBackupDeleteFailure(_ context.Context, err error) error {
 	message := discord.Message{
 		Embeds: []discord.Embed{
 			{
-				Title:       "Error",
-				Description: err.Error(),
-				Color:       deletionFailureColor,
+				Color:  color(ev.Type),
+				Fields: fields,
 			},
 		},
 		Components: []discord.Component{},
 		Username:   constants.ProgramIdentifier,
-		Content:    fmt.Sprintf("**PG-DB Backup Deletion Failed** - *%s*", d.Cfg.App.InstanceID),
+		Content:    fmt.Sprintf("**%s** - *%s*", ev.Type.Title(), d.InstanceID),
 	}
 
-	return message.Send(d.Cfg.Notifiers.Discord.Webhook)
+	return message.Send(d.Cfg.Webhook)
 }
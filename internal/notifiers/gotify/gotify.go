@@ -0,0 +1,87 @@
+// Package gotify provides a notifier that pushes backup events to a Gotify server.
+package gotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/constants"
+	"github.com/hibare/stashly/internal/events"
+)
+
+var httpClient = &http.Client{Timeout: constants.DefaultNotifierTimeout}
+
+// message is the payload accepted by the Gotify /message endpoint.
+type message struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+// Gotify sends notifications to a Gotify application.
+type Gotify struct {
+	Cfg        config.GotifyNotifierConfig
+	InstanceID string
+}
+
+// Enabled checks if the Gotify notifier is enabled in the configuration.
+func (g *Gotify) Enabled() bool {
+	return g.Cfg.Enabled
+}
+
+func (g *Gotify) push(ctx context.Context, msg message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(g.Cfg.URL, "/") + "/message"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.Cfg.Token)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (g *Gotify) priority(t events.Type) int {
+	switch t {
+	case events.TypeStarted, events.TypeSuccess, events.TypePurgeSuccess:
+		return g.Cfg.Priority.Success
+	case events.TypePartial, events.TypeFailure, events.TypeCancelled, events.TypeVerifyFailure:
+		return g.Cfg.Priority.Failure
+	case events.TypePurgeFailure:
+		return g.Cfg.Priority.DeleteFailure
+	default:
+		return g.Cfg.Priority.Success
+	}
+}
+
+// Notify pushes the event to Gotify.
+func (g *Gotify) Notify(ctx context.Context, ev events.Event) error {
+	lines := []string{}
+	for _, f := range ev.Fields() {
+		lines = append(lines, f.Name+": "+f.Value)
+	}
+	return g.push(ctx, message{
+		Title:    fmt.Sprintf("%s - %s", ev.Type.Title(), g.InstanceID),
+		Message:  strings.Join(lines, "\n"),
+		Priority: g.priority(ev.Type),
+	})
+}
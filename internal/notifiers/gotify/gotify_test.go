@@ -0,0 +1,71 @@
+package gotify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGotify(url string) *Gotify {
+	return &Gotify{
+		Cfg: config.GotifyNotifierConfig{
+			Enabled: true,
+			URL:     url + "/",
+			Token:   "app-token",
+			Priority: config.NotifierPriorityConfig{
+				Success:       2,
+				Failure:       8,
+				DeleteFailure: 5,
+			},
+		},
+		InstanceID: "test-instance",
+	}
+}
+
+func TestGotify_Notify(t *testing.T) {
+	var got []message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/message", r.URL.Path)
+		assert.Equal(t, "app-token", r.Header.Get("X-Gotify-Key"))
+
+		var msg message
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		got = append(got, msg)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	g := newTestGotify(server.URL)
+	dump := &dumpster.DumpResponse{TotalDatabases: 2, ExportedDatabases: 2, StorageKey: "key"}
+	require.NoError(t, g.Notify(t.Context(), events.New(events.TypeSuccess, "run-1", dump, nil)))
+	require.NoError(t, g.Notify(t.Context(), events.New(events.TypeFailure, "run-1", nil, errors.New("dump failed"))))
+	require.NoError(t, g.Notify(t.Context(), events.New(events.TypePurgeFailure, "run-1", dump, errors.New("delete failed"))))
+
+	require.Len(t, got, 3)
+	assert.Equal(t, "PG-DB Backup Successful - test-instance", got[0].Title)
+	assert.Equal(t, 2, got[0].Priority)
+	assert.Contains(t, got[0].Message, "Key: key")
+	assert.Contains(t, got[1].Message, "Error: dump failed")
+	assert.Equal(t, 8, got[1].Priority)
+	assert.Equal(t, 5, got[2].Priority)
+}
+
+func TestGotify_UnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	g := newTestGotify(server.URL)
+	err := g.Notify(t.Context(), events.New(events.TypeStarted, "run-1", nil, nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status code: 401")
+}
@@ -5,10 +5,17 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
 	"sync"
 
 	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/events"
 	"github.com/hibare/stashly/internal/notifiers/discord"
+	"github.com/hibare/stashly/internal/notifiers/gotify"
+	"github.com/hibare/stashly/internal/notifiers/ntfy"
+	"github.com/hibare/stashly/internal/notifiers/teams"
+	"github.com/hibare/stashly/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
@@ -23,31 +30,45 @@ var (
 // revive:disable-next-line exported
 type NotifiersIface interface {
 	Enabled() bool
-	NotifyBackupSuccess(ctx context.Context, databases int, key string) error
-	NotifyBackupFailure(ctx context.Context, err error) error
-	NotifyBackupDeleteFailure(ctx context.Context, err error) error
+	Notify(ctx context.Context, ev events.Event) error
 }
 
 // NotifierStoreIface defines the interface for managing multiple notifiers.
 type NotifierStoreIface interface {
 	Enabled() bool
-	NotifyBackupSuccess(ctx context.Context, databases int, key string) error
-	NotifyBackupFailure(ctx context.Context, err error) error
-	NotifyBackupDeleteFailure(ctx context.Context, err error) error
+	Notify(ctx context.Context, ev events.Event) error
+	Test(ctx context.Context) []TestResult
 	InitStore()
 }
 
+// TestResult is the outcome of sending a test message to a notifier.
+type TestResult struct {
+	Name string
+	Err  error
+}
+
+// instance is a named notifier together with the events it is subscribed to.
+type instance struct {
+	name     string
+	events   []events.Type
+	notifier NotifiersIface
+}
+
+func (i *instance) subscribed(t events.Type) bool {
+	return slices.Contains(i.events, t)
+}
+
 // Notifier manages multiple notifier implementations.
 type Notifier struct {
 	cfg   *config.Config
 	mu    sync.RWMutex
-	store []NotifiersIface
+	store []*instance
 }
 
-func (n *Notifier) register(nf NotifiersIface) {
+func (n *Notifier) register(name string, subscribed []events.Type, nf NotifiersIface) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
-	n.store = append(n.store, nf)
+	n.store = append(n.store, &instance{name: name, events: subscribed, notifier: nf})
 }
 
 // Enabled checks if notifiers are globally enabled in the configuration.
@@ -55,66 +76,107 @@ func (n *Notifier) Enabled() bool {
 	return n.cfg.Notifiers.Enabled
 }
 
-// NotifyBackupSuccess sends a backup success notification using all enabled notifiers.
-func (n *Notifier) NotifyBackupSuccess(ctx context.Context, databases int, key string) error {
+// Notify delivers the event to all enabled notifiers.
+func (n *Notifier) Notify(ctx context.Context, ev events.Event) error {
 	if !n.Enabled() {
-		return ErrNotifierDisabled
+		return ErrNotifiersDisabled
 	}
 
-	for _, notifier := range n.store {
-		if !notifier.Enabled() {
-			slog.DebugContext(ctx, "Notifier disabled; skipping NotifyBackupSuccess")
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	for _, inst := range n.store {
+		if !inst.notifier.Enabled() {
+			slog.DebugContext(ctx, "Notifier disabled; skipping", "notifier", inst.name, "event", ev.Type)
+			continue
+		}
+		if !inst.subscribed(ev.Type) {
+			slog.DebugContext(ctx, "Event not subscribed; skipping", "notifier", inst.name, "event", ev.Type)
 			continue
 		}
-		if err := notifier.NotifyBackupSuccess(ctx, databases, key); err != nil {
-			slog.ErrorContext(ctx, "Failed to send NotifyBackupSuccess", "error", err)
+		nCtx, span := tracing.Start(ctx, "notify",
+			attribute.String("stashly.notifier", inst.name), attribute.String("stashly.event", string(ev.Type)))
+		err := inst.notifier.Notify(nCtx, ev)
+		tracing.End(span, err)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to send notification", "notifier", inst.name, "event", ev.Type, "error", err)
 		}
 	}
 
 	return nil
 }
 
-// NotifyBackupFailure sends a backup failure notification using all enabled notifiers.
-func (n *Notifier) NotifyBackupFailure(ctx context.Context, nErr error) error {
+// Test sends a test message to every enabled notifier, regardless of its subscriptions,
+// and reports the outcome for each.
+func (n *Notifier) Test(ctx context.Context) []TestResult {
 	if !n.Enabled() {
-		return ErrNotifierDisabled
+		return nil
 	}
 
-	for _, notifier := range n.store {
-		if !notifier.Enabled() {
-			slog.DebugContext(ctx, "Notifier disabled; skipping NotifyBackupFailure")
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	ev := events.New(events.TypeTest, "", nil, nil)
+	ev.Job = n.cfg.Job
+	results := []TestResult{}
+	for _, inst := range n.store {
+		if !inst.notifier.Enabled() {
 			continue
 		}
-		if err := notifier.NotifyBackupFailure(ctx, nErr); err != nil {
-			slog.ErrorContext(ctx, "Failed to send NotifyBackupFailure", "error", err)
-		}
+		results = append(results, TestResult{Name: inst.name, Err: inst.notifier.Notify(ctx, ev)})
 	}
-
-	return nil
+	return results
 }
 
-// NotifyBackupDeleteFailure sends a backup deletion failure notification using all enabled notifiers.
-func (n *Notifier) NotifyBackupDeleteFailure(ctx context.Context, nErr error) error {
-	if !n.Enabled() {
-		return ErrNotifierDisabled
+// parseEvents converts configured event names into event types.
+// An empty list selects the default events and "all" selects every event.
+func parseEvents(ctx context.Context, name string, names []string) []events.Type {
+	if len(names) == 0 {
+		return events.DefaultTypes
 	}
 
-	for _, notifier := range n.store {
-		if !notifier.Enabled() {
-			slog.DebugContext(ctx, "Notifier disabled; skipping NotifyBackupDeleteFailure")
-			continue
+	subscribed := []events.Type{}
+	for _, s := range names {
+		if s == "all" {
+			return events.Types
 		}
-		if err := notifier.NotifyBackupDeleteFailure(ctx, nErr); err != nil {
-			slog.ErrorContext(ctx, "Failed to send NotifyBackupDeleteFailure", "error", err)
+		t, err := events.ParseType(s)
+		if err != nil {
+			slog.WarnContext(ctx, "Ignoring unknown notifier event", "notifier", name, "error", err)
+			continue
 		}
+		subscribed = append(subscribed, t)
 	}
+	return subscribed
+}
 
-	return nil
+// newInstanceNotifier builds the notifier implementation for a configured instance.
+func newInstanceNotifier(ic config.NotifierInstanceConfig, instanceID string) NotifiersIface {
+	switch ic.Type {
+	case config.NotifierTypeDiscord:
+		return &discord.Discord{Cfg: ic.Discord, InstanceID: instanceID}
+	case config.NotifierTypeNtfy:
+		return &ntfy.Ntfy{Cfg: ic.Ntfy, InstanceID: instanceID}
+	case config.NotifierTypeGotify:
+		return &gotify.Gotify{Cfg: ic.Gotify, InstanceID: instanceID}
+	case config.NotifierTypeTeams:
+		return &teams.Teams{Cfg: ic.Teams, InstanceID: instanceID}
+	default:
+		return nil
+	}
 }
 
-// InitStore initializes and registers all available notifiers.
+// InitStore builds and registers a notifier for every configured instance.
 func (n *Notifier) InitStore() {
-	n.register(&discord.Discord{Cfg: n.cfg})
+	ctx := context.Background()
+	for _, ic := range n.cfg.Notifiers.All() {
+		nf := newInstanceNotifier(ic, n.cfg.App.InstanceID)
+		if nf == nil {
+			slog.WarnContext(ctx, "Unknown notifier type; skipping", "notifier", ic.Name, "type", ic.Type)
+			continue
+		}
+		n.register(ic.Name, parseEvents(ctx, ic.Name, ic.Events), nf)
+	}
 }
 
 // NewNotifier creates a new Notifier instance with the provided configuration.
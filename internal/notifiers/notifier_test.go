@@ -0,0 +1,115 @@
+package notifiers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	enabled  bool
+	received []events.Type
+}
+
+func (r *recorder) Enabled() bool { return r.enabled }
+
+func (r *recorder) Notify(_ context.Context, ev events.Event) error {
+	r.received = append(r.received, ev.Type)
+	return nil
+}
+
+func TestNotifier_NotifyFiltersEvents(t *testing.T) {
+	n := &Notifier{cfg: &config.Config{Notifiers: config.NotifiersConfig{Enabled: true}}}
+
+	oncall := &recorder{enabled: true}
+	log := &recorder{enabled: true}
+	disabled := &recorder{enabled: false}
+	n.register("oncall", []events.Type{events.TypeFailure}, oncall)
+	n.register("log", events.Types, log)
+	n.register("disabled", events.Types, disabled)
+
+	for _, typ := range []events.Type{events.TypeStarted, events.TypeSuccess, events.TypeFailure} {
+		require.NoError(t, n.Notify(t.Context(), events.New(typ, "run-1", nil, nil)))
+	}
+
+	assert.Equal(t, []events.Type{events.TypeFailure}, oncall.received)
+	assert.Equal(t, []events.Type{events.TypeStarted, events.TypeSuccess, events.TypeFailure}, log.received)
+	assert.Empty(t, disabled.received)
+}
+
+func TestNotifier_NotifyGloballyDisabled(t *testing.T) {
+	n := &Notifier{cfg: &config.Config{}}
+	rec := &recorder{enabled: true}
+	n.register("rec", events.Types, rec)
+
+	err := n.Notify(t.Context(), events.New(events.TypeFailure, "run-1", nil, nil))
+	require.ErrorIs(t, err, ErrNotifiersDisabled)
+	assert.Empty(t, rec.received)
+}
+
+func TestNotifier_Test(t *testing.T) {
+	n := &Notifier{cfg: &config.Config{Notifiers: config.NotifiersConfig{Enabled: true}}}
+
+	oncall := &recorder{enabled: true}
+	disabled := &recorder{enabled: false}
+	n.register("oncall", []events.Type{events.TypeFailure}, oncall)
+	n.register("disabled", events.Types, disabled)
+
+	results := n.Test(t.Context())
+	assert.Equal(t, []TestResult{{Name: "oncall"}}, results)
+	assert.Equal(t, []events.Type{events.TypeTest}, oncall.received)
+	assert.Empty(t, disabled.received)
+
+	n.cfg.Notifiers.Enabled = false
+	assert.Empty(t, n.Test(t.Context()))
+}
+
+func TestParseEvents(t *testing.T) {
+	ctx := t.Context()
+	assert.Equal(t, events.DefaultTypes, parseEvents(ctx, "n", nil))
+	assert.Equal(t, events.Types, parseEvents(ctx, "n", []string{"failure", "all"}))
+	assert.Equal(t, []events.Type{events.TypeFailure, events.TypePartial},
+		parseEvents(ctx, "n", []string{"failure", "bogus", "partial"}))
+}
+
+func TestNotifier_InitStore(t *testing.T) {
+	cfg := &config.Config{
+		Notifiers: config.NotifiersConfig{
+			Enabled: true,
+			Discord: config.DiscordNotifierConfig{Enabled: true, Webhook: "https://discord.example.com"},
+			Instances: []config.NotifierInstanceConfig{
+				{
+					Name:   "oncall",
+					Type:   config.NotifierTypeNtfy,
+					Events: []string{"failure"},
+					Ntfy:   config.NtfyNotifierConfig{URL: "https://ntfy.example.com/oncall"},
+				},
+				{
+					Name:    "backups-log",
+					Type:    config.NotifierTypeTeams,
+					Events:  []string{"all"},
+					Teams:   config.TeamsNotifierConfig{Webhook: "https://teams.example.com"},
+					Discord: config.DiscordNotifierConfig{Enabled: true},
+				},
+			},
+		},
+	}
+
+	n, ok := NewNotifier(cfg).(*Notifier)
+	require.True(t, ok)
+	n.InitStore()
+
+	require.Len(t, n.store, 3)
+	assert.Equal(t, "discord", n.store[0].name)
+	assert.Equal(t, events.DefaultTypes, n.store[0].events)
+	assert.Equal(t, "oncall", n.store[1].name)
+	assert.True(t, n.store[1].notifier.Enabled())
+	assert.Equal(t, []events.Type{events.TypeFailure}, n.store[1].events)
+	assert.Equal(t, "backups-log", n.store[2].name)
+	assert.True(t, n.store[2].notifier.Enabled())
+	assert.Equal(t, events.Types, n.store[2].events)
+}
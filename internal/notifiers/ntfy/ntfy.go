@@ -0,0 +1,93 @@
+// Package ntfy provides a notifier that publishes backup events to an ntfy topic.
+package ntfy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/constants"
+	"github.com/hibare/stashly/internal/events"
+)
+
+var httpClient = &http.Client{Timeout: constants.DefaultNotifierTimeout}
+
+// Ntfy sends notifications to an ntfy topic.
+type Ntfy struct {
+	Cfg        config.NtfyNotifierConfig
+	InstanceID string
+}
+
+// Enabled checks if the ntfy notifier is enabled in the configuration.
+func (n *Ntfy) Enabled() bool {
+	return n.Cfg.Enabled
+}
+
+func (n *Ntfy) publish(ctx context.Context, title, message string, priority int, tags ...string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.Cfg.URL, strings.NewReader(message))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Title", title)
+	req.Header.Set("Priority", strconv.Itoa(priority))
+	tags = append(tags, n.Cfg.Tags...)
+	if len(tags) > 0 {
+		req.Header.Set("Tags", strings.Join(tags, ","))
+	}
+	if n.Cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Cfg.Token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (n *Ntfy) priority(t events.Type) int {
+	switch t {
+	case events.TypeStarted, events.TypeSuccess, events.TypePurgeSuccess:
+		return n.Cfg.Priority.Success
+	case events.TypePartial, events.TypeFailure, events.TypeCancelled, events.TypeVerifyFailure:
+		return n.Cfg.Priority.Failure
+	case events.TypePurgeFailure:
+		return n.Cfg.Priority.DeleteFailure
+	default:
+		return n.Cfg.Priority.Success
+	}
+}
+
+func tag(t events.Type) string {
+	switch t {
+	case events.TypeStarted:
+		return "hourglass_flowing_sand"
+	case events.TypeSuccess, events.TypePurgeSuccess:
+		return "white_check_mark"
+	case events.TypePartial, events.TypeCancelled, events.TypePurgeFailure:
+		return "warning"
+	case events.TypeFailure, events.TypeVerifyFailure:
+		return "rotating_light"
+	default:
+		return "information_source"
+	}
+}
+
+// Notify publishes the event to the ntfy topic.
+func (n *Ntfy) Notify(ctx context.Context, ev events.Event) error {
+	title := fmt.Sprintf("%s - %s", ev.Type.Title(), n.InstanceID)
+	lines := []string{}
+	for _, f := range ev.Fields() {
+		lines = append(lines, f.Name+": "+f.Value)
+	}
+	return n.publish(ctx, title, strings.Join(lines, "\n"), n.priority(ev.Type), tag(ev.Type))
+}
@@ -0,0 +1,90 @@
+package ntfy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNtfy(url string) *Ntfy {
+	return &Ntfy{
+		Cfg: config.NtfyNotifierConfig{
+			Enabled: true,
+			URL:     url,
+			Token:   "tk_test",
+			Tags:    []string{"postgres"},
+			Priority: config.NotifierPriorityConfig{
+				Success:       3,
+				Failure:       5,
+				DeleteFailure: 4,
+			},
+		},
+		InstanceID: "test-instance",
+	}
+}
+
+func TestNtfy_NotifySuccess(t *testing.T) {
+	var gotReq *http.Request
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotReq = r
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := newTestNtfy(server.URL)
+	dump := &dumpster.DumpResponse{
+		TotalDatabases:    3,
+		ExportedDatabases: 3,
+		StorageKey:        "backups/20240101000000/db_exports.zip",
+	}
+	err := n.Notify(t.Context(), events.New(events.TypeSuccess, "run-1", dump, nil))
+	require.NoError(t, err)
+
+	require.NotNil(t, gotReq)
+	assert.Equal(t, http.MethodPost, gotReq.Method)
+	assert.Equal(t, "PG-DB Backup Successful - test-instance", gotReq.Header.Get("Title"))
+	assert.Equal(t, "3", gotReq.Header.Get("Priority"))
+	assert.Equal(t, "white_check_mark,postgres", gotReq.Header.Get("Tags"))
+	assert.Equal(t, "Bearer tk_test", gotReq.Header.Get("Authorization"))
+	assert.Contains(t, gotBody, "Run ID: run-1")
+	assert.Contains(t, gotBody, "Databases: 3/3")
+}
+
+func TestNtfy_FailurePriorities(t *testing.T) {
+	var priorities []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priorities = append(priorities, r.Header.Get("Priority"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := newTestNtfy(server.URL)
+	require.NoError(t, n.Notify(t.Context(), events.New(events.TypeFailure, "run-1", nil, errors.New("boom"))))
+	require.NoError(t, n.Notify(t.Context(), events.New(events.TypePurgeFailure, "run-1", nil, errors.New("boom"))))
+	require.NoError(t, n.Notify(t.Context(), events.New(events.TypeStarted, "run-1", nil, nil)))
+
+	assert.Equal(t, []string{"5", "4", "3"}, priorities)
+}
+
+func TestNtfy_UnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	n := newTestNtfy(server.URL)
+	err := n.Notify(t.Context(), events.New(events.TypeFailure, "run-1", nil, errors.New("boom")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status code: 403")
+}
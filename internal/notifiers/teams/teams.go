@@ -0,0 +1,145 @@
+// Package teams provides a notifier that posts Adaptive Cards to Microsoft Teams.
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/constants"
+	"github.com/hibare/stashly/internal/events"
+)
+
+const (
+	adaptiveCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	adaptiveCardVersion     = "1.4"
+	adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+
+	defaultStyle = "default"
+	successStyle = "good"
+	failureStyle = "attention"
+	warningStyle = "warning"
+)
+
+var httpClient = &http.Client{Timeout: constants.DefaultNotifierTimeout}
+
+// Fact is a single title/value pair rendered in an Adaptive Card FactSet.
+type Fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Element is an Adaptive Card body element (TextBlock or FactSet).
+type Element struct {
+	Type   string `json:"type"`
+	Text   string `json:"text,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+	Color  string `json:"color,omitempty"`
+	Wrap   bool   `json:"wrap,omitempty"`
+	Facts  []Fact `json:"facts,omitempty"`
+}
+
+// Card is an Adaptive Card.
+type Card struct {
+	Schema  string    `json:"$schema"`
+	Type    string    `json:"type"`
+	Version string    `json:"version"`
+	Body    []Element `json:"body"`
+}
+
+// Attachment wraps an Adaptive Card for delivery through a Teams webhook.
+type Attachment struct {
+	ContentType string `json:"contentType"`
+	Content     Card   `json:"content"`
+}
+
+// Message is the payload accepted by Teams Workflows and incoming webhooks.
+type Message struct {
+	Type        string       `json:"type"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// newMessage builds a message containing a single Adaptive Card with a heading and facts.
+func newMessage(title, style string, facts []Fact) Message {
+	return Message{
+		Type: "message",
+		Attachments: []Attachment{
+			{
+				ContentType: adaptiveCardContentType,
+				Content: Card{
+					Schema:  adaptiveCardSchema,
+					Type:    "AdaptiveCard",
+					Version: adaptiveCardVersion,
+					Body: []Element{
+						{Type: "TextBlock", Text: title, Size: "Medium", Weight: "Bolder", Color: style, Wrap: true},
+						{Type: "FactSet", Facts: facts},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Teams sends notifications to a Microsoft Teams channel via webhook.
+type Teams struct {
+	Cfg        config.TeamsNotifierConfig
+	InstanceID string
+}
+
+// Enabled checks if the Teams notifier is enabled in the configuration.
+func (t *Teams) Enabled() bool {
+	return t.Cfg.Enabled
+}
+
+func (t *Teams) send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Cfg.Webhook, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// Workflows webhooks return 202, legacy incoming webhooks return 200.
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func style(t events.Type) string {
+	switch t {
+	case events.TypeStarted:
+		return defaultStyle
+	case events.TypeSuccess, events.TypePurgeSuccess:
+		return successStyle
+	case events.TypePartial, events.TypeCancelled, events.TypePurgeFailure:
+		return warningStyle
+	case events.TypeFailure, events.TypeVerifyFailure:
+		return failureStyle
+	default:
+		return defaultStyle
+	}
+}
+
+// Notify posts the event to the Teams channel as an Adaptive Card.
+func (t *Teams) Notify(ctx context.Context, ev events.Event) error {
+	facts := []Fact{{Title: "Instance", Value: t.InstanceID}}
+	for _, f := range ev.Fields() {
+		facts = append(facts, Fact{Title: f.Name, Value: f.Value})
+	}
+	return t.send(ctx, newMessage(ev.Type.Title(), style(ev.Type), facts))
+}
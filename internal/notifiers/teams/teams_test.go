@@ -0,0 +1,80 @@
+package teams
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/dumpster"
+	"github.com/hibare/stashly/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTeams(url string) *Teams {
+	return &Teams{
+		Cfg:        config.TeamsNotifierConfig{Enabled: true, Webhook: url},
+		InstanceID: "test-instance",
+	}
+}
+
+func TestTeams_NotifySuccess(t *testing.T) {
+	var got Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	tm := newTestTeams(server.URL)
+	dump := &dumpster.DumpResponse{
+		TotalDatabases:    4,
+		ExportedDatabases: 4,
+		StorageKey:        "backups/20240101000000/db_exports.zip",
+		Duration:          90 * time.Second,
+	}
+	require.NoError(t, tm.Notify(t.Context(), events.New(events.TypeSuccess, "run-1", dump, nil)))
+
+	require.Len(t, got.Attachments, 1)
+	card := got.Attachments[0].Content
+	assert.Equal(t, adaptiveCardContentType, got.Attachments[0].ContentType)
+	assert.Equal(t, "AdaptiveCard", card.Type)
+	require.Len(t, card.Body, 2)
+	assert.Equal(t, "PG-DB Backup Successful", card.Body[0].Text)
+	assert.Contains(t, card.Body[1].Facts, Fact{Title: "Instance", Value: "test-instance"})
+	assert.Contains(t, card.Body[1].Facts, Fact{Title: "Databases", Value: "4/4"})
+	assert.Contains(t, card.Body[1].Facts, Fact{Title: "Duration", Value: "1m30s"})
+}
+
+func TestTeams_NotifyFailure(t *testing.T) {
+	var got Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tm := newTestTeams(server.URL)
+	require.NoError(t, tm.Notify(t.Context(), events.New(events.TypeFailure, "run-1", nil, errors.New("pg_dump exploded"))))
+
+	card := got.Attachments[0].Content
+	assert.Equal(t, failureStyle, card.Body[0].Color)
+	assert.Contains(t, card.Body[1].Facts, Fact{Title: "Error", Value: "pg_dump exploded"})
+}
+
+func TestTeams_UnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	tm := newTestTeams(server.URL)
+	err := tm.Notify(t.Context(), events.New(events.TypePurgeFailure, "run-1", nil, errors.New("boom")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status code: 400")
+}
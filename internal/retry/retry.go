@@ -0,0 +1,68 @@
+// Package retry retries operations with exponential backoff.
+package retry
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// Policy describes how often and how quickly an operation is retried.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values below 1 mean 1.
+	MaxAttempts int
+
+	// InitialDelay is the wait before the first retry.
+	InitialDelay time.Duration
+
+	// Multiplier scales the delay after every retry. Values below 1 mean 1.
+	Multiplier float64
+}
+
+// Attempts returns the total number of attempts allowed by the policy.
+func (p Policy) Attempts() int {
+	return max(p.MaxAttempts, 1)
+}
+
+// Delay returns the wait before the given retry, where 1 is the first retry.
+func (p Policy) Delay(retry int) time.Duration {
+	if retry < 1 {
+		return 0
+	}
+	multiplier := max(p.Multiplier, 1)
+	return time.Duration(float64(p.InitialDelay) * math.Pow(multiplier, float64(retry-1)))
+}
+
+// Wait blocks for the delay before the given retry, returning early with ctx's error if it is done.
+func (p Policy) Wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(p.Delay(retry))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Do calls fn until it succeeds or the attempts are exhausted, waiting between attempts.
+// It stops early when ctx is done. The error of the last attempt is returned.
+func (p Policy) Do(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= p.Attempts(); attempt++ {
+		if attempt > 1 {
+			slog.WarnContext(ctx, "Retrying after failure", "operation", op, "attempt", attempt,
+				"max_attempts", p.Attempts(), "delay", p.Delay(attempt-1), "error", err)
+			if wErr := p.Wait(ctx, attempt-1); wErr != nil {
+				return err
+			}
+		}
+
+		if err = fn(ctx); err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
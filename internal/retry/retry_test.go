@@ -0,0 +1,72 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{MaxAttempts: 4, InitialDelay: time.Second, Multiplier: 2}
+
+	assert.Equal(t, time.Duration(0), p.Delay(0))
+	assert.Equal(t, time.Second, p.Delay(1))
+	assert.Equal(t, 2*time.Second, p.Delay(2))
+	assert.Equal(t, 4*time.Second, p.Delay(3))
+
+	constant := Policy{InitialDelay: time.Second}
+	assert.Equal(t, time.Second, constant.Delay(3))
+}
+
+func TestPolicy_Attempts(t *testing.T) {
+	assert.Equal(t, 1, Policy{}.Attempts())
+	assert.Equal(t, 3, Policy{MaxAttempts: 3}.Attempts())
+}
+
+func TestPolicy_Do_SucceedsAfterRetries(t *testing.T) {
+	p := Policy{MaxAttempts: 3, InitialDelay: time.Millisecond, Multiplier: 2}
+
+	calls := 0
+	err := p.Do(t.Context(), "test", func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestPolicy_Do_ReturnsLastError(t *testing.T) {
+	p := Policy{MaxAttempts: 2, InitialDelay: time.Millisecond}
+
+	calls := 0
+	err := p.Do(t.Context(), "test", func(context.Context) error {
+		calls++
+		return errors.New("still failing")
+	})
+
+	require.EqualError(t, err, "still failing")
+	assert.Equal(t, 2, calls)
+}
+
+func TestPolicy_Do_StopsWhenCancelled(t *testing.T) {
+	p := Policy{MaxAttempts: 5, InitialDelay: time.Hour}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	calls := 0
+	err := p.Do(ctx, "test", func(context.Context) error {
+		calls++
+		cancel()
+		return errors.New("failed")
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,522 @@
+// Package scheduler runs backup jobs on cron schedules and coordinates their shutdown.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron"
+	"github.com/robfig/cron/v3"
+)
+
+// RunFunc performs a single backup run. The context is cancelled when the run must stop.
+type RunFunc func(ctx context.Context) error
+
+// BlackoutFunc is called when a run is skipped or deferred because a blackout window is active.
+// until is the time the window ends, or the deferred run starts.
+type BlackoutFunc func(ctx context.Context, window string, until time.Time, deferred bool)
+
+// Errors a run's context is cancelled with when it must stop early, available via context.Cause.
+var (
+	ErrMaxDuration = errors.New("maximum run duration exceeded")
+	ErrBlackout    = errors.New("blackout window started")
+)
+
+// LastRunFunc returns the time of a job's last successful run, or the zero time if it never ran.
+type LastRunFunc func(ctx context.Context) (time.Time, error)
+
+// Job describes a scheduled backup job.
+type Job struct {
+	Name string
+	Cron string
+	Run  RunFunc
+
+	// Jitter delays each run by a random duration in [0, Jitter).
+	Jitter time.Duration
+
+	// Queue defers a run triggered while the previous run of the job is still in progress until
+	// that run finishes, instead of skipping it. At most one run is queued.
+	Queue bool
+
+	// LastRun enables catch-up: if a scheduled run was missed since the last successful run,
+	// the job runs once immediately at startup.
+	LastRun LastRunFunc
+
+	// Blackouts are windows during which runs are skipped or deferred. A run still going when a
+	// window starts is cancelled.
+	Blackouts []Window
+
+	// OnBlackout is notified of runs skipped or deferred by a blackout window.
+	OnBlackout BlackoutFunc
+
+	// MaxDuration cancels a run that takes longer; zero means no limit.
+	MaxDuration time.Duration
+
+	// trigger records what started the run; the zero value is a cron tick.
+	trigger Trigger
+}
+
+// Scheduler triggers backup runs on cron schedules and tracks in-flight runs
+// so they can be waited for or cancelled on shutdown.
+type Scheduler struct {
+	cron        *gocron.Scheduler
+	location    *time.Location
+	gracePeriod time.Duration
+	jobs        []Job
+
+	// runCtx is detached from the shutdown signal so running backups are only
+	// cancelled once the grace period has elapsed.
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+
+	mu        sync.Mutex
+	stopping  bool
+	running   map[string]bool
+	queued    map[string]*Job
+	deferred  map[string]bool
+	scheduled map[string]*gocron.Job
+	last      map[string]runResult
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// runResult records the outcome of a job's most recent run.
+type runResult struct {
+	started  time.Time
+	finished time.Time
+	err      error
+}
+
+// Errors returned by Trigger.
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrStopping   = errors.New("scheduler is shutting down")
+)
+
+// Errors returned by Healthy.
+var (
+	ErrNotRunning = errors.New("scheduler is not running")
+	ErrOverdue    = errors.New("scheduled run is overdue")
+)
+
+// overdueAfter is how long a job's next run may lie in the past before the scheduler is considered stalled.
+const overdueAfter = time.Minute
+
+// JobStatus describes the state of a scheduled job.
+type JobStatus struct {
+	Name     string    `json:"name"`
+	Cron     string    `json:"cron"`
+	Running  bool      `json:"running"`
+	Queued   bool      `json:"queued"`
+	Deferred bool      `json:"deferred"`
+	NextRun  time.Time `json:"next_run,omitzero"`
+
+	LastStarted  time.Time `json:"last_started,omitzero"`
+	LastFinished time.Time `json:"last_finished,omitzero"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// New creates a scheduler evaluating cron expressions in loc (UTC if nil).
+// Runs inherit values from ctx but not its cancellation.
+func New(ctx context.Context, loc *time.Location, gracePeriod time.Duration) *Scheduler {
+	if loc == nil {
+		loc = time.UTC
+	}
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	return &Scheduler{
+		cron:        gocron.NewScheduler(loc),
+		location:    loc,
+		gracePeriod: gracePeriod,
+		runCtx:      runCtx,
+		cancelRuns:  cancel,
+		running:     map[string]bool{},
+		queued:      map[string]*Job{},
+		deferred:    map[string]bool{},
+		scheduled:   map[string]*gocron.Job{},
+		last:        map[string]runResult{},
+		stop:        make(chan struct{}),
+	}
+}
+
+// Add schedules the job on its cron expression.
+func (s *Scheduler) Add(job Job) error {
+	scheduled, err := s.cron.Cron(job.Cron).Tag(job.Name).Do(func() {
+		s.execute(job)
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.scheduled[job.Name] = scheduled
+	s.jobs = append(s.jobs, job)
+	s.mu.Unlock()
+	return nil
+}
+
+// Replace swaps the scheduled jobs for jobs, evaluated in loc (unchanged if nil). Either every job
+// is rescheduled or, if a cron expression is invalid, nothing changes. Runs in progress, queued or
+// deferred keep the job they were started with; a new run of a job still running is skipped or
+// queued as usual.
+func (s *Scheduler) Replace(loc *time.Location, jobs []Job) error {
+	for _, job := range jobs {
+		if _, err := cron.ParseStandard(job.Cron); err != nil {
+			return fmt.Errorf("job %s: invalid cron expression %q: %w", job.Name, job.Cron, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopping {
+		return ErrStopping
+	}
+
+	for name, scheduled := range s.scheduled {
+		s.cron.RemoveByReference(scheduled)
+		delete(s.scheduled, name)
+	}
+	if loc != nil && loc.String() != s.location.String() {
+		s.location = loc
+		s.cron.ChangeLocation(loc)
+	}
+	s.jobs = nil
+	for _, job := range jobs {
+		scheduled, err := s.cron.Cron(job.Cron).Tag(job.Name).Do(func() {
+			s.execute(job)
+		})
+		if err != nil {
+			// Unreachable after the cron expressions were parsed above
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		s.scheduled[job.Name] = scheduled
+		s.jobs = append(s.jobs, job)
+	}
+	return nil
+}
+
+// currentLocation returns the location cron expressions are evaluated in.
+func (s *Scheduler) currentLocation() *time.Location {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.location
+}
+
+// snapshot returns the scheduled jobs.
+func (s *Scheduler) snapshot() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Job(nil), s.jobs...)
+}
+
+// Trigger starts an immediate run of the named job, or of every job if name is empty. The run
+// ignores jitter and blackout windows; if the job is already running, it is queued behind that run.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	stopping := s.stopping
+	s.mu.Unlock()
+	if stopping {
+		return ErrStopping
+	}
+
+	found := false
+	for _, job := range s.snapshot() {
+		if name != "" && job.Name != name {
+			continue
+		}
+		found = true
+
+		job.Jitter = 0
+		job.Blackouts = nil
+		job.Queue = true
+		job.trigger = TriggerManual
+		slog.InfoContext(s.runCtx, "Backup triggered on demand", "job", job.Name)
+		go s.execute(job)
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	return nil
+}
+
+// Status returns the state of every scheduled job.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := JobStatus{
+			Name:         job.Name,
+			Cron:         job.Cron,
+			Running:      s.running[job.Name],
+			Queued:       s.queued[job.Name] != nil,
+			Deferred:     s.deferred[job.Name],
+			LastStarted:  s.last[job.Name].started,
+			LastFinished: s.last[job.Name].finished,
+		}
+		if err := s.last[job.Name].err; err != nil {
+			status.LastError = err.Error()
+		}
+		if scheduled := s.scheduled[job.Name]; scheduled != nil {
+			status.NextRun = scheduled.NextRun()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Healthy reports whether the scheduler is running and starting jobs on time.
+func (s *Scheduler) Healthy() error {
+	if !s.cron.IsRunning() {
+		return ErrNotRunning
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopping {
+		return ErrStopping
+	}
+	now := time.Now()
+	for _, job := range s.jobs {
+		scheduled := s.scheduled[job.Name]
+		if scheduled == nil {
+			continue
+		}
+		if next := scheduled.NextRun(); !next.IsZero() && now.Sub(next) > overdueAfter {
+			return fmt.Errorf("%w: job %s was due at %s", ErrOverdue, job.Name, next.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// execute runs the job unless the scheduler is shutting down or the job is already running,
+// in which case the run is skipped or queued.
+func (s *Scheduler) execute(job Job) {
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		slog.WarnContext(s.runCtx, "Scheduler is shutting down; skipping backup", "job", job.Name)
+		return
+	}
+	if s.running[job.Name] {
+		if job.Queue {
+			s.queued[job.Name] = &job
+			slog.WarnContext(s.runCtx, "Previous backup still running; queueing run", "job", job.Name)
+		} else {
+			slog.WarnContext(s.runCtx, "Previous backup still running; skipping run", "job", job.Name)
+		}
+		s.mu.Unlock()
+		return
+	}
+	s.running[job.Name] = true
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+
+	for {
+		s.run(job)
+		queued := s.next(job.Name)
+		if queued == nil {
+			return
+		}
+		job = *queued
+		slog.InfoContext(s.runCtx, "Starting queued backup", "job", job.Name)
+	}
+}
+
+// next returns the queued run of the job that should start; otherwise it marks the job idle.
+func (s *Scheduler) next(name string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queued := s.queued[name]
+	delete(s.queued, name)
+	if queued != nil && !s.stopping {
+		return queued
+	}
+	s.running[name] = false
+	return nil
+}
+
+// run performs a single run of the job after its jitter delay.
+func (s *Scheduler) run(job Job) {
+	if job.Jitter > 0 {
+		delay := rand.N(job.Jitter) //nolint:gosec // jitter does not need a secure source
+		slog.DebugContext(s.runCtx, "Delaying backup by jitter", "job", job.Name, "delay", delay)
+		select {
+		case <-time.After(delay):
+		case <-s.stop:
+			slog.WarnContext(s.runCtx, "Scheduler is shutting down; skipping backup", "job", job.Name)
+			return
+		}
+	}
+
+	now := time.Now().In(s.currentLocation())
+	trigger := job.trigger
+	if trigger == "" {
+		trigger = TriggerCron
+	}
+	triggerCtx := WithTrigger(s.runCtx, trigger)
+
+	if w, until, ok := blackout(job.Blackouts, now); ok {
+		s.holdOff(triggerCtx, job, w, until)
+		return
+	}
+
+	ctx, cancel := context.WithCancelCause(triggerCtx)
+	defer cancel(nil)
+	if job.MaxDuration > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, job.MaxDuration, ErrMaxDuration)
+		defer stop()
+	}
+	if w, start := nextBlackout(job.Blackouts, now); !start.IsZero() {
+		timer := time.AfterFunc(start.Sub(now), func() {
+			slog.WarnContext(s.runCtx, "Blackout window started; cancelling backup", "job", job.Name, "window", w.Name)
+			cancel(fmt.Errorf("%w: %s", ErrBlackout, w.Name))
+		})
+		defer timer.Stop()
+	}
+
+	result := runResult{started: time.Now()}
+	err := job.Run(ctx)
+	if err != nil && ctx.Err() != nil {
+		err = context.Cause(ctx)
+	}
+	result.finished, result.err = time.Now(), err
+	s.mu.Lock()
+	s.last[job.Name] = result
+	s.mu.Unlock()
+
+	if err != nil {
+		slog.ErrorContext(s.runCtx, "Scheduled backup failed", "job", job.Name, "error", err)
+		return
+	}
+	slog.InfoContext(s.runCtx, "Scheduled backup completed successfully", "job", job.Name)
+}
+
+// holdOff skips a run due during the blackout window w, or defers it until the window ends at until.
+// Runs deferred while one is already pending are coalesced into it.
+func (s *Scheduler) holdOff(ctx context.Context, job Job, w Window, until time.Time) {
+	if !w.Defer {
+		slog.WarnContext(s.runCtx, "Blackout window active; skipping backup", "job", job.Name, "window", w.Name, "until", until)
+		if job.OnBlackout != nil {
+			job.OnBlackout(ctx, w.Name, until, false)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	pending := s.deferred[job.Name]
+	s.deferred[job.Name] = true
+	s.mu.Unlock()
+	if pending {
+		slog.InfoContext(s.runCtx, "Blackout window active; backup already deferred", "job", job.Name, "window", w.Name)
+		return
+	}
+
+	slog.WarnContext(s.runCtx, "Blackout window active; deferring backup", "job", job.Name, "window", w.Name, "until", until)
+	if job.OnBlackout != nil {
+		job.OnBlackout(ctx, w.Name, until, true)
+	}
+
+	go func() {
+		select {
+		case <-time.After(time.Until(until)):
+		case <-s.stop:
+			return
+		}
+		s.mu.Lock()
+		s.deferred[job.Name] = false
+		s.mu.Unlock()
+		slog.InfoContext(s.runCtx, "Starting deferred backup", "job", job.Name)
+		job.trigger = TriggerDeferred
+		s.execute(job)
+	}()
+}
+
+// catchUp runs the job immediately if a scheduled run was missed since its last successful run.
+func (s *Scheduler) catchUp(job Job) {
+	schedule, err := cron.ParseStandard(job.Cron)
+	if err != nil {
+		slog.WarnContext(s.runCtx, "Cannot parse cron expression; skipping catch-up", "job", job.Name, "error", err)
+		return
+	}
+
+	last, err := job.LastRun(s.runCtx)
+	if err != nil {
+		slog.WarnContext(s.runCtx, "Cannot determine last backup; skipping catch-up", "job", job.Name, "error", err)
+		return
+	}
+
+	if missed(schedule, last.In(s.currentLocation()), time.Now()) {
+		slog.InfoContext(s.runCtx, "Scheduled backup was missed; catching up", "job", job.Name, "last_backup", last)
+		job.trigger = TriggerCatchUp
+		s.execute(job)
+	}
+}
+
+// missed reports whether a run scheduled after last was due before now.
+// A zero last time means the job never ran.
+func missed(schedule cron.Schedule, last, now time.Time) bool {
+	if last.IsZero() {
+		return true
+	}
+	return !schedule.Next(last).After(now)
+}
+
+// Run starts the scheduler and blocks until ctx is done, then shuts down gracefully.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.cron.StartAsync()
+	for _, job := range s.snapshot() {
+		if job.LastRun != nil {
+			go s.catchUp(job)
+		}
+	}
+	<-ctx.Done()
+
+	slog.InfoContext(s.runCtx, "Shutdown requested; stopping scheduler")
+	// gocron's Stop waits for running job functions, so it must not delay Shutdown's grace period
+	// and cancellation of the runs it started.
+	cronStopped := make(chan struct{})
+	go func() {
+		s.cron.Stop()
+		close(cronStopped)
+	}()
+	s.Shutdown()
+	<-cronStopped
+}
+
+// Shutdown stops new runs from starting and waits for running backups. Runs still going after
+// the grace period are cancelled; Shutdown returns once they have cleaned up.
+func (s *Scheduler) Shutdown() {
+	s.mu.Lock()
+	if !s.stopping {
+		s.stopping = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	if s.gracePeriod > 0 {
+		slog.InfoContext(s.runCtx, "Waiting for running backups to finish", "grace_period", s.gracePeriod)
+		select {
+		case <-done:
+			s.cancelRuns()
+			return
+		case <-time.After(s.gracePeriod):
+			slog.WarnContext(s.runCtx, "Grace period elapsed; cancelling running backups")
+		}
+	}
+
+	s.cancelRuns()
+	<-done
+}
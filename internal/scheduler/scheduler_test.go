@@ -0,0 +1,489 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_ShutdownWaitsWithinGracePeriod(t *testing.T) {
+	s := New(t.Context(), nil, time.Second)
+
+	started := make(chan struct{})
+	var runErr error
+	go s.execute(Job{Name: "test", Run: func(ctx context.Context) error {
+		close(started)
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			runErr = ctx.Err()
+			return runErr
+		}
+	}})
+	<-started
+
+	s.Shutdown()
+	assert.NoError(t, runErr, "run finishing within the grace period must not be cancelled")
+}
+
+func TestScheduler_ShutdownCancelsAfterGracePeriod(t *testing.T) {
+	s := New(t.Context(), nil, 20*time.Millisecond)
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	go s.execute(Job{Name: "test", Run: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}})
+	<-started
+
+	s.Shutdown()
+	select {
+	case <-cancelled:
+	default:
+		t.Fatal("Shutdown returned before the run was cancelled")
+	}
+}
+
+func TestScheduler_RunCancelsCronStartedRunAfterGracePeriod(t *testing.T) {
+	s := New(t.Context(), nil, 50*time.Millisecond)
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	require.NoError(t, s.Add(Job{Name: "test", Cron: "0 0 1 1 *", Run: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}}))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	returned := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(returned)
+	}()
+	require.Eventually(t, s.cron.IsRunning, time.Second, time.Millisecond)
+	require.NoError(t, s.cron.RunByTag("test"))
+	<-started
+
+	cancel()
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after the grace period")
+	}
+	select {
+	case <-cancelled:
+	default:
+		t.Fatal("Run returned before the cron-started run was cancelled")
+	}
+}
+
+func TestScheduler_SkipsRunsWhileStopping(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+	s.Shutdown()
+
+	ran := false
+	s.execute(Job{Name: "test", Run: func(context.Context) error {
+		ran = true
+		return nil
+	}})
+	assert.False(t, ran)
+}
+
+func TestScheduler_RunContextIgnoresParentCancellation(t *testing.T) {
+	parent, cancel := context.WithCancel(t.Context())
+	s := New(parent, nil, 0)
+	cancel()
+
+	assert.NoError(t, s.runCtx.Err())
+	s.Shutdown()
+	assert.Error(t, s.runCtx.Err())
+}
+
+func TestScheduler_JitterInterruptedByShutdown(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+
+	ran := false
+	done := make(chan struct{})
+	go func() {
+		s.execute(Job{Name: "test", Jitter: time.Hour, Run: func(context.Context) error {
+			ran = true
+			return nil
+		}})
+		close(done)
+	}()
+
+	s.Shutdown()
+	<-done
+	assert.False(t, ran)
+}
+
+func TestMissed(t *testing.T) {
+	schedule, err := cron.ParseStandard("0 0 * * *")
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	assert.True(t, missed(schedule, time.Time{}, now), "never ran")
+	assert.True(t, missed(schedule, now.Add(-36*time.Hour), now), "midnight run missed")
+	assert.False(t, missed(schedule, now.Add(-6*time.Hour), now), "next run is still ahead")
+}
+
+func TestMissed_Location(t *testing.T) {
+	schedule, err := cron.ParseStandard("0 0 * * *")
+	require.NoError(t, err)
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	// Tokyo midnight falls at 15:00 UTC, between the last run at 01:00 UTC and now at 23:00 UTC.
+	now := time.Date(2024, 1, 10, 23, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 1, 10, 1, 0, 0, 0, time.UTC)
+	assert.False(t, missed(schedule, last, now), "UTC midnight is still ahead")
+	assert.True(t, missed(schedule, last.In(tokyo), now), "Tokyo midnight has passed")
+}
+
+func TestScheduler_CatchUp(t *testing.T) {
+	s := New(t.Context(), time.UTC, 0)
+
+	ran := false
+	s.catchUp(Job{
+		Name: "test",
+		Cron: "0 0 * * *",
+		LastRun: func(context.Context) (time.Time, error) {
+			return time.Now().Add(-48 * time.Hour), nil
+		},
+		Run: func(context.Context) error {
+			ran = true
+			return nil
+		},
+	})
+	assert.True(t, ran)
+
+	ran = false
+	s.catchUp(Job{
+		Name: "test",
+		Cron: "0 0 * * *",
+		LastRun: func(context.Context) (time.Time, error) {
+			return time.Now(), nil
+		},
+		Run: func(context.Context) error {
+			ran = true
+			return nil
+		},
+	})
+	assert.False(t, ran)
+}
+
+func TestScheduler_OverlapSkip(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	runs := 0
+	job := Job{Name: "test", Run: func(context.Context) error {
+		runs++
+		close(started)
+		<-release
+		return nil
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		s.execute(job)
+		close(done)
+	}()
+	<-started
+
+	// A second trigger while the first run is in progress is skipped
+	s.execute(job)
+	close(release)
+	<-done
+	assert.Equal(t, 1, runs)
+}
+
+func TestScheduler_OverlapQueue(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	runs := 0
+	job := Job{Name: "test", Queue: true, Run: func(context.Context) error {
+		runs++
+		started <- struct{}{}
+		<-release
+		return nil
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		s.execute(job)
+		close(done)
+	}()
+	<-started
+
+	// Triggers while running are coalesced into a single queued run
+	s.execute(job)
+	s.execute(job)
+	close(release)
+	<-done
+	assert.Equal(t, 2, runs)
+}
+
+func TestScheduler_BlackoutSkip(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+	w, err := NewFixedWindow("freeze", time.Now().Add(-time.Hour), time.Now().Add(time.Hour), false)
+	require.NoError(t, err)
+
+	ran := false
+	var notified string
+	s.execute(Job{
+		Name:      "test",
+		Blackouts: []Window{w},
+		Run: func(context.Context) error {
+			ran = true
+			return nil
+		},
+		OnBlackout: func(_ context.Context, window string, _ time.Time, deferred bool) {
+			assert.False(t, deferred)
+			notified = window
+		},
+	})
+	assert.False(t, ran)
+	assert.Equal(t, "freeze", notified)
+}
+
+func TestScheduler_BlackoutDefer(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+	w, err := NewFixedWindow("close", time.Now().Add(-time.Hour), time.Now().Add(50*time.Millisecond), true)
+	require.NoError(t, err)
+
+	ran := make(chan struct{}, 2)
+	deferrals := 0
+	job := Job{
+		Name:      "test",
+		Blackouts: []Window{w},
+		Run: func(context.Context) error {
+			ran <- struct{}{}
+			return nil
+		},
+		OnBlackout: func(_ context.Context, _ string, _ time.Time, deferred bool) {
+			assert.True(t, deferred)
+			deferrals++
+		},
+	}
+
+	// Runs due during the window are coalesced into a single deferred run
+	s.execute(job)
+	s.execute(job)
+	assert.Equal(t, 1, deferrals)
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("deferred run did not start after the window ended")
+	}
+	s.Shutdown()
+	assert.Empty(t, ran)
+}
+
+func TestScheduler_BlackoutCancelsRunningBackup(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+	w, err := NewFixedWindow("close", time.Now().Add(30*time.Millisecond), time.Now().Add(time.Hour), false)
+	require.NoError(t, err)
+
+	var cause error
+	s.execute(Job{Name: "test", Blackouts: []Window{w}, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return ctx.Err()
+	}})
+	require.ErrorIs(t, cause, ErrBlackout)
+	assert.Contains(t, cause.Error(), "close")
+}
+
+func TestScheduler_MaxDuration(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+
+	var cause error
+	s.execute(Job{Name: "test", MaxDuration: 20 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return ctx.Err()
+	}})
+	require.ErrorIs(t, cause, ErrMaxDuration)
+}
+
+func TestScheduler_Trigger(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+
+	ran := make(chan string, 2)
+	for _, name := range []string{"orders", "crm"} {
+		require.NoError(t, s.Add(Job{Name: name, Cron: "0 0 * * *", Jitter: time.Hour, Run: func(context.Context) error {
+			ran <- name
+			return nil
+		}}))
+	}
+
+	require.NoError(t, s.Trigger("orders"))
+	select {
+	case name := <-ran:
+		assert.Equal(t, "orders", name, "triggered runs skip jitter")
+	case <-time.After(time.Second):
+		t.Fatal("triggered run did not start")
+	}
+
+	require.ErrorIs(t, s.Trigger("missing"), ErrUnknownJob)
+
+	s.Shutdown()
+	require.ErrorIs(t, s.Trigger(""), ErrStopping)
+}
+
+func TestScheduler_TriggerQueuesBehindRunningBackup(t *testing.T) {
+	s := New(t.Context(), nil, 0)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	// Overlapping scheduled runs are skipped, but an on-demand run is never dropped
+	require.NoError(t, s.Add(Job{Name: "test", Cron: "0 0 * * *", Run: func(context.Context) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}}))
+
+	done := make(chan struct{})
+	go func() {
+		s.execute(s.jobs[0])
+		close(done)
+	}()
+	<-started
+
+	require.NoError(t, s.Trigger("test"))
+	require.Eventually(t, func() bool { return s.Status()[0].Queued }, time.Second, time.Millisecond)
+
+	close(release)
+	<-done
+	assert.Len(t, started, 1, "the queued run started after the first one finished")
+}
+
+func TestScheduler_Status(t *testing.T) {
+	s := New(t.Context(), time.UTC, 0)
+	require.NoError(t, s.Add(Job{Name: "test", Cron: "0 0 * * *", Run: func(context.Context) error {
+		return assert.AnError
+	}}))
+	s.cron.StartAsync()
+	defer s.cron.Stop()
+
+	s.execute(s.jobs[0])
+
+	statuses := s.Status()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "test", statuses[0].Name)
+	assert.False(t, statuses[0].Running)
+	assert.False(t, statuses[0].NextRun.IsZero())
+	assert.False(t, statuses[0].LastFinished.Before(statuses[0].LastStarted))
+	assert.Equal(t, assert.AnError.Error(), statuses[0].LastError)
+}
+
+func TestScheduler_Healthy(t *testing.T) {
+	s := New(t.Context(), time.UTC, 0)
+	require.NoError(t, s.Add(Job{Name: "test", Cron: "0 0 * * *", Run: func(context.Context) error { return nil }}))
+	require.ErrorIs(t, s.Healthy(), ErrNotRunning)
+
+	s.cron.StartAsync()
+	defer s.cron.Stop()
+	require.NoError(t, s.Healthy())
+
+	s.Shutdown()
+	require.ErrorIs(t, s.Healthy(), ErrStopping)
+}
+
+func TestScheduler_RunTrigger(t *testing.T) {
+	s := New(t.Context(), time.UTC, 0)
+
+	triggers := make(chan Trigger, 1)
+	job := Job{
+		Name: "test",
+		Cron: "0 0 * * *",
+		Run: func(ctx context.Context) error {
+			triggers <- TriggerFrom(ctx)
+			return nil
+		},
+		LastRun: func(context.Context) (time.Time, error) { return time.Time{}, nil },
+	}
+	require.NoError(t, s.Add(job))
+
+	s.execute(job)
+	assert.Equal(t, TriggerCron, <-triggers)
+
+	s.catchUp(job)
+	assert.Equal(t, TriggerCatchUp, <-triggers)
+
+	require.NoError(t, s.Trigger("test"))
+	assert.Equal(t, TriggerManual, <-triggers)
+
+	assert.Equal(t, TriggerManual, TriggerFrom(context.Background()), "runs outside the scheduler are manual")
+}
+
+func TestScheduler_Replace(t *testing.T) {
+	s := New(t.Context(), nil, time.Second)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var oldErr error
+	require.NoError(t, s.Add(Job{Name: "orders", Cron: "0 0 * * *", Run: func(ctx context.Context) error {
+		close(started)
+		<-release
+		oldErr = ctx.Err()
+		return nil
+	}}))
+	s.cron.StartAsync()
+	defer s.cron.Stop()
+	require.NoError(t, s.Trigger("orders"))
+	<-started
+
+	// An invalid job leaves the schedule unchanged
+	err := s.Replace(nil, []Job{{Name: "orders", Cron: "0 1 * * *"}, {Name: "crm", Cron: "bogus"}})
+	require.ErrorContains(t, err, `job crm: invalid cron expression "bogus"`)
+	assert.Equal(t, []string{"0 0 * * *"}, crons(s.Status()))
+
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	require.NoError(t, err)
+	ran := make(chan string, 1)
+	require.NoError(t, s.Replace(sydney, []Job{
+		{Name: "orders", Cron: "30 2 * * *", Run: func(context.Context) error { ran <- "new orders"; return nil }},
+		{Name: "crm", Cron: "0 3 * * *"},
+	}))
+
+	status := s.Status()
+	assert.Equal(t, []string{"30 2 * * *", "0 3 * * *"}, crons(status))
+	assert.True(t, status[0].Running, "the run in progress continues")
+	assert.Equal(t, 2, status[0].NextRun.In(sydney).Hour(), "cron expressions are evaluated in the new location")
+
+	// The run in progress is not disturbed, and a new run of the job is queued behind it
+	require.NoError(t, s.Trigger("orders"))
+	close(release)
+	select {
+	case name := <-ran:
+		assert.Equal(t, "new orders", name)
+	case <-time.After(time.Second):
+		t.Fatal("queued run of the replaced job did not start")
+	}
+	s.Shutdown()
+	require.NoError(t, oldErr)
+	require.ErrorIs(t, s.Replace(nil, nil), ErrStopping)
+}
+
+func crons(statuses []JobStatus) []string {
+	out := []string{}
+	for _, st := range statuses {
+		out = append(out, st.Cron)
+	}
+	return out
+}
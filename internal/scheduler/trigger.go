@@ -0,0 +1,29 @@
+package scheduler
+
+import "context"
+
+// Trigger identifies what started a run.
+type Trigger string
+
+// Triggers reported by TriggerFrom.
+const (
+	TriggerCron     Trigger = "cron"
+	TriggerCatchUp  Trigger = "catch-up"
+	TriggerDeferred Trigger = "deferred"
+	TriggerManual   Trigger = "manual"
+)
+
+type triggerKey struct{}
+
+// WithTrigger returns a context recording what started the run.
+func WithTrigger(ctx context.Context, t Trigger) context.Context {
+	return context.WithValue(ctx, triggerKey{}, t)
+}
+
+// TriggerFrom returns what started the run. Runs not started by the scheduler are manual.
+func TriggerFrom(ctx context.Context) Trigger {
+	if t, ok := ctx.Value(triggerKey{}).(Trigger); ok {
+		return t
+	}
+	return TriggerManual
+}
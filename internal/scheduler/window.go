@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxChainedWindows bounds how many back-to-back windows a deferred run waits through.
+const maxChainedWindows = 100
+
+// Window is a blackout period during which scheduled runs must not happen. It either recurs,
+// starting on a cron schedule and lasting a fixed duration, or covers a single fixed period.
+type Window struct {
+	Name string
+
+	// Defer postpones a run due during the window until the window ends; otherwise the run is skipped.
+	Defer bool
+
+	start    cron.Schedule
+	duration time.Duration
+
+	from time.Time
+	to   time.Time
+}
+
+// NewRecurringWindow creates a window starting on the cron expression spec and lasting duration.
+func NewRecurringWindow(name, spec string, duration time.Duration, deferRuns bool) (Window, error) {
+	if duration <= 0 {
+		return Window{}, errors.New("duration must be positive")
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid cron expression: %w", err)
+	}
+	return Window{Name: name, Defer: deferRuns, start: schedule, duration: duration}, nil
+}
+
+// NewFixedWindow creates a window covering [from, to).
+func NewFixedWindow(name string, from, to time.Time, deferRuns bool) (Window, error) {
+	if !to.After(from) {
+		return Window{}, errors.New("end must be after start")
+	}
+	return Window{Name: name, Defer: deferRuns, from: from, to: to}, nil
+}
+
+// activeAt returns the end of the window if it is active at t.
+// Recurring windows are evaluated in t's location.
+func (w Window) activeAt(t time.Time) (time.Time, bool) {
+	if w.start == nil {
+		return w.to, !t.Before(w.from) && t.Before(w.to)
+	}
+
+	// The window is active if it started within the last duration; the latest start ends last.
+	var end time.Time
+	for start := w.start.Next(t.Add(-w.duration)); !start.IsZero() && !start.After(t); start = w.start.Next(start) {
+		end = start.Add(w.duration)
+	}
+	return end, !end.IsZero()
+}
+
+// nextStart returns the first start of the window after t, or the zero time if it never starts again.
+func (w Window) nextStart(t time.Time) time.Time {
+	if w.start == nil {
+		if w.from.After(t) {
+			return w.from
+		}
+		return time.Time{}
+	}
+	return w.start.Next(t)
+}
+
+// active returns the window active at t that ends last, preferring windows that skip runs.
+func active(windows []Window, t time.Time) (Window, time.Time, bool) {
+	var (
+		found Window
+		until time.Time
+		ok    bool
+	)
+	for _, w := range windows {
+		end, isActive := w.activeAt(t)
+		if !isActive {
+			continue
+		}
+		if !w.Defer {
+			return w, end, true
+		}
+		if !ok || end.After(until) {
+			found, until, ok = w, end, true
+		}
+	}
+	return found, until, ok
+}
+
+// blackout reports whether a window is active at t. If an active window skips runs, that window is
+// returned. Otherwise runs are deferred until the returned time, when no deferring window is active.
+func blackout(windows []Window, t time.Time) (Window, time.Time, bool) {
+	w, until, ok := active(windows, t)
+	if !ok || !w.Defer {
+		return w, until, ok
+	}
+
+	// Windows chained back to back keep the run deferred; the bound stops windows that never end
+	for range maxChainedWindows {
+		next, end, nextOK := active(windows, until)
+		if !nextOK || !next.Defer {
+			break
+		}
+		until = end
+	}
+	return w, until, true
+}
+
+// nextBlackout returns the window starting first after t, and when it starts.
+func nextBlackout(windows []Window, t time.Time) (Window, time.Time) {
+	var (
+		first Window
+		at    time.Time
+	)
+	for _, w := range windows {
+		start := w.nextStart(t)
+		if start.IsZero() {
+			continue
+		}
+		if at.IsZero() || start.Before(at) {
+			first, at = w, start
+		}
+	}
+	return first, at
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindow_Recurring(t *testing.T) {
+	// Daily closing run from 18:00 to 22:00
+	w, err := NewRecurringWindow("close", "0 18 * * *", 4*time.Hour, false)
+	require.NoError(t, err)
+
+	day := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	end, ok := w.activeAt(day.Add(19 * time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, day.Add(22*time.Hour), end)
+
+	_, ok = w.activeAt(day.Add(17 * time.Hour))
+	assert.False(t, ok, "before the window")
+	_, ok = w.activeAt(day.Add(22 * time.Hour))
+	assert.False(t, ok, "the window ends exclusively")
+
+	assert.Equal(t, day.Add(18*time.Hour), w.nextStart(day.Add(12*time.Hour)))
+}
+
+func TestWindow_RecurringLocation(t *testing.T) {
+	w, err := NewRecurringWindow("close", "0 18 * * *", time.Hour, false)
+	require.NoError(t, err)
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	// 09:30 UTC is 18:30 in Tokyo
+	now := time.Date(2024, 1, 31, 9, 30, 0, 0, time.UTC)
+	_, ok := w.activeAt(now)
+	assert.False(t, ok)
+	_, ok = w.activeAt(now.In(tokyo))
+	assert.True(t, ok)
+}
+
+func TestWindow_Fixed(t *testing.T) {
+	from := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	w, err := NewFixedWindow("freeze", from, to, true)
+	require.NoError(t, err)
+
+	end, ok := w.activeAt(from.Add(time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, to, end)
+	_, ok = w.activeAt(to)
+	assert.False(t, ok)
+
+	assert.Equal(t, from, w.nextStart(from.Add(-time.Hour)))
+	assert.True(t, w.nextStart(from).IsZero(), "fixed windows start once")
+}
+
+func TestNewWindow_Invalid(t *testing.T) {
+	_, err := NewRecurringWindow("w", "not a cron", time.Hour, false)
+	require.Error(t, err)
+	_, err = NewRecurringWindow("w", "0 18 * * *", 0, false)
+	require.Error(t, err)
+
+	now := time.Now()
+	_, err = NewFixedWindow("w", now, now, false)
+	require.Error(t, err)
+}
+
+func TestBlackout(t *testing.T) {
+	day := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	first, err := NewRecurringWindow("first", "0 18 * * *", 2*time.Hour, true)
+	require.NoError(t, err)
+	second, err := NewFixedWindow("second", day.Add(20*time.Hour), day.Add(23*time.Hour), true)
+	require.NoError(t, err)
+	skip, err := NewFixedWindow("skip", day.Add(19*time.Hour), day.Add(21*time.Hour), false)
+	require.NoError(t, err)
+
+	_, _, ok := blackout([]Window{first, second}, day.Add(12*time.Hour))
+	assert.False(t, ok)
+
+	// Back-to-back deferring windows defer the run until the last one ends
+	w, until, ok := blackout([]Window{first, second}, day.Add(18*time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, "first", w.Name)
+	assert.Equal(t, day.Add(23*time.Hour), until)
+
+	// A skipping window wins over deferring ones
+	w, until, ok = blackout([]Window{first, skip}, day.Add(19*time.Hour+30*time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, "skip", w.Name)
+	assert.False(t, w.Defer)
+	assert.Equal(t, day.Add(21*time.Hour), until)
+}
+
+func TestNextBlackout(t *testing.T) {
+	day := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	daily, err := NewRecurringWindow("daily", "0 18 * * *", time.Hour, false)
+	require.NoError(t, err)
+	fixed, err := NewFixedWindow("fixed", day.Add(15*time.Hour), day.Add(16*time.Hour), false)
+	require.NoError(t, err)
+
+	w, at := nextBlackout([]Window{daily, fixed}, day.Add(12*time.Hour))
+	assert.Equal(t, "fixed", w.Name)
+	assert.Equal(t, day.Add(15*time.Hour), at)
+
+	w, at = nextBlackout([]Window{daily, fixed}, day.Add(16*time.Hour))
+	assert.Equal(t, "daily", w.Name)
+	assert.Equal(t, day.Add(18*time.Hour), at)
+
+	_, at = nextBlackout(nil, day)
+	assert.True(t, at.IsZero())
+}
@@ -0,0 +1,105 @@
+// Package secrets resolves references such as file:///run/secrets/pg-password, env://PGPASSWORD and
+// vault://secret/stashly#password into the secret values they point to.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownScheme is returned for a reference whose scheme has no registered provider.
+var ErrUnknownScheme = errors.New("unknown secret provider")
+
+// schemeRe matches URL schemes (RFC 3986), so that values such as "p@ss://word" are not mistaken for references.
+var schemeRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*$`)
+
+// urlSchemes are the schemes of secret settings that are URLs, such as webhooks, rather than references.
+var urlSchemes = []string{"http", "https"}
+
+// Provider looks up secrets in an external source.
+type Provider interface {
+	// Scheme is the URL scheme of the references the provider resolves, e.g. "vault".
+	Scheme() string
+
+	// Resolve returns the secret named by ref, the reference without its "<scheme>://" prefix.
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// Resolver dispatches references to the provider registered for their scheme.
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver creates a resolver for the given providers.
+func NewResolver(providers ...Provider) *Resolver {
+	r := &Resolver{providers: map[string]Provider{}}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds a provider, replacing any provider registered for the same scheme.
+func (r *Resolver) Register(p Provider) {
+	r.providers[p.Scheme()] = p
+}
+
+// Resolve returns the secret a reference points to. Values that are not references, such as plain
+// passwords or https:// webhooks, are returned unchanged. A reference to a scheme without a registered
+// provider, such as a mistyped "vualt://", returns an error wrapping ErrUnknownScheme rather than
+// being used as the secret.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := strings.Cut(value, "://")
+	if !ok || !schemeRe.MatchString(scheme) || slices.Contains(urlSchemes, strings.ToLower(scheme)) {
+		return value, nil
+	}
+	p, ok := r.providers[scheme]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownScheme, scheme)
+	}
+	secret, err := p.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("%s secret: %w", scheme, err)
+	}
+	return secret, nil
+}
+
+// File resolves file:// references to the content of the file, without a trailing newline.
+type File struct{}
+
+// Scheme implements Provider.
+func (File) Scheme() string { return "file" }
+
+// Resolve implements Provider.
+func (File) Resolve(_ context.Context, path string) (string, error) {
+	return ReadFile(path)
+}
+
+// ReadFile reads a secret from a file, such as a Docker or Kubernetes secret, trimming the trailing newline.
+func ReadFile(path string) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // the path is configured by the operator
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Env resolves env:// references to the value of the environment variable.
+type Env struct{}
+
+// Scheme implements Provider.
+func (Env) Scheme() string { return "env" }
+
+// Resolve implements Provider.
+func (Env) Resolve(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,45 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(secretFile, []byte("s3cr3t\n"), 0600))
+	t.Setenv("STASHLY_TEST_SECRET", "from-env")
+
+	r := NewResolver(File{}, Env{})
+
+	for value, want := range map[string]string{
+		"plain":                     "plain",
+		"":                          "",
+		"https://discord.com/hook":  "https://discord.com/hook",
+		"HTTP://gotify.local":       "HTTP://gotify.local",
+		"p@ss://word":               "p@ss://word",
+		"file://" + secretFile:      "s3cr3t",
+		"env://STASHLY_TEST_SECRET": "from-env",
+	} {
+		got, err := r.Resolve(ctx, value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	// A mistyped or unregistered scheme is not used as the secret itself
+	_, err := r.Resolve(ctx, "vualt://secret/stashly#password")
+	require.ErrorIs(t, err, ErrUnknownScheme)
+	require.EqualError(t, err, `unknown secret provider "vualt"`)
+
+	_, err = r.Resolve(ctx, "env://STASHLY_TEST_UNSET")
+	require.EqualError(t, err, "env secret: environment variable STASHLY_TEST_UNSET is not set")
+
+	_, err = r.Resolve(ctx, "file://"+filepath.Join(dir, "missing"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,176 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultVaultTimeout bounds each request to Vault unless configured otherwise.
+const defaultVaultTimeout = 10 * time.Second
+
+// VaultOptions configures the Vault KV provider.
+type VaultOptions struct {
+	// Address is the base URL of the Vault server, e.g. https://vault.example.com:8200.
+	Address string
+	Token   string
+
+	// Namespace is sent as X-Vault-Namespace (Vault Enterprise / HCP); empty for the root namespace.
+	Namespace string
+
+	// KVVersion is the version of the KV secrets engine, 1 or 2 (default).
+	KVVersion int
+	Timeout   time.Duration
+}
+
+// Vault resolves vault://<mount>/<path>#<key> references from a HashiCorp Vault KV secrets engine,
+// e.g. vault://secret/stashly/postgres#password. The key may be omitted if the secret has a single key.
+// Each secret is read once and cached, so several keys of a secret cost one request.
+type Vault struct {
+	opts   VaultOptions
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]map[string]any
+}
+
+// NewVault creates a Vault KV provider.
+func NewVault(opts VaultOptions) *Vault {
+	if opts.KVVersion == 0 {
+		opts.KVVersion = 2
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultVaultTimeout
+	}
+	return &Vault{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		cache:  map[string]map[string]any{},
+	}
+}
+
+// Scheme implements Provider.
+func (v *Vault) Scheme() string { return "vault" }
+
+// Resolve implements Provider.
+func (v *Vault) Resolve(ctx context.Context, ref string) (string, error) {
+	secretPath, key, _ := strings.Cut(ref, "#")
+	mount, secretPath, ok := strings.Cut(strings.Trim(secretPath, "/"), "/")
+	if !ok || mount == "" || secretPath == "" {
+		return "", fmt.Errorf("invalid reference %q: want vault://<mount>/<path>#<key>", ref)
+	}
+
+	data, err := v.read(ctx, mount, secretPath)
+	if err != nil {
+		return "", fmt.Errorf("%s/%s: %w", mount, secretPath, err)
+	}
+
+	if key == "" {
+		if len(data) != 1 {
+			keys := make([]string, 0, len(data))
+			for k := range data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return "", fmt.Errorf("%s/%s has keys %s; select one with #<key>", mount, secretPath, strings.Join(keys, ", "))
+		}
+		for k := range data {
+			key = k
+		}
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("%s/%s has no key %q", mount, secretPath, key)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s/%s key %q is not a string", mount, secretPath, key)
+	}
+	return s, nil
+}
+
+// read returns the key/value pairs of a secret.
+func (v *Vault) read(ctx context.Context, mount, secretPath string) (map[string]any, error) {
+	cacheKey := mount + "/" + secretPath
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if data, ok := v.cache[cacheKey]; ok {
+		return data, nil
+	}
+
+	if v.opts.Address == "" {
+		return nil, errors.New("vault address is not set")
+	}
+	endpoint, err := url.Parse(v.opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault address: %w", err)
+	}
+	if v.opts.KVVersion == 2 {
+		endpoint = endpoint.JoinPath("v1", mount, "data", secretPath)
+	} else {
+		endpoint = endpoint.JoinPath("v1", mount, secretPath)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.opts.Token)
+	if v.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.opts.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errors.New("secret not found")
+	case resp.StatusCode == http.StatusForbidden:
+		return nil, errors.New("permission denied")
+	case resp.StatusCode != http.StatusOK:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected vault status code: %d", resp.StatusCode)
+	}
+
+	// KV v2 nests the key/value pairs under data.data, next to data.metadata
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid vault response: %w", err)
+	}
+	raw := body.Data
+	if v.opts.KVVersion == 2 {
+		var nested struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err = json.Unmarshal(raw, &nested); err != nil {
+			return nil, fmt.Errorf("invalid vault response: %w", err)
+		}
+		raw = nested.Data
+	}
+	var data map[string]any
+	if err = json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("invalid vault response: %w", err)
+	}
+	if data == nil {
+		// A deleted KV v2 version returns null data
+		return nil, errors.New("secret not found")
+	}
+
+	v.cache[cacheKey] = data
+	return data, nil
+}
@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFakeVault(t *testing.T, requests *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/stashly/postgres":
+			assert.Equal(t, "team-a", r.Header.Get("X-Vault-Namespace"))
+			_, _ = w.Write([]byte(`{"data":{"data":{"user":"backup","password":"pg-pass"},"metadata":{"version":3}}}`))
+		case "/v1/secret/data/stashly/token":
+			_, _ = w.Write([]byte(`{"data":{"data":{"value":"tok"}}}`))
+		case "/v1/kv/stashly/s3":
+			_, _ = w.Write([]byte(`{"data":{"secret-key":"s3-pass"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVault_Resolve(t *testing.T) {
+	ctx := t.Context()
+	requests := 0
+	srv := newFakeVault(t, &requests)
+	v := NewVault(VaultOptions{Address: srv.URL, Token: "root", Namespace: "team-a"})
+
+	got, err := v.Resolve(ctx, "secret/stashly/postgres#password")
+	require.NoError(t, err)
+	assert.Equal(t, "pg-pass", got)
+
+	// The secret is cached
+	got, err = v.Resolve(ctx, "secret/stashly/postgres#user")
+	require.NoError(t, err)
+	assert.Equal(t, "backup", got)
+	assert.Equal(t, 1, requests)
+
+	got, err = v.Resolve(ctx, "secret/stashly/token")
+	require.NoError(t, err)
+	assert.Equal(t, "tok", got)
+
+	_, err = v.Resolve(ctx, "secret/stashly/postgres")
+	require.EqualError(t, err, "secret/stashly/postgres has keys password, user; select one with #<key>")
+
+	_, err = v.Resolve(ctx, "secret/stashly/postgres#missing")
+	require.EqualError(t, err, `secret/stashly/postgres has no key "missing"`)
+
+	_, err = v.Resolve(ctx, "secret/stashly/missing#password")
+	require.EqualError(t, err, "secret/stashly/missing: secret not found")
+
+	_, err = v.Resolve(ctx, "secret")
+	require.Error(t, err)
+}
+
+func TestVault_ResolveKVv1(t *testing.T) {
+	requests := 0
+	srv := newFakeVault(t, &requests)
+	v := NewVault(VaultOptions{Address: srv.URL, Token: "root", KVVersion: 1})
+
+	got, err := v.Resolve(t.Context(), "kv/stashly/s3#secret-key")
+	require.NoError(t, err)
+	assert.Equal(t, "s3-pass", got)
+}
+
+func TestVault_PermissionDenied(t *testing.T) {
+	requests := 0
+	srv := newFakeVault(t, &requests)
+	v := NewVault(VaultOptions{Address: srv.URL, Token: "wrong"})
+
+	_, err := v.Resolve(t.Context(), "secret/stashly/token")
+	require.EqualError(t, err, "secret/stashly/token: permission denied")
+}
+
+// TestVault_DevServer runs against a Vault dev-mode server, e.g. the vault service of
+// docker-compose.dev.yml: STASHLY_TEST_VAULT_ADDR=http://127.0.0.1:8200 STASHLY_TEST_VAULT_TOKEN=root.
+func TestVault_DevServer(t *testing.T) {
+	addr, token := os.Getenv("STASHLY_TEST_VAULT_ADDR"), os.Getenv("STASHLY_TEST_VAULT_TOKEN")
+	if addr == "" {
+		t.Skip("STASHLY_TEST_VAULT_ADDR not set")
+	}
+
+	// Dev mode mounts a KV v2 engine at secret/
+	body, err := json.Marshal(map[string]any{"data": map[string]string{"password": "dev-pass"}})
+	require.NoError(t, err)
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, addr+"/v1/secret/data/stashly-test", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("X-Vault-Token", token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	got, err := NewVault(VaultOptions{Address: addr, Token: token}).Resolve(t.Context(), "secret/stashly-test#password")
+	require.NoError(t, err)
+	assert.Equal(t, "dev-pass", got)
+}
@@ -0,0 +1,87 @@
+// Package server serves the daemon's optional HTTP endpoints, such as Prometheus metrics and the REST API.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// readHeaderTimeout bounds how long a client may take to send request headers.
+const readHeaderTimeout = 5 * time.Second
+
+// Server serves registered handlers on a TCP address.
+type Server struct {
+	addr string
+	mux  *http.ServeMux
+	srv  *http.Server
+	ln   net.Listener
+
+	certFile, keyFile string
+}
+
+// New creates a server for addr, such as ":9090". Handlers are added with Handle before Start.
+func New(addr string) *Server {
+	mux := http.NewServeMux()
+	return &Server{
+		addr: addr,
+		mux:  mux,
+		srv:  &http.Server{Handler: mux, ReadHeaderTimeout: readHeaderTimeout},
+	}
+}
+
+// Handle registers the handler for the given pattern.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// UseTLS serves HTTPS with the PEM-encoded certificate and key files instead of plain HTTP.
+func (s *Server) UseTLS(certFile, keyFile string) {
+	s.certFile, s.keyFile = certFile, keyFile
+}
+
+// Start listens on the address and serves requests in the background.
+func (s *Server) Start(ctx context.Context) error {
+	serve := s.srv.Serve
+	if s.certFile != "" {
+		// Load the key pair up front, so a bad certificate fails Start rather than the background server
+		cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return fmt.Errorf("error loading TLS certificate: %w", err)
+		}
+		s.srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		serve = func(ln net.Listener) error { return s.srv.ServeTLS(ln, "", "") }
+	}
+
+	ln, err := (&net.ListenConfig{}).Listen(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", s.addr, err)
+	}
+	s.ln = ln
+
+	go func() {
+		if sErr := serve(ln); sErr != nil && !errors.Is(sErr, http.ErrServerClosed) {
+			slog.ErrorContext(ctx, "HTTP server stopped", "error", sErr)
+		}
+	}()
+	slog.InfoContext(ctx, "Listening for HTTP requests", "address", ln.Addr().String(), "tls", s.certFile != "")
+	return nil
+}
+
+// Addr returns the address the server is listening on, or nil before Start.
+func (s *Server) Addr() net.Addr {
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Close stops serving, waiting for in-flight requests until ctx is done.
+func (s *Server) Close(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
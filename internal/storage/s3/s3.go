@@ -2,11 +2,27 @@
 package s3
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsS3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	commonS3 "github.com/hibare/GoCommon/v2/pkg/s3"
 	"github.com/hibare/stashly/internal/config"
+	"github.com/hibare/stashly/internal/storage"
+	"github.com/hibare/stashly/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // S3 implements the StorageIface for S3-compatible storage backends.
@@ -45,12 +61,38 @@ func (s *S3) Name() string {
 	return fmt.Sprintf("s3 (%s)", s.s3.Bucket)
 }
 
+// Ping checks that the bucket exists and is accessible with the configured credentials.
+func (s *S3) Ping(ctx context.Context) (err error) {
+	ctx, span := s.startSpan(ctx, "storage.Ping", "")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = awsS3.New(s.s3.Sess).HeadBucketWithContext(ctx, &awsS3.HeadBucketInput{
+		Bucket: aws.String(s.s3.Bucket),
+	})
+	return mapError(err)
+}
+
 // Upload uploads a local file to S3 and returns the remote key/path.
-func (s *S3) Upload(localPath string) (string, error) {
-	key, err := s.s3.UploadFile(localPath)
+// Multipart uploads are aborted if ctx is cancelled or the upload fails.
+func (s *S3) Upload(ctx context.Context, localPath string) (_ string, err error) {
+	ctx, span := s.startSpan(ctx, "storage.Upload", filepath.Base(localPath))
+	defer func() { tracing.End(span, err) }()
+
+	f, err := os.Open(localPath) //nolint:gosec // path is produced by the dumpster
 	if err != nil {
 		return "", err
 	}
+	defer func() { _ = f.Close() }()
+
+	key := filepath.Join(s.s3.Prefix, filepath.Base(localPath))
+	uploader := s3manager.NewUploader(s.s3.Sess)
+	if _, err = uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.s3.Bucket),
+		Key:    aws.String(key),
+		Body:   f,
+	}); err != nil {
+		return "", err
+	}
 	return key, nil
 }
 
@@ -78,3 +120,145 @@ func (s *S3) Delete(key string) error {
 func (s *S3) TrimPrefix(keys []string) []string {
 	return s.s3.TrimPrefix(keys)
 }
+
+// backupPrefix returns the prefix under which the instance's backups are stored.
+func (s *S3) backupPrefix() string {
+	return path.Join(s.cfg.S3.Prefix, s.cfg.App.InstanceID) + "/"
+}
+
+// Objects lists the objects making up the backup key, as returned by List, or of every backup if key is empty.
+func (s *S3) Objects(ctx context.Context, key string) (_ []storage.ObjectInfo, err error) {
+	ctx, span := s.startSpan(ctx, "storage.Objects", key)
+	defer func() { tracing.End(span, err) }()
+
+	prefix := s.backupPrefix()
+	if key != "" {
+		prefix += strings.TrimSuffix(key, "/") + "/"
+	}
+	objects := []storage.ObjectInfo{}
+	err = awsS3.New(s.s3.Sess).ListObjectsV2PagesWithContext(ctx, &awsS3.ListObjectsV2Input{
+		Bucket: aws.String(s.s3.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *awsS3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range page.Contents {
+			if aws.StringValue(obj.Key) == prefix {
+				continue
+			}
+			objects = append(objects, storage.ObjectInfo{
+				Key:          strings.TrimPrefix(aws.StringValue(obj.Key), s.backupPrefix()),
+				Size:         aws.Int64Value(obj.Size),
+				LastModified: aws.TimeValue(obj.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return objects, nil
+}
+
+// Download opens an object returned by Objects for reading.
+func (s *S3) Download(ctx context.Context, key string) (_ io.ReadCloser, err error) {
+	ctx, span := s.startSpan(ctx, "storage.Download", key)
+	defer func() { tracing.End(span, err) }()
+
+	out, err := awsS3.New(s.s3.Sess).GetObjectWithContext(ctx, &awsS3.GetObjectInput{
+		Bucket: aws.String(s.s3.Bucket),
+		Key:    aws.String(s.backupPrefix() + key),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return out.Body, nil
+}
+
+// startSpan starts a span for an operation on key in the bucket.
+func (s *S3) startSpan(ctx context.Context, name, key string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name,
+		attribute.String("stashly.storage", s.Name()),
+		attribute.String("stashly.key", key))
+}
+
+// objectKey returns the full key of an object relative to the configured root prefix.
+func (s *S3) objectKey(key string) string {
+	return path.Join(s.cfg.S3.Prefix, key)
+}
+
+// mapError converts S3 request failures into storage errors.
+func mapError(err error) error {
+	var reqErr awserr.RequestFailure
+	if !errors.As(err, &reqErr) {
+		return err
+	}
+	switch reqErr.StatusCode() {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %w", storage.ErrNotExist, err)
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return fmt.Errorf("%w: %w", storage.ErrPreconditionFailed, err)
+	default:
+		return err
+	}
+}
+
+// PutObject writes data to key, relative to the configured root prefix, and returns the new ETag.
+// Conditional writes rely on S3 If-None-Match / If-Match support.
+func (s *S3) PutObject(ctx context.Context, key string, data []byte, opts storage.PutOptions) (_ string, err error) {
+	ctx, span := s.startSpan(ctx, "storage.PutObject", key)
+	defer func() { tracing.End(span, err) }()
+
+	req, out := awsS3.New(s.s3.Sess).PutObjectRequest(&awsS3.PutObjectInput{
+		Bucket: aws.String(s.s3.Bucket),
+		Key:    aws.String(s.objectKey(key)),
+		Body:   bytes.NewReader(data),
+	})
+	req.SetContext(ctx)
+	if opts.IfNoneMatch {
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	}
+	if opts.IfMatch != "" {
+		req.HTTPRequest.Header.Set("If-Match", opts.IfMatch)
+	}
+	if err = req.Send(); err != nil {
+		return "", mapError(err)
+	}
+	return aws.StringValue(out.ETag), nil
+}
+
+// GetObject reads key, relative to the configured root prefix, and returns its data and ETag.
+func (s *S3) GetObject(ctx context.Context, key string) (_ []byte, _ string, err error) {
+	ctx, span := s.startSpan(ctx, "storage.GetObject", key)
+	defer func() { tracing.End(span, err) }()
+
+	out, err := awsS3.New(s.s3.Sess).GetObjectWithContext(ctx, &awsS3.GetObjectInput{
+		Bucket: aws.String(s.s3.Bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return nil, "", mapError(err)
+	}
+	defer func() { _ = out.Body.Close() }()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, aws.StringValue(out.ETag), nil
+}
+
+// DeleteObject deletes key, relative to the configured root prefix.
+// Conditional deletes rely on S3 If-Match support.
+func (s *S3) DeleteObject(ctx context.Context, key string, opts storage.DeleteOptions) (err error) {
+	ctx, span := s.startSpan(ctx, "storage.DeleteObject", key)
+	defer func() { tracing.End(span, err) }()
+
+	req, _ := awsS3.New(s.s3.Sess).DeleteObjectRequest(&awsS3.DeleteObjectInput{
+		Bucket: aws.String(s.s3.Bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	req.SetContext(ctx)
+	if opts.IfMatch != "" {
+		req.HTTPRequest.Header.Set("If-Match", opts.IfMatch)
+	}
+	return mapError(req.Send())
+}
@@ -1,6 +1,48 @@
 // Package storage defines the interface for various storage backends.
 package storage
 
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrNotExist is returned when an object does not exist.
+	ErrNotExist = errors.New("object does not exist")
+
+	// ErrPreconditionFailed is returned when a conditional write loses to a concurrent writer.
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// MetadataDir is the directory, relative to the storage root, holding Stashly's own objects
+// (locks, ...). It is never treated as a backup.
+const MetadataDir = ".stashly"
+
+// PutOptions makes an object write conditional.
+type PutOptions struct {
+	// IfNoneMatch fails the write with ErrPreconditionFailed if the object already exists.
+	IfNoneMatch bool
+
+	// IfMatch fails the write with ErrPreconditionFailed unless the object's current ETag matches.
+	IfMatch string
+}
+
+// DeleteOptions makes an object delete conditional.
+type DeleteOptions struct {
+	// IfMatch fails the delete with ErrPreconditionFailed unless the object's current ETag matches.
+	IfMatch string
+}
+
+// ObjectInfo describes an object belonging to a backup.
+type ObjectInfo struct {
+	// Key is the object's key relative to the backups, as accepted by Download.
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
 // StorageIface defines a generic storage backend used to upload and manage backups.
 // revive:disable-next-line exported
 type StorageIface interface {
@@ -10,8 +52,12 @@ type StorageIface interface {
 	// Name returns the name of the storage backend (e.g., "s3", "gcs")
 	Name() string
 
-	// Upload uploads a local file and returns the remote key/path
-	Upload(localPath string) (string, error)
+	// Ping checks that the backend is reachable and the bucket accessible.
+	Ping(ctx context.Context) error
+
+	// Upload uploads a local file and returns the remote key/path.
+	// Cancelling ctx aborts the upload, including any partially uploaded parts.
+	Upload(ctx context.Context, localPath string) (string, error)
 
 	// List returns keys/identifiers under configured prefix
 	List() ([]string, error)
@@ -21,4 +67,21 @@ type StorageIface interface {
 
 	// TrimPrefix trims the configured prefix from a given key, if present
 	TrimPrefix(keys []string) []string
+
+	// Objects lists the objects making up the backup key, as returned by List, or of every backup if key is empty.
+	Objects(ctx context.Context, key string) ([]ObjectInfo, error)
+
+	// Download opens an object returned by Objects for reading. The caller must close it.
+	// It returns ErrNotExist if the object does not exist.
+	Download(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// PutObject writes data to key, relative to the storage root, and returns the new ETag.
+	PutObject(ctx context.Context, key string, data []byte, opts PutOptions) (string, error)
+
+	// GetObject reads key, relative to the storage root, and returns its data and ETag.
+	// It returns ErrNotExist if the object does not exist.
+	GetObject(ctx context.Context, key string) ([]byte, string, error)
+
+	// DeleteObject deletes key, relative to the storage root.
+	DeleteObject(ctx context.Context, key string, opts DeleteOptions) error
 }
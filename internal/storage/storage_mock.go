@@ -3,6 +3,9 @@
 package storage
 
 import (
+	"context"
+	"io"
+
 	"github.com/stretchr/testify/mock"
 )
 
@@ -23,9 +26,15 @@ func (_m *MockStorageIface) Name() string {
 	return _mockArgs.String(0)
 }
 
-// Upload provides a mock function with given fields: localPath
-func (_m *MockStorageIface) Upload(localPath string) (string, error) {
-	_mockArgs := _m.Called(localPath)
+// Ping provides a mock function with given fields: ctx
+func (_m *MockStorageIface) Ping(ctx context.Context) error {
+	_mockArgs := _m.Called(ctx)
+	return _mockArgs.Error(0)
+}
+
+// Upload provides a mock function with given fields: ctx, localPath
+func (_m *MockStorageIface) Upload(ctx context.Context, localPath string) (string, error) {
+	_mockArgs := _m.Called(ctx, localPath)
 	return _mockArgs.String(0), _mockArgs.Error(1)
 }
 
@@ -50,6 +59,45 @@ func (_m *MockStorageIface) TrimPrefix(keys []string) []string {
 	return _mockArgs.Get(0).([]string)
 }
 
+// Objects provides a mock function with given fields: ctx, key
+func (_m *MockStorageIface) Objects(ctx context.Context, key string) ([]ObjectInfo, error) {
+	_mockArgs := _m.Called(ctx, key)
+	if _mockArgs.Get(0) == nil {
+		return nil, _mockArgs.Error(1)
+	}
+	return _mockArgs.Get(0).([]ObjectInfo), _mockArgs.Error(1)
+}
+
+// Download provides a mock function with given fields: ctx, key
+func (_m *MockStorageIface) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	_mockArgs := _m.Called(ctx, key)
+	if _mockArgs.Get(0) == nil {
+		return nil, _mockArgs.Error(1)
+	}
+	return _mockArgs.Get(0).(io.ReadCloser), _mockArgs.Error(1)
+}
+
+// PutObject provides a mock function with given fields: ctx, key, data, opts
+func (_m *MockStorageIface) PutObject(ctx context.Context, key string, data []byte, opts PutOptions) (string, error) {
+	_mockArgs := _m.Called(ctx, key, data, opts)
+	return _mockArgs.String(0), _mockArgs.Error(1)
+}
+
+// GetObject provides a mock function with given fields: ctx, key
+func (_m *MockStorageIface) GetObject(ctx context.Context, key string) ([]byte, string, error) {
+	_mockArgs := _m.Called(ctx, key)
+	if _mockArgs.Get(0) == nil {
+		return nil, _mockArgs.String(1), _mockArgs.Error(2)
+	}
+	return _mockArgs.Get(0).([]byte), _mockArgs.String(1), _mockArgs.Error(2)
+}
+
+// DeleteObject provides a mock function with given fields: ctx, key, opts
+func (_m *MockStorageIface) DeleteObject(ctx context.Context, key string, opts DeleteOptions) error {
+	_mockArgs := _m.Called(ctx, key, opts)
+	return _mockArgs.Error(0)
+}
+
 // NewMockStorageIface creates a new instance of MockStorageIface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 func NewMockStorageIface(t mock.TestingT) *MockStorageIface {
 	mock := &MockStorageIface{}
@@ -0,0 +1,125 @@
+// Package tracing sets up OpenTelemetry tracing. Spans are exported over OTLP when configured through
+// the standard OTEL_* environment variables and are otherwise discarded.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// instrumentationName identifies Stashly's spans.
+	instrumentationName = "github.com/hibare/stashly"
+
+	// serviceName is the service name unless OTEL_SERVICE_NAME is set.
+	serviceName = "stashly"
+
+	exporterOTLP = "otlp"
+	exporterNone = "none"
+
+	protocolGRPC         = "grpc"
+	protocolHTTPProtobuf = "http/protobuf"
+)
+
+// ErrUnsupported is returned for exporter settings Stashly does not support.
+var ErrUnsupported = errors.New("unsupported tracing configuration")
+
+// ShutdownFunc flushes pending spans and stops the exporter.
+type ShutdownFunc func(ctx context.Context) error
+
+// Enabled reports whether the environment asks for spans to be exported. This is the case when
+// OTEL_TRACES_EXPORTER is set or an OTLP endpoint is set, unless OTEL_SDK_DISABLED is true or
+// OTEL_TRACES_EXPORTER is "none".
+func Enabled() bool {
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		return false
+	}
+	switch strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER")) {
+	case "":
+		return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+	case exporterNone:
+		return false
+	default:
+		return true
+	}
+}
+
+// protocol returns the OTLP protocol for traces, http/protobuf unless set otherwise.
+func protocol() string {
+	for _, key := range []string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if p := strings.TrimSpace(os.Getenv(key)); p != "" {
+			return p
+		}
+	}
+	return protocolHTTPProtobuf
+}
+
+// Setup installs the global tracer provider and W3C trace context propagation. If tracing is not
+// enabled, spans are not recorded and the returned function does nothing. Endpoint, headers,
+// sampling, batching and resource attributes are read from the standard environment variables.
+func Setup(ctx context.Context) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	if exp := strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER")); exp != "" && exp != exporterOTLP {
+		return nil, fmt.Errorf("%w: OTEL_TRACES_EXPORTER=%s", ErrUnsupported, exp)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error detecting tracing resource: %w", err)
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch p := protocol(); p {
+	case protocolHTTPProtobuf:
+		exporter, err = otlptracehttp.New(ctx)
+	case protocolGRPC:
+		exporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("%w: OTLP protocol %s", ErrUnsupported, p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End marks the span as failed if err is not nil and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
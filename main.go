@@ -1,7 +1,12 @@
 // package main implements the entry point for the Stashly application.
 package main
 
-import "github.com/hibare/stashly/cmd"
+import (
+	// Embed the time zone database so backup.timezone works in minimal images.
+	_ "time/tzdata"
+
+	"github.com/hibare/stashly/cmd"
+)
 
 func main() {
 	cmd.Execute()